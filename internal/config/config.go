@@ -1,26 +1,236 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"strconv"
+	"strings"
+	"time"
+
+	"otp-auth-service/internal/models"
 )
 
 type Config struct {
-	JWTSecret     string
+	JWTSecret string
+	// JWTAlgorithm is HS256 (signed with JWTSecret), RS256, ES256 or EdDSA
+	// (signed with the PEM private key in JWTPrivateKeyFile)
+	JWTAlgorithm      string
+	JWTPrivateKeyFile string
+	// JWTPreviousSecrets are former JWT_SECRET values still accepted for
+	// verification after a rotation
+	JWTPreviousSecrets []string
+	// JWTKeysDir holds rotating private keys; see the rotate-keys command
+	JWTKeysDir            string
+	JWTKeysReloadInterval time.Duration
+	// JWTIssuer and JWTAudiences are the iss and aud claims of issued
+	// tokens and are enforced when validating them
+	JWTIssuer       string
+	JWTAudiences    []string
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+	// AuthCookieName is a cookie holding an access token that /auth/verify
+	// accepts in place of the Authorization header; empty disables it
+	AuthCookieName string
+
+	// OAuth authorization server. Clients are registered in the config
+	// file under oauth_clients.
+	OAuthClients []*models.OAuthClient
+	// OAuthLoginURL is the login UI /oauth/authorize sends the browser to
+	OAuthLoginURL         string
+	OAuthAuthorizationTTL time.Duration
+	OAuthCodeTTL          time.Duration
+
 	RedisAddr     string
 	RedisPassword string
 	RedisDB       int
+
+	// UserStore is where users are kept: memory, redis, sqlite or postgres.
+	// DatabaseURL is the SQLite file or PostgreSQL connection string.
+	UserStore   string
+	DatabaseURL string
+
+	// OTP format
+	OTPLength   int
+	OTPAlphabet string // numeric or alphanumeric
+
+	// OTPHashSecret keys the HMAC under which OTP codes are stored
+	OTPHashSecret string
+
+	// OTPPolicy holds the defaults every purpose starts from
+	OTPPolicy OTPPolicy
+	// OTPPurposePolicies holds the resolved policy for every OTP purpose
+	OTPPurposePolicies map[models.OTPPurpose]OTPPolicy
+
+	// OTP delivery
+	OTPSender              string // console, http or file
+	OTPSenderFile          string
+	SMSGatewayURL          string
+	SMSGatewayMethod       string
+	SMSGatewayUsername     string
+	SMSGatewayPassword     string
+	SMSGatewayToken        string
+	SMSGatewayBodyTemplate string
+	SMSGatewayContentType  string
+	SMSGatewayTimeout      time.Duration
 }
 
-func Load() *Config {
+// Load reads the configuration from the optional JSON file named by
+// CONFIG_FILE and from environment variables, which take precedence
+func Load() (*Config, error) {
 	redisDB, _ := strconv.Atoi(getEnv("REDIS_DB", "0"))
+	otpLength, _ := strconv.Atoi(getEnv("OTP_LENGTH", "6"))
+	smsGatewayTimeout, _ := time.ParseDuration(getEnv("SMS_GATEWAY_TIMEOUT", "10s"))
+
+	accessTokenTTL, err := envDuration("ACCESS_TOKEN_TTL", 15*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+	refreshTokenTTL, err := envDuration("REFRESH_TOKEN_TTL", 30*24*time.Hour)
+	if err != nil {
+		return nil, err
+	}
+	keysReloadInterval, err := envDuration("JWT_KEYS_RELOAD_INTERVAL", time.Minute)
+	if err != nil {
+		return nil, err
+	}
+	oauthAuthorizationTTL, err := envDuration("OAUTH_AUTHORIZATION_TTL", 10*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+	oauthCodeTTL, err := envDuration("OAUTH_CODE_TTL", time.Minute)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &Config{
+		JWTSecret:             getEnv("JWT_SECRET", "your-secret-key-change-in-production"),
+		JWTAlgorithm:          getEnv("JWT_ALGORITHM", "HS256"),
+		JWTPrivateKeyFile:     getEnv("JWT_PRIVATE_KEY_FILE", ""),
+		JWTPreviousSecrets:    splitList(getEnv("JWT_PREVIOUS_SECRETS", "")),
+		JWTKeysDir:            getEnv("JWT_KEYS_DIR", ""),
+		JWTKeysReloadInterval: keysReloadInterval,
+		JWTIssuer:             getEnv("JWT_ISSUER", "otp-auth-service"),
+		JWTAudiences:          splitList(getEnv("JWT_AUDIENCES", "otp-auth-service")),
+		AccessTokenTTL:        accessTokenTTL,
+		RefreshTokenTTL:       refreshTokenTTL,
+		AuthCookieName:        getEnv("AUTH_COOKIE_NAME", ""),
+
+		OAuthLoginURL:         getEnv("OAUTH_LOGIN_URL", ""),
+		OAuthAuthorizationTTL: oauthAuthorizationTTL,
+		OAuthCodeTTL:          oauthCodeTTL,
 
-	return &Config{
-		JWTSecret:     getEnv("JWT_SECRET", "your-secret-key-change-in-production"),
 		RedisAddr:     getEnv("REDIS_ADDR", "localhost:6379"),
 		RedisPassword: getEnv("REDIS_PASSWORD", ""),
 		RedisDB:       redisDB,
+
+		UserStore:   getEnv("USER_STORE", "memory"),
+		DatabaseURL: getEnv("DATABASE_URL", ""),
+
+		OTPLength:   otpLength,
+		OTPAlphabet: getEnv("OTP_ALPHABET", "numeric"),
+
+		OTPHashSecret: getEnv("OTP_HASH_SECRET", "your-otp-hash-secret-change-in-production"),
+
+		OTPSender:              getEnv("OTP_SENDER", "console"),
+		OTPSenderFile:          getEnv("OTP_SENDER_FILE", "otp_messages.log"),
+		SMSGatewayURL:          getEnv("SMS_GATEWAY_URL", ""),
+		SMSGatewayMethod:       getEnv("SMS_GATEWAY_METHOD", "POST"),
+		SMSGatewayUsername:     getEnv("SMS_GATEWAY_USERNAME", ""),
+		SMSGatewayPassword:     getEnv("SMS_GATEWAY_PASSWORD", ""),
+		SMSGatewayToken:        getEnv("SMS_GATEWAY_TOKEN", ""),
+		SMSGatewayBodyTemplate: getEnv("SMS_GATEWAY_BODY_TEMPLATE", ""),
+		SMSGatewayContentType:  getEnv("SMS_GATEWAY_CONTENT_TYPE", "application/json"),
+		SMSGatewayTimeout:      smsGatewayTimeout,
+
+		OTPPolicy: DefaultOTPPolicy(),
+	}
+
+	var file *fileConfig
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		var err error
+		if file, err = cfg.loadFile(path); err != nil {
+			return nil, fmt.Errorf("load config file %s: %w", path, err)
+		}
+	}
+
+	if err := cfg.OTPPolicy.loadEnv("OTP_"); err != nil {
+		return nil, err
+	}
+
+	// Purpose specific settings override the defaults, from the file
+	// (otp_purposes.<purpose>) and then from OTP_<PURPOSE>_* variables
+	cfg.OTPPurposePolicies = make(map[models.OTPPurpose]OTPPolicy)
+	for _, purpose := range models.OTPPurposes {
+		policy := cfg.OTPPolicy
+		if file != nil {
+			if override, ok := file.OTPPurposes[string(purpose)]; ok {
+				if err := override.apply(&policy); err != nil {
+					return nil, fmt.Errorf("otp_purposes.%s: %w", purpose, err)
+				}
+			}
+		}
+		if err := policy.loadEnv("OTP_" + strings.ToUpper(string(purpose)) + "_"); err != nil {
+			return nil, err
+		}
+		cfg.OTPPurposePolicies[purpose] = policy
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// Validate checks the configuration for values the service cannot run with
+func (c *Config) Validate() error {
+	if c.AccessTokenTTL < time.Minute {
+		return fmt.Errorf("access token TTL must be at least 1m, got %s", c.AccessTokenTTL)
+	}
+	if c.JWTIssuer == "" {
+		return fmt.Errorf("JWT issuer must not be empty")
+	}
+	if len(c.JWTAudiences) == 0 {
+		return fmt.Errorf("at least one JWT audience is required")
+	}
+	if c.JWTKeysReloadInterval <= 0 {
+		return fmt.Errorf("JWT keys reload interval must be positive, got %s", c.JWTKeysReloadInterval)
 	}
+	if c.RefreshTokenTTL <= c.AccessTokenTTL {
+		return fmt.Errorf("refresh token TTL (%s) must be longer than access token TTL (%s)", c.RefreshTokenTTL, c.AccessTokenTTL)
+	}
+	if c.OAuthAuthorizationTTL <= 0 || c.OAuthCodeTTL <= 0 {
+		return fmt.Errorf("OAuth authorization and code TTLs must be positive")
+	}
+	switch c.UserStore {
+	case "memory", "redis":
+	case "sqlite", "postgres":
+		if c.DatabaseURL == "" {
+			return fmt.Errorf("DATABASE_URL is required for the %s user store", c.UserStore)
+		}
+	default:
+		return fmt.Errorf("unknown user store %q", c.UserStore)
+	}
+	if err := validateOAuthClients(c.OAuthClients); err != nil {
+		return fmt.Errorf("invalid oauth_clients: %w", err)
+	}
+	if err := c.OTPPolicy.Validate(); err != nil {
+		return fmt.Errorf("invalid OTP policy: %w", err)
+	}
+	for purpose, policy := range c.OTPPurposePolicies {
+		if err := policy.Validate(); err != nil {
+			return fmt.Errorf("invalid OTP policy for %s: %w", purpose, err)
+		}
+	}
+	return nil
+}
+
+// PolicyFor returns the OTP policy of a purpose, falling back to the defaults
+func (c *Config) PolicyFor(purpose models.OTPPurpose) OTPPolicy {
+	if policy, ok := c.OTPPurposePolicies[purpose]; ok {
+		return policy
+	}
+	return c.OTPPolicy
 }
 
 func getEnv(key, defaultValue string) string {
@@ -29,3 +239,14 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+// splitList splits a comma separated value, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
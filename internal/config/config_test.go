@@ -0,0 +1,257 @@
+package config
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"otp-auth-service/internal/models"
+)
+
+func TestLoad_Defaults(t *testing.T) {
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if cfg.OTPPolicy != DefaultOTPPolicy() {
+		t.Errorf("Expected default OTP policy, got %+v", cfg.OTPPolicy)
+	}
+
+	for _, purpose := range models.OTPPurposes {
+		if cfg.PolicyFor(purpose) != DefaultOTPPolicy() {
+			t.Errorf("Expected default OTP policy for %s, got %+v", purpose, cfg.PolicyFor(purpose))
+		}
+	}
+}
+
+func TestLoad_FileAndEnv(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	err := os.WriteFile(path, []byte(`{
+		"otp_policy": {
+			"ttl": "5m",
+			"max_attempts": 5,
+			"rate_limit_window": "1h",
+			"key_prefix": "staging_otp"
+		}
+	}`), 0o600)
+	if err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	t.Setenv("CONFIG_FILE", path)
+	t.Setenv("OTP_MAX_ATTEMPTS", "10")
+	t.Setenv("OTP_RATE_LIMIT_MAX", "20")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	policy := cfg.OTPPolicy
+	if policy.TTL != 5*time.Minute {
+		t.Errorf("Expected TTL from file, got %v", policy.TTL)
+	}
+	if policy.MaxAttempts != 10 {
+		t.Errorf("Expected env to override file max attempts, got %d", policy.MaxAttempts)
+	}
+	if policy.RateLimitWindow != time.Hour {
+		t.Errorf("Expected rate limit window from file, got %v", policy.RateLimitWindow)
+	}
+	if policy.RateLimitMax != 20 {
+		t.Errorf("Expected rate limit max from env, got %d", policy.RateLimitMax)
+	}
+	if policy.KeyPrefix != "staging_otp" || policy.RateLimitKeyPrefix != "rate_limit" {
+		t.Errorf("Unexpected key prefixes %q, %q", policy.KeyPrefix, policy.RateLimitKeyPrefix)
+	}
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+	}{
+		{name: "unparsable ttl", env: map[string]string{"OTP_TTL": "two minutes"}},
+		{name: "zero attempts", env: map[string]string{"OTP_MAX_ATTEMPTS": "0"}},
+		{name: "negative quota", env: map[string]string{"OTP_RATE_LIMIT_MAX": "-1"}},
+		{name: "same prefixes", env: map[string]string{"OTP_KEY_PREFIX": "x", "OTP_RATE_LIMIT_KEY_PREFIX": "x"}},
+		{name: "missing file", env: map[string]string{"CONFIG_FILE": "/does/not/exist.json"}},
+		{name: "invalid purpose policy", env: map[string]string{"OTP_ACCOUNT_DELETION_MAX_ATTEMPTS": "0"}},
+		{name: "unparsable access token ttl", env: map[string]string{"ACCESS_TOKEN_TTL": "soon"}},
+		{name: "refresh shorter than access", env: map[string]string{"ACCESS_TOKEN_TTL": "1h", "REFRESH_TOKEN_TTL": "30m"}},
+		{name: "no audiences", env: map[string]string{"JWT_AUDIENCES": " , "}},
+		{name: "unknown user store", env: map[string]string{"USER_STORE": "mysql", "DATABASE_URL": "users"}},
+		{name: "user store without database", env: map[string]string{"USER_STORE": "postgres"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			if _, err := Load(); err == nil {
+				t.Error("Expected error")
+			}
+		})
+	}
+}
+
+func TestLoad_PurposePolicies(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	err := os.WriteFile(path, []byte(`{
+		"otp_policy": {"ttl": "3m"},
+		"otp_purposes": {
+			"account_deletion": {"max_attempts": 1},
+			"transaction_confirm": {"ttl": "5m"}
+		}
+	}`), 0o600)
+	if err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	t.Setenv("CONFIG_FILE", path)
+	t.Setenv("OTP_TRANSACTION_CONFIRM_TTL", "10m")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if ttl := cfg.PolicyFor(models.PurposeLogin).TTL; ttl != 3*time.Minute {
+		t.Errorf("Expected login to inherit default TTL, got %v", ttl)
+	}
+	if attempts := cfg.PolicyFor(models.PurposeAccountDeletion).MaxAttempts; attempts != 1 {
+		t.Errorf("Expected account deletion max attempts from file, got %d", attempts)
+	}
+	if ttl := cfg.PolicyFor(models.PurposeAccountDeletion).TTL; ttl != 3*time.Minute {
+		t.Errorf("Expected account deletion to inherit default TTL, got %v", ttl)
+	}
+	if ttl := cfg.PolicyFor(models.PurposeTransactionConfirm).TTL; ttl != 10*time.Minute {
+		t.Errorf("Expected env to override transaction confirm TTL, got %v", ttl)
+	}
+}
+
+func TestLoad_UnknownPurposeInFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"otp_purposes": {"bogus": {"ttl": "1m"}}}`), 0o600); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	t.Setenv("CONFIG_FILE", path)
+
+	if _, err := Load(); err == nil {
+		t.Error("Expected error for unknown purpose")
+	}
+}
+
+func TestLoad_OAuthClients(t *testing.T) {
+	const secretHash = "a0f3285b07c26c0dcd2191447f391170d06035e8d57e31a048ba87074f3a9a15"
+
+	tests := []struct {
+		name    string
+		clients string
+		wantErr bool
+	}{
+		{name: "valid", clients: `[
+			{"client_id": "spa", "name": "Web App", "redirect_uris": ["https://app.example.com/callback"], "scopes": ["profile"]},
+			{"client_id": "backend", "secret_hash": "` + secretHash + `", "redirect_uris": ["http://localhost:3000/cb"]}
+		]`},
+		{name: "missing client_id", clients: `[{"redirect_uris": ["https://app.example.com/cb"]}]`, wantErr: true},
+		{name: "duplicate client_id", clients: `[
+			{"client_id": "spa", "redirect_uris": ["https://app.example.com/cb"]},
+			{"client_id": "spa", "redirect_uris": ["https://app.example.com/cb"]}
+		]`, wantErr: true},
+		{name: "no redirect URI", clients: `[{"client_id": "spa"}]`, wantErr: true},
+		{name: "relative redirect URI", clients: `[{"client_id": "spa", "redirect_uris": ["/callback"]}]`, wantErr: true},
+		{name: "redirect URI with fragment", clients: `[{"client_id": "spa", "redirect_uris": ["https://app.example.com/cb#x"]}]`, wantErr: true},
+		{name: "invalid secret hash", clients: `[{"client_id": "spa", "secret_hash": "s3cret", "redirect_uris": ["https://app.example.com/cb"]}]`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.json")
+			if err := os.WriteFile(path, []byte(`{"oauth_clients": `+tt.clients+`}`), 0o600); err != nil {
+				t.Fatalf("Failed to write config file: %v", err)
+			}
+			t.Setenv("CONFIG_FILE", path)
+
+			cfg, err := Load()
+			if tt.wantErr {
+				if err == nil {
+					t.Error("Expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if len(cfg.OAuthClients) != 2 || !cfg.OAuthClients[0].IsPublic() || cfg.OAuthClients[1].IsPublic() {
+				t.Errorf("Unexpected clients: %+v", cfg.OAuthClients)
+			}
+		})
+	}
+}
+
+func TestLoad_MachineClients(t *testing.T) {
+	const secretHash = "a0f3285b07c26c0dcd2191447f391170d06035e8d57e31a048ba87074f3a9a15"
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatalf("Failed to encode key: %v", err)
+	}
+	keyFile := filepath.Join(t.TempDir(), "batch.pem")
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0o600); err != nil {
+		t.Fatalf("Failed to write key file: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		clients string
+		wantErr bool
+	}{
+		{name: "secret", clients: `[{"client_id": "batch", "secret_hash": "` + secretHash + `", "grant_types": ["client_credentials"], "scopes": ["users:read"]}]`},
+		{name: "private_key_jwt", clients: `[{"client_id": "batch", "public_key_file": "` + keyFile + `", "grant_types": ["client_credentials"]}]`},
+		{name: "public client", clients: `[{"client_id": "batch", "grant_types": ["client_credentials"]}]`, wantErr: true},
+		{name: "secret and key", clients: `[{"client_id": "batch", "secret_hash": "` + secretHash + `", "public_key_file": "` + keyFile + `", "grant_types": ["client_credentials"]}]`, wantErr: true},
+		{name: "missing key file", clients: `[{"client_id": "batch", "public_key_file": "/nonexistent.pem", "grant_types": ["client_credentials"]}]`, wantErr: true},
+		{name: "unknown grant type", clients: `[{"client_id": "batch", "secret_hash": "` + secretHash + `", "grant_types": ["password"]}]`, wantErr: true},
+		{name: "authorization code without redirect URI", clients: `[{"client_id": "batch", "secret_hash": "` + secretHash + `", "grant_types": ["client_credentials", "authorization_code"]}]`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.json")
+			if err := os.WriteFile(path, []byte(`{"oauth_clients": `+tt.clients+`}`), 0o600); err != nil {
+				t.Fatalf("Failed to write config file: %v", err)
+			}
+			t.Setenv("CONFIG_FILE", path)
+
+			cfg, err := Load()
+			if tt.wantErr {
+				if err == nil {
+					t.Error("Expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			client := cfg.OAuthClients[0]
+			if client.IsPublic() || !client.AllowsGrantType("client_credentials") || client.AllowsGrantType("authorization_code") {
+				t.Errorf("Unexpected client: %+v", client)
+			}
+			if client.PublicKeyFile != "" && client.PublicKey == nil {
+				t.Error("Expected public key to be loaded")
+			}
+		})
+	}
+}
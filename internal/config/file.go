@@ -0,0 +1,96 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"otp-auth-service/internal/models"
+)
+
+// fileConfig is the JSON config file format. Every field is optional;
+// values from the file are applied before environment variables.
+type fileConfig struct {
+	OTPPolicy    *otpPolicyFile            `json:"otp_policy"`
+	OTPPurposes  map[string]*otpPolicyFile `json:"otp_purposes"`
+	OAuthClients []*models.OAuthClient     `json:"oauth_clients"`
+}
+
+type otpPolicyFile struct {
+	TTL                string `json:"ttl"`
+	MaxAttempts        *int   `json:"max_attempts"`
+	RateLimitWindow    string `json:"rate_limit_window"`
+	RateLimitMax       *int   `json:"rate_limit_max"`
+	ResendCooldown     string `json:"resend_cooldown"`
+	KeyPrefix          string `json:"key_prefix"`
+	RateLimitKeyPrefix string `json:"rate_limit_key_prefix"`
+	CooldownKeyPrefix  string `json:"cooldown_key_prefix"`
+}
+
+// loadFile applies the file's settings and returns it for the sections
+// that are resolved later (per-purpose overrides)
+func (c *Config) loadFile(path string) (*fileConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file fileConfig
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+
+	if file.OTPPolicy != nil {
+		if err := file.OTPPolicy.apply(&c.OTPPolicy); err != nil {
+			return nil, fmt.Errorf("otp_policy: %w", err)
+		}
+	}
+
+	c.OAuthClients = file.OAuthClients
+
+	for purpose := range file.OTPPurposes {
+		if !models.OTPPurpose(purpose).IsValid() {
+			return nil, fmt.Errorf("otp_purposes: unknown purpose %q", purpose)
+		}
+	}
+
+	return &file, nil
+}
+
+func (f *otpPolicyFile) apply(p *OTPPolicy) error {
+	var err error
+
+	if f.TTL != "" {
+		if p.TTL, err = time.ParseDuration(f.TTL); err != nil {
+			return fmt.Errorf("invalid ttl: %w", err)
+		}
+	}
+	if f.MaxAttempts != nil {
+		p.MaxAttempts = *f.MaxAttempts
+	}
+	if f.RateLimitWindow != "" {
+		if p.RateLimitWindow, err = time.ParseDuration(f.RateLimitWindow); err != nil {
+			return fmt.Errorf("invalid rate_limit_window: %w", err)
+		}
+	}
+	if f.RateLimitMax != nil {
+		p.RateLimitMax = *f.RateLimitMax
+	}
+	if f.ResendCooldown != "" {
+		if p.ResendCooldown, err = time.ParseDuration(f.ResendCooldown); err != nil {
+			return fmt.Errorf("invalid resend_cooldown: %w", err)
+		}
+	}
+	if f.KeyPrefix != "" {
+		p.KeyPrefix = f.KeyPrefix
+	}
+	if f.RateLimitKeyPrefix != "" {
+		p.RateLimitKeyPrefix = f.RateLimitKeyPrefix
+	}
+	if f.CooldownKeyPrefix != "" {
+		p.CooldownKeyPrefix = f.CooldownKeyPrefix
+	}
+
+	return nil
+}
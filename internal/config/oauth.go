@@ -0,0 +1,112 @@
+package config
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"net/url"
+	"os"
+
+	"otp-auth-service/internal/models"
+)
+
+// validateOAuthClients checks the registered clients and loads their public
+// keys. Redirect URIs must be absolute and without a fragment (RFC 6749
+// section 3.1.2).
+func validateOAuthClients(clients []*models.OAuthClient) error {
+	seen := make(map[string]bool, len(clients))
+	for i, client := range clients {
+		if client == nil || client.ID == "" {
+			return fmt.Errorf("client %d has no client_id", i)
+		}
+		if seen[client.ID] {
+			return fmt.Errorf("duplicate client_id %q", client.ID)
+		}
+		seen[client.ID] = true
+
+		if client.SecretHash != "" {
+			if hash, err := hex.DecodeString(client.SecretHash); err != nil || len(hash) != 32 {
+				return fmt.Errorf("%s: secret_hash must be a hex encoded SHA-256", client.ID)
+			}
+		}
+
+		if client.PublicKeyFile != "" {
+			if client.SecretHash != "" {
+				return fmt.Errorf("%s: set either secret_hash or public_key_file, not both", client.ID)
+			}
+			key, err := loadClientPublicKey(client.PublicKeyFile)
+			if err != nil {
+				return fmt.Errorf("%s: %w", client.ID, err)
+			}
+			client.PublicKey = key
+		}
+
+		for _, grantType := range client.GrantTypes {
+			switch grantType {
+			case models.GrantTypeAuthorizationCode, models.GrantTypeRefreshToken:
+			case models.GrantTypeClientCredentials:
+				if client.IsPublic() {
+					return fmt.Errorf("%s: client_credentials requires secret_hash or public_key_file", client.ID)
+				}
+			default:
+				return fmt.Errorf("%s: unsupported grant type %q", client.ID, grantType)
+			}
+		}
+
+		// Machine clients never redirect a browser
+		if !client.AllowsGrantType(models.GrantTypeAuthorizationCode) {
+			continue
+		}
+		if len(client.RedirectURIs) == 0 {
+			return fmt.Errorf("%s: at least one redirect URI is required", client.ID)
+		}
+		for _, redirectURI := range client.RedirectURIs {
+			u, err := url.Parse(redirectURI)
+			if err != nil || !u.IsAbs() || u.Fragment != "" {
+				return fmt.Errorf("%s: invalid redirect URI %q", client.ID, redirectURI)
+			}
+		}
+	}
+	return nil
+}
+
+// loadClientPublicKey reads the PEM public key of a private_key_jwt client.
+// Keys must suit one of the algorithms tokens can be signed with.
+func loadClientPublicKey(path string) (crypto.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read public key: %w", err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "PUBLIC KEY" {
+		return nil, fmt.Errorf("%s is not a PEM public key", path)
+	}
+
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key: %w", err)
+	}
+
+	switch pub := key.(type) {
+	case *rsa.PublicKey:
+		if pub.N.BitLen() < 2048 {
+			return nil, fmt.Errorf("RSA public keys must be at least 2048 bits")
+		}
+	case *ecdsa.PublicKey:
+		if pub.Curve != elliptic.P256() {
+			return nil, fmt.Errorf("EC public keys must use P-256")
+		}
+	case ed25519.PublicKey:
+	default:
+		return nil, fmt.Errorf("unsupported public key type %T", key)
+	}
+
+	return key, nil
+}
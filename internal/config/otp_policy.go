@@ -0,0 +1,112 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// OTPPolicy controls the lifetime, attempt limit and request quota of OTPs
+type OTPPolicy struct {
+	TTL                time.Duration
+	MaxAttempts        int
+	RateLimitWindow    time.Duration
+	RateLimitMax       int
+	ResendCooldown     time.Duration
+	KeyPrefix          string
+	RateLimitKeyPrefix string
+	CooldownKeyPrefix  string
+}
+
+// DefaultOTPPolicy returns the policy used when nothing is configured
+func DefaultOTPPolicy() OTPPolicy {
+	return OTPPolicy{
+		TTL:                2 * time.Minute,
+		MaxAttempts:        3,
+		RateLimitWindow:    10 * time.Minute,
+		RateLimitMax:       3,
+		ResendCooldown:     30 * time.Second,
+		KeyPrefix:          "otp",
+		RateLimitKeyPrefix: "rate_limit",
+		CooldownKeyPrefix:  "otp_cooldown",
+	}
+}
+
+// Validate checks that the policy values are usable
+func (p OTPPolicy) Validate() error {
+	if p.TTL < time.Second {
+		return fmt.Errorf("OTP TTL must be at least 1s, got %s", p.TTL)
+	}
+	if p.MaxAttempts < 1 {
+		return fmt.Errorf("OTP max attempts must be at least 1, got %d", p.MaxAttempts)
+	}
+	if p.RateLimitWindow < time.Second {
+		return fmt.Errorf("OTP rate limit window must be at least 1s, got %s", p.RateLimitWindow)
+	}
+	if p.RateLimitMax < 1 {
+		return fmt.Errorf("OTP rate limit max must be at least 1, got %d", p.RateLimitMax)
+	}
+	if p.ResendCooldown < time.Second {
+		return fmt.Errorf("OTP resend cooldown must be at least 1s, got %s", p.ResendCooldown)
+	}
+	if p.KeyPrefix == "" || p.RateLimitKeyPrefix == "" || p.CooldownKeyPrefix == "" {
+		return fmt.Errorf("OTP key prefixes must not be empty")
+	}
+	if p.KeyPrefix == p.RateLimitKeyPrefix || p.KeyPrefix == p.CooldownKeyPrefix || p.RateLimitKeyPrefix == p.CooldownKeyPrefix {
+		return fmt.Errorf("OTP key prefixes must all differ")
+	}
+	return nil
+}
+
+// loadEnv overrides policy values from environment variables named
+// <prefix>TTL, <prefix>MAX_ATTEMPTS, <prefix>RATE_LIMIT_WINDOW,
+// <prefix>RATE_LIMIT_MAX, <prefix>RESEND_COOLDOWN, <prefix>KEY_PREFIX,
+// <prefix>RATE_LIMIT_KEY_PREFIX and <prefix>COOLDOWN_KEY_PREFIX
+func (p *OTPPolicy) loadEnv(prefix string) error {
+	var err error
+
+	if p.TTL, err = envDuration(prefix+"TTL", p.TTL); err != nil {
+		return err
+	}
+	if p.MaxAttempts, err = envInt(prefix+"MAX_ATTEMPTS", p.MaxAttempts); err != nil {
+		return err
+	}
+	if p.RateLimitWindow, err = envDuration(prefix+"RATE_LIMIT_WINDOW", p.RateLimitWindow); err != nil {
+		return err
+	}
+	if p.RateLimitMax, err = envInt(prefix+"RATE_LIMIT_MAX", p.RateLimitMax); err != nil {
+		return err
+	}
+	if p.ResendCooldown, err = envDuration(prefix+"RESEND_COOLDOWN", p.ResendCooldown); err != nil {
+		return err
+	}
+	p.KeyPrefix = getEnv(prefix+"KEY_PREFIX", p.KeyPrefix)
+	p.RateLimitKeyPrefix = getEnv(prefix+"RATE_LIMIT_KEY_PREFIX", p.RateLimitKeyPrefix)
+	p.CooldownKeyPrefix = getEnv(prefix+"COOLDOWN_KEY_PREFIX", p.CooldownKeyPrefix)
+
+	return nil
+}
+
+func envDuration(key string, defaultValue time.Duration) (time.Duration, error) {
+	value := getEnv(key, "")
+	if value == "" {
+		return defaultValue, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", key, err)
+	}
+	return d, nil
+}
+
+func envInt(key string, defaultValue int) (int, error) {
+	value := getEnv(key, "")
+	if value == "" {
+		return defaultValue, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", key, err)
+	}
+	return n, nil
+}
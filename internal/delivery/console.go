@@ -0,0 +1,23 @@
+package delivery
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// ConsoleSender writes messages to stdout (for development)
+type ConsoleSender struct {
+	out io.Writer
+}
+
+func NewConsoleSender() *ConsoleSender {
+	return &ConsoleSender{
+		out: os.Stdout,
+	}
+}
+
+func (s *ConsoleSender) Send(msg *Message) error {
+	_, err := fmt.Fprintf(s.out, "[%s] to %s: %s\n", msg.Channel, msg.Recipient, msg.Body)
+	return err
+}
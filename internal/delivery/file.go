@@ -0,0 +1,68 @@
+package delivery
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+)
+
+// FileSender appends every message as a JSON line to a file so tests
+// and scripts can read back the codes that were sent
+type FileSender struct {
+	path  string
+	mutex sync.Mutex
+}
+
+// FileRecord is a single line written by FileSender
+type FileRecord struct {
+	Message
+	SentAt time.Time `json:"sent_at"`
+}
+
+func NewFileSender(path string) *FileSender {
+	return &FileSender{
+		path: path,
+	}
+}
+
+func (s *FileSender) Send(msg *Message) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	line, err := json.Marshal(&FileRecord{Message: *msg, SentAt: time.Now()})
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.Write(append(line, '\n'))
+	return err
+}
+
+// ReadFile returns all records written to the given file, oldest first
+func ReadFile(path string) ([]*FileRecord, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var records []*FileRecord
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var record FileRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, err
+		}
+		records = append(records, &record)
+	}
+
+	return records, scanner.Err()
+}
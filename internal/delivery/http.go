@@ -0,0 +1,103 @@
+package delivery
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"text/template"
+	"time"
+)
+
+// DefaultBodyTemplate is the request body sent to the SMS gateway when no
+// template is configured
+const DefaultBodyTemplate = `{"to":{{json .Recipient}},"channel":{{json .Channel}},"message":{{json .Body}}}`
+
+// HTTPSenderConfig configures a generic HTTP SMS gateway
+type HTTPSenderConfig struct {
+	URL          string
+	Method       string
+	Username     string
+	Password     string
+	Token        string
+	BodyTemplate string
+	ContentType  string
+	Timeout      time.Duration
+}
+
+// HTTPSender posts messages to an HTTP SMS gateway using a body template
+type HTTPSender struct {
+	config   HTTPSenderConfig
+	template *template.Template
+	client   *http.Client
+}
+
+var templateFuncs = template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+}
+
+func NewHTTPSender(cfg HTTPSenderConfig) (*HTTPSender, error) {
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("SMS gateway URL is required")
+	}
+	if cfg.Method == "" {
+		cfg.Method = http.MethodPost
+	}
+	if cfg.BodyTemplate == "" {
+		cfg.BodyTemplate = DefaultBodyTemplate
+	}
+	if cfg.ContentType == "" {
+		cfg.ContentType = "application/json"
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+
+	tmpl, err := template.New("body").Funcs(templateFuncs).Parse(cfg.BodyTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SMS gateway body template: %w", err)
+	}
+
+	return &HTTPSender{
+		config:   cfg,
+		template: tmpl,
+		client:   &http.Client{Timeout: cfg.Timeout},
+	}, nil
+}
+
+func (s *HTTPSender) Send(msg *Message) error {
+	var body bytes.Buffer
+	if err := s.template.Execute(&body, msg); err != nil {
+		return fmt.Errorf("render SMS gateway body: %w", err)
+	}
+
+	req, err := http.NewRequest(s.config.Method, s.config.URL, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", s.config.ContentType)
+
+	// Bearer token takes precedence over basic auth
+	if s.config.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.config.Token)
+	} else if s.config.Username != "" {
+		req.SetBasicAuth(s.config.Username, s.config.Password)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("SMS gateway returned %d: %s", resp.StatusCode, bytes.TrimSpace(respBody))
+	}
+
+	return nil
+}
@@ -0,0 +1,59 @@
+package delivery
+
+import (
+	"fmt"
+
+	"otp-auth-service/internal/config"
+)
+
+// Channel identifies the medium used to deliver a message
+type Channel string
+
+const (
+	ChannelSMS      Channel = "sms"
+	ChannelVoice    Channel = "voice"
+	ChannelWhatsApp Channel = "whatsapp"
+)
+
+// Provider names accepted in config.Config.OTPSender
+const (
+	ProviderConsole = "console"
+	ProviderHTTP    = "http"
+	ProviderFile    = "file"
+)
+
+// Message is a single outbound notification
+type Message struct {
+	Channel   Channel           `json:"channel"`
+	Recipient string            `json:"recipient"`
+	Body      string            `json:"body"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+}
+
+// Sender delivers messages to end users
+type Sender interface {
+	Send(msg *Message) error
+}
+
+// NewSender builds the sender selected in the configuration
+func NewSender(cfg *config.Config) (Sender, error) {
+	switch cfg.OTPSender {
+	case "", ProviderConsole:
+		return NewConsoleSender(), nil
+	case ProviderHTTP:
+		return NewHTTPSender(HTTPSenderConfig{
+			URL:          cfg.SMSGatewayURL,
+			Method:       cfg.SMSGatewayMethod,
+			Username:     cfg.SMSGatewayUsername,
+			Password:     cfg.SMSGatewayPassword,
+			Token:        cfg.SMSGatewayToken,
+			BodyTemplate: cfg.SMSGatewayBodyTemplate,
+			ContentType:  cfg.SMSGatewayContentType,
+			Timeout:      cfg.SMSGatewayTimeout,
+		})
+	case ProviderFile:
+		return NewFileSender(cfg.OTPSenderFile), nil
+	default:
+		return nil, fmt.Errorf("unknown OTP sender %q", cfg.OTPSender)
+	}
+}
@@ -0,0 +1,172 @@
+package delivery
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"otp-auth-service/internal/config"
+)
+
+func TestConsoleSender_Send(t *testing.T) {
+	var out bytes.Buffer
+	sender := &ConsoleSender{out: &out}
+
+	err := sender.Send(&Message{Channel: ChannelSMS, Recipient: "+1234567890", Body: "code 123456"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if !strings.Contains(out.String(), "+1234567890") || !strings.Contains(out.String(), "code 123456") {
+		t.Errorf("Unexpected console output: %q", out.String())
+	}
+}
+
+func TestFileSender_Send(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "messages.log")
+	sender := NewFileSender(path)
+
+	for _, body := range []string{"first", "second"} {
+		err := sender.Send(&Message{
+			Channel:   ChannelSMS,
+			Recipient: "+1234567890",
+			Body:      body,
+			Metadata:  map[string]string{"type": "otp"},
+		})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+
+	records, err := ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read records: %v", err)
+	}
+
+	if len(records) != 2 {
+		t.Fatalf("Expected 2 records, got %d", len(records))
+	}
+	if records[1].Body != "second" {
+		t.Errorf("Expected last body 'second', got '%s'", records[1].Body)
+	}
+	if records[0].Metadata["type"] != "otp" {
+		t.Errorf("Expected metadata to be preserved, got %v", records[0].Metadata)
+	}
+	if records[0].SentAt.IsZero() {
+		t.Error("Expected SentAt to be set")
+	}
+}
+
+func TestHTTPSender_Send(t *testing.T) {
+	var gotBody map[string]string
+	var gotAuth string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+		body, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(body, &gotBody); err != nil {
+			t.Errorf("Gateway received invalid JSON: %s", body)
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	sender, err := NewHTTPSender(HTTPSenderConfig{URL: server.URL, Token: "secret"})
+	if err != nil {
+		t.Fatalf("Failed to create sender: %v", err)
+	}
+
+	err = sender.Send(&Message{Channel: ChannelSMS, Recipient: "+1234567890", Body: `say "hi"`})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if gotAuth != "Bearer secret" {
+		t.Errorf("Expected bearer auth, got '%s'", gotAuth)
+	}
+	if gotBody["to"] != "+1234567890" || gotBody["message"] != `say "hi"` || gotBody["channel"] != "sms" {
+		t.Errorf("Unexpected gateway body: %v", gotBody)
+	}
+}
+
+func TestHTTPSender_CustomTemplateAndBasicAuth(t *testing.T) {
+	var gotBody string
+	var gotUser, gotPass string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotUser, gotPass, _ = r.BasicAuth()
+		body, _ := io.ReadAll(r.Body)
+		gotBody = string(body)
+	}))
+	defer server.Close()
+
+	sender, err := NewHTTPSender(HTTPSenderConfig{
+		URL:          server.URL,
+		Username:     "user",
+		Password:     "pass",
+		BodyTemplate: "To={{.Recipient}}&Body={{.Body}}",
+		ContentType:  "application/x-www-form-urlencoded",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create sender: %v", err)
+	}
+
+	if err := sender.Send(&Message{Recipient: "+1234567890", Body: "hello"}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if gotUser != "user" || gotPass != "pass" {
+		t.Errorf("Expected basic auth user/pass, got %s/%s", gotUser, gotPass)
+	}
+	if gotBody != "To=+1234567890&Body=hello" {
+		t.Errorf("Unexpected body: %s", gotBody)
+	}
+}
+
+func TestHTTPSender_GatewayError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "quota exceeded", http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	sender, err := NewHTTPSender(HTTPSenderConfig{URL: server.URL})
+	if err != nil {
+		t.Fatalf("Failed to create sender: %v", err)
+	}
+
+	err = sender.Send(&Message{Recipient: "+1234567890", Body: "hello"})
+	if err == nil {
+		t.Fatal("Expected error for non-2xx gateway response")
+	}
+	if !strings.Contains(err.Error(), "429") {
+		t.Errorf("Expected status code in error, got %v", err)
+	}
+}
+
+func TestNewSender(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     *config.Config
+		wantErr bool
+	}{
+		{name: "console", cfg: &config.Config{OTPSender: ProviderConsole}},
+		{name: "file", cfg: &config.Config{OTPSender: ProviderFile, OTPSenderFile: "otp.log"}},
+		{name: "http", cfg: &config.Config{OTPSender: ProviderHTTP, SMSGatewayURL: "http://localhost:9999/send"}},
+		{name: "http without url", cfg: &config.Config{OTPSender: ProviderHTTP}, wantErr: true},
+		{name: "unknown", cfg: &config.Config{OTPSender: "pigeon"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewSender(tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewSender() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
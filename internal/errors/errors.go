@@ -11,6 +11,7 @@ type DomainError struct {
 	Code       string `json:"code"`
 	Message    string `json:"message"`
 	Details    string `json:"details,omitempty"`
+	RetryAfter int    `json:"retry_after,omitempty"`
 	HTTPStatus int    `json:"-"`
 }
 
@@ -37,6 +38,18 @@ func (e *DomainError) WithDetails(details string) *DomainError {
 		Code:       e.Code,
 		Message:    e.Message,
 		Details:    details,
+		RetryAfter: e.RetryAfter,
+		HTTPStatus: e.HTTPStatus,
+	}
+}
+
+// WithRetryAfter tells the client how many seconds to wait before retrying
+func (e *DomainError) WithRetryAfter(seconds int) *DomainError {
+	return &DomainError{
+		Code:       e.Code,
+		Message:    e.Message,
+		Details:    e.Details,
+		RetryAfter: seconds,
 		HTTPStatus: e.HTTPStatus,
 	}
 }
@@ -44,28 +57,51 @@ func (e *DomainError) WithDetails(details string) *DomainError {
 // Common domain errors
 var (
 	// Authentication errors
-	ErrInvalidOTP        = New("INVALID_OTP", "Invalid OTP provided", http.StatusUnauthorized)
-	ErrOTPExpired        = New("OTP_EXPIRED", "OTP has expired", http.StatusUnauthorized)
-	ErrOTPNotFound       = New("OTP_NOT_FOUND", "OTP not found", http.StatusUnauthorized)
-	ErrTooManyAttempts   = New("TOO_MANY_ATTEMPTS", "Too many failed attempts", http.StatusUnauthorized)
-	ErrRateLimitExceeded = New("RATE_LIMIT_EXCEEDED", "Rate limit exceeded", http.StatusTooManyRequests)
-	ErrInvalidToken      = New("INVALID_TOKEN", "Invalid or expired token", http.StatusUnauthorized)
-	ErrMissingAuthHeader = New("MISSING_AUTH_HEADER", "Authorization header is required", http.StatusUnauthorized)
-	ErrInvalidAuthFormat = New("INVALID_AUTH_FORMAT", "Invalid authorization header format", http.StatusUnauthorized)
+	ErrInvalidOTP          = New("INVALID_OTP", "Invalid OTP provided", http.StatusUnauthorized)
+	ErrOTPExpired          = New("OTP_EXPIRED", "OTP has expired", http.StatusUnauthorized)
+	ErrOTPNotFound         = New("OTP_NOT_FOUND", "OTP not found", http.StatusUnauthorized)
+	ErrTooManyAttempts     = New("TOO_MANY_ATTEMPTS", "Too many failed attempts", http.StatusUnauthorized)
+	ErrRateLimitExceeded   = New("RATE_LIMIT_EXCEEDED", "Rate limit exceeded", http.StatusTooManyRequests)
+	ErrInvalidToken        = New("INVALID_TOKEN", "Invalid or expired token", http.StatusUnauthorized)
+	ErrInvalidRefreshToken = New("INVALID_REFRESH_TOKEN", "Invalid or expired refresh token", http.StatusUnauthorized)
+	ErrTokenRevoked        = New("TOKEN_REVOKED", "Token has been revoked", http.StatusUnauthorized)
+	ErrRefreshTokenReused  = New("REFRESH_TOKEN_REUSED", "Refresh token was already used, all sessions from this login have been revoked", http.StatusUnauthorized)
+	ErrMissingAuthHeader   = New("MISSING_AUTH_HEADER", "Authorization header is required", http.StatusUnauthorized)
+	ErrInvalidAuthFormat   = New("INVALID_AUTH_FORMAT", "Invalid authorization header format", http.StatusUnauthorized)
+	ErrOTPDeliveryFailed   = New("OTP_DELIVERY_FAILED", "Failed to deliver OTP", http.StatusBadGateway)
+	ErrResendCooldown      = New("RESEND_COOLDOWN", "OTP was sent recently, please wait before resending", http.StatusTooManyRequests)
+	ErrInsufficientScope   = New("INSUFFICIENT_SCOPE", "Token does not grant the required scope", http.StatusForbidden)
+	ErrUserRequired        = New("USER_REQUIRED", "This endpoint is only available to users", http.StatusForbidden)
+	ErrStepUpRequired      = New("STEP_UP_REQUIRED", "A recent OTP verification is required", http.StatusUnauthorized)
 
 	// User errors
 	ErrUserNotFound      = New("USER_NOT_FOUND", "User not found", http.StatusNotFound)
 	ErrUserAlreadyExists = New("USER_ALREADY_EXISTS", "User with this phone number already exists", http.StatusConflict)
+	ErrUserDeactivated   = New("USER_DEACTIVATED", "User account is deactivated", http.StatusForbidden)
 	ErrInvalidUserID     = New("INVALID_USER_ID", "Invalid user ID", http.StatusBadRequest)
+	ErrSessionNotFound   = New("SESSION_NOT_FOUND", "Session not found", http.StatusNotFound)
+
+	// OAuth errors
+	ErrClientNotFound               = New("CLIENT_NOT_FOUND", "OAuth client not found", http.StatusNotFound)
+	ErrAuthorizationRequestNotFound = New("AUTHORIZATION_REQUEST_NOT_FOUND", "Authorization request not found or expired", http.StatusNotFound)
+	ErrAuthorizationOutOfOrder      = New("AUTHORIZATION_OUT_OF_ORDER", "Authorization steps must be completed in order", http.StatusConflict)
+	ErrInvalidAuthorizationCode     = New("INVALID_AUTHORIZATION_CODE", "Invalid, expired or already used authorization code", http.StatusBadRequest)
+	ErrConsentNotFound              = New("CONSENT_NOT_FOUND", "Consent not found", http.StatusNotFound)
 
 	// Validation errors
 	ErrInvalidRequest       = New("INVALID_REQUEST", "Invalid request body", http.StatusBadRequest)
 	ErrInvalidPhoneNumber   = New("INVALID_PHONE_NUMBER", "Invalid phone number format", http.StatusBadRequest)
 	ErrMissingRequiredField = New("MISSING_REQUIRED_FIELD", "Required field is missing", http.StatusBadRequest)
 	ErrInvalidOTPFormat     = New("INVALID_OTP_FORMAT", "Invalid OTP format", http.StatusBadRequest)
+	ErrInvalidChannel       = New("INVALID_CHANNEL", "Invalid delivery channel", http.StatusBadRequest)
+	ErrInvalidOTPPurpose    = New("INVALID_OTP_PURPOSE", "Invalid OTP purpose", http.StatusBadRequest)
 	ErrInvalidUUID          = New("INVALID_UUID", "Invalid UUID format", http.StatusBadRequest)
 	ErrInvalidPagination    = New("INVALID_PAGINATION", "Invalid pagination parameters", http.StatusBadRequest)
 	ErrInvalidSearchQuery    = New("INVALID_SEARCH_QUERY", "Invalid search query", http.StatusBadRequest)
+	ErrInvalidSort          = New("INVALID_SORT", "Invalid sort parameters", http.StatusBadRequest)
+	ErrInvalidCursor        = New("INVALID_CURSOR", "Invalid pagination cursor", http.StatusBadRequest)
+	ErrInvalidFilter        = New("INVALID_FILTER", "Invalid filter parameters", http.StatusBadRequest)
+	ErrInvalidProfile       = New("INVALID_PROFILE", "Invalid profile", http.StatusBadRequest)
 
 	// Internal errors
 	ErrInternalServer = New("INTERNAL_SERVER_ERROR", "Internal server error", http.StatusInternalServerError)
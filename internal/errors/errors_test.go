@@ -178,18 +178,39 @@ func TestPredefinedErrors(t *testing.T) {
 		{"ErrTooManyAttempts", ErrTooManyAttempts},
 		{"ErrRateLimitExceeded", ErrRateLimitExceeded},
 		{"ErrInvalidToken", ErrInvalidToken},
+		{"ErrInvalidRefreshToken", ErrInvalidRefreshToken},
+		{"ErrTokenRevoked", ErrTokenRevoked},
+		{"ErrRefreshTokenReused", ErrRefreshTokenReused},
 		{"ErrMissingAuthHeader", ErrMissingAuthHeader},
 		{"ErrInvalidAuthFormat", ErrInvalidAuthFormat},
+		{"ErrOTPDeliveryFailed", ErrOTPDeliveryFailed},
+		{"ErrResendCooldown", ErrResendCooldown},
+		{"ErrInsufficientScope", ErrInsufficientScope},
+		{"ErrUserRequired", ErrUserRequired},
+		{"ErrStepUpRequired", ErrStepUpRequired},
 		{"ErrUserNotFound", ErrUserNotFound},
 		{"ErrUserAlreadyExists", ErrUserAlreadyExists},
+		{"ErrUserDeactivated", ErrUserDeactivated},
 		{"ErrInvalidUserID", ErrInvalidUserID},
+		{"ErrSessionNotFound", ErrSessionNotFound},
+		{"ErrClientNotFound", ErrClientNotFound},
+		{"ErrAuthorizationRequestNotFound", ErrAuthorizationRequestNotFound},
+		{"ErrAuthorizationOutOfOrder", ErrAuthorizationOutOfOrder},
+		{"ErrInvalidAuthorizationCode", ErrInvalidAuthorizationCode},
+		{"ErrConsentNotFound", ErrConsentNotFound},
 		{"ErrInvalidRequest", ErrInvalidRequest},
 		{"ErrInvalidPhoneNumber", ErrInvalidPhoneNumber},
 		{"ErrMissingRequiredField", ErrMissingRequiredField},
 		{"ErrInvalidOTPFormat", ErrInvalidOTPFormat},
+		{"ErrInvalidChannel", ErrInvalidChannel},
+		{"ErrInvalidOTPPurpose", ErrInvalidOTPPurpose},
 		{"ErrInvalidUUID", ErrInvalidUUID},
 		{"ErrInvalidPagination", ErrInvalidPagination},
 		{"ErrInvalidSearchQuery", ErrInvalidSearchQuery},
+		{"ErrInvalidSort", ErrInvalidSort},
+		{"ErrInvalidCursor", ErrInvalidCursor},
+		{"ErrInvalidFilter", ErrInvalidFilter},
+		{"ErrInvalidProfile", ErrInvalidProfile},
 		{"ErrInternalServer", ErrInternalServer},
 		{"ErrDatabaseError", ErrDatabaseError},
 		{"ErrRedisError", ErrRedisError},
@@ -228,3 +249,20 @@ func TestErrorWithDetails(t *testing.T) {
 		t.Error("WithDetails should return a new error instance")
 	}
 }
+
+func TestErrorWithRetryAfter(t *testing.T) {
+	err := ErrResendCooldown.WithRetryAfter(12)
+
+	if ErrResendCooldown.RetryAfter != 0 {
+		t.Error("Original error should not be modified")
+	}
+	if err.RetryAfter != 12 {
+		t.Errorf("Expected retry after 12, got %d", err.RetryAfter)
+	}
+
+	// Details can be added without losing the retry hint
+	withDetails := err.WithDetails("phone number: +1234567890")
+	if withDetails.RetryAfter != 12 {
+		t.Errorf("Expected WithDetails to keep retry after, got %d", withDetails.RetryAfter)
+	}
+}
@@ -0,0 +1,80 @@
+package errors
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// OAuthError is an error of the OAuth endpoints in the RFC 6749 format,
+// which OAuth client libraries expect instead of a DomainError
+type OAuthError struct {
+	Code        string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+	HTTPStatus  int    `json:"-"`
+}
+
+// Error implements the error interface
+func (e *OAuthError) Error() string {
+	if e.Description != "" {
+		return fmt.Sprintf("%s: %s", e.Code, e.Description)
+	}
+	return e.Code
+}
+
+// NewOAuth creates a new OAuth error
+func NewOAuth(code string, httpStatus int) *OAuthError {
+	return &OAuthError{
+		Code:       code,
+		HTTPStatus: httpStatus,
+	}
+}
+
+// WithDescription adds a human readable description to the error
+func (e *OAuthError) WithDescription(description string) *OAuthError {
+	return &OAuthError{
+		Code:        e.Code,
+		Description: description,
+		HTTPStatus:  e.HTTPStatus,
+	}
+}
+
+// OAuth errors defined by RFC 6749 sections 4.1.2.1 and 5.2
+var (
+	ErrOAuthInvalidRequest          = NewOAuth("invalid_request", http.StatusBadRequest)
+	ErrOAuthInvalidClient           = NewOAuth("invalid_client", http.StatusUnauthorized)
+	ErrOAuthInvalidGrant            = NewOAuth("invalid_grant", http.StatusBadRequest)
+	ErrOAuthUnauthorizedClient      = NewOAuth("unauthorized_client", http.StatusBadRequest)
+	ErrOAuthUnsupportedGrantType    = NewOAuth("unsupported_grant_type", http.StatusBadRequest)
+	ErrOAuthUnsupportedResponseType = NewOAuth("unsupported_response_type", http.StatusBadRequest)
+	ErrOAuthInvalidScope            = NewOAuth("invalid_scope", http.StatusBadRequest)
+	ErrOAuthAccessDenied            = NewOAuth("access_denied", http.StatusForbidden)
+	ErrOAuthServerError             = NewOAuth("server_error", http.StatusInternalServerError)
+)
+
+// invalidGrantCodes are domain errors that mean the presented code or
+// refresh token cannot be used
+var invalidGrantCodes = map[string]bool{
+	ErrInvalidAuthorizationCode.Code: true,
+	ErrInvalidRefreshToken.Code:      true,
+	ErrRefreshTokenReused.Code:       true,
+	ErrUserNotFound.Code:             true,
+}
+
+// GetOAuthError converts an error of the services into an OAuth error
+func GetOAuthError(err error) *OAuthError {
+	var oauthErr *OAuthError
+	if errors.As(err, &oauthErr) {
+		return oauthErr
+	}
+
+	domainErr := GetDomainError(err)
+	switch {
+	case invalidGrantCodes[domainErr.Code]:
+		return ErrOAuthInvalidGrant.WithDescription(domainErr.Message)
+	case domainErr.HTTPStatus >= http.StatusInternalServerError:
+		return ErrOAuthServerError
+	default:
+		return ErrOAuthInvalidRequest.WithDescription(domainErr.Message)
+	}
+}
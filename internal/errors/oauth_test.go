@@ -0,0 +1,46 @@
+package errors
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestGetOAuthError(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantCode   string
+		wantStatus int
+	}{
+		{name: "oauth error", err: ErrOAuthInvalidScope.WithDescription("unknown scope"), wantCode: "invalid_scope", wantStatus: http.StatusBadRequest},
+		{name: "used code", err: ErrInvalidAuthorizationCode, wantCode: "invalid_grant", wantStatus: http.StatusBadRequest},
+		{name: "reused refresh token", err: ErrRefreshTokenReused, wantCode: "invalid_grant", wantStatus: http.StatusBadRequest},
+		{name: "redis failure", err: ErrRedisError.WithDetails("connection refused"), wantCode: "server_error", wantStatus: http.StatusInternalServerError},
+		{name: "plain error", err: errors.New("boom"), wantCode: "server_error", wantStatus: http.StatusInternalServerError},
+		{name: "other domain error", err: ErrRateLimitExceeded, wantCode: "invalid_request", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := GetOAuthError(tt.err)
+			if got.Code != tt.wantCode {
+				t.Errorf("Expected code %s, got %s", tt.wantCode, got.Code)
+			}
+			if got.HTTPStatus != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d", tt.wantStatus, got.HTTPStatus)
+			}
+		})
+	}
+}
+
+func TestOAuthError_WithDescription(t *testing.T) {
+	err := ErrOAuthInvalidGrant.WithDescription("code expired")
+
+	if ErrOAuthInvalidGrant.Description != "" {
+		t.Error("Original error should not be modified")
+	}
+	if got, want := err.Error(), "invalid_grant: code expired"; got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
@@ -2,8 +2,11 @@ package handlers
 
 import (
 	"net/http"
+	"strconv"
+	"strings"
 
 	"otp-auth-service/internal/errors"
+	"otp-auth-service/internal/middleware"
 	"otp-auth-service/internal/models"
 	"otp-auth-service/internal/services"
 	"otp-auth-service/internal/validation"
@@ -23,11 +26,11 @@ func NewAuthHandler(authService *services.AuthService) *AuthHandler {
 
 // RequestOTP godoc
 // @Summary Request OTP for authentication
-// @Description Generate and send OTP to the provided phone number
+// @Description Generate and send a login OTP to the provided phone number. The optional purpose must be login; OTPs for other purposes are requested by signed in users at /users/me/otp.
 // @Tags auth
 // @Accept json
 // @Produce json
-// @Param request body models.RequestOTPRequest true "Phone number"
+// @Param request body models.RequestOTPRequest true "Phone number and optional purpose"
 // @Success 200 {object} models.RequestOTPResponse
 // @Failure 400 {object} map[string]interface{}
 // @Failure 429 {object} map[string]interface{}
@@ -50,7 +53,16 @@ func (h *AuthHandler) RequestOTP(c *gin.Context) {
 		return
 	}
 
-	response, err := h.authService.RequestOTP(req.PhoneNumber)
+	// Validate purpose
+	if err := validation.ValidateLoginOTPPurpose(req.Purpose); err != nil {
+		domainErr := errors.GetDomainError(err)
+		c.JSON(domainErr.HTTPStatus, gin.H{
+			"error": domainErr,
+		})
+		return
+	}
+
+	response, err := h.authService.RequestOTP(req.PhoneNumber, req.Purpose)
 	if err != nil {
 		domainErr := errors.GetDomainError(err)
 		c.JSON(domainErr.HTTPStatus, gin.H{
@@ -62,9 +74,63 @@ func (h *AuthHandler) RequestOTP(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
+// ResendOTP godoc
+// @Summary Resend the pending OTP
+// @Description Re-deliver the still valid OTP, optionally on another channel (sms, voice, whatsapp). Does not issue a new code or count against the request rate limit, but enforces a cooldown between sends.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body models.ResendOTPRequest true "Phone number, optional channel and purpose (login only)"
+// @Success 200 {object} models.ResendOTPResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 429 {object} map[string]interface{}
+// @Router /auth/resend-otp [post]
+func (h *AuthHandler) ResendOTP(c *gin.Context) {
+	var req models.ResendOTPRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(errors.ErrInvalidRequest.HTTPStatus, gin.H{
+			"error": errors.ErrInvalidRequest.WithDetails(err.Error()),
+		})
+		return
+	}
+
+	// Validate phone number and channel
+	if err := validation.ValidateResendOTP(req.PhoneNumber, req.Channel); err != nil {
+		domainErr := errors.GetDomainError(err)
+		c.JSON(domainErr.HTTPStatus, gin.H{
+			"error": domainErr,
+		})
+		return
+	}
+
+	// Validate purpose
+	if err := validation.ValidateLoginOTPPurpose(req.Purpose); err != nil {
+		domainErr := errors.GetDomainError(err)
+		c.JSON(domainErr.HTTPStatus, gin.H{
+			"error": domainErr,
+		})
+		return
+	}
+
+	response, err := h.authService.ResendOTP(req.PhoneNumber, req.Channel, req.Purpose)
+	if err != nil {
+		domainErr := errors.GetDomainError(err)
+		if domainErr.RetryAfter > 0 {
+			c.Header("Retry-After", strconv.Itoa(domainErr.RetryAfter))
+		}
+		c.JSON(domainErr.HTTPStatus, gin.H{
+			"error": domainErr,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
 // VerifyOTP godoc
 // @Summary Verify OTP and authenticate user
-// @Description Verify OTP and return JWT token for authentication
+// @Description Verify a login OTP, start a session and return access and refresh tokens. OTPs issued for other purposes are rejected. The optional device_name labels the session.
 // @Tags auth
 // @Accept json
 // @Produce json
@@ -84,6 +150,9 @@ func (h *AuthHandler) VerifyOTP(c *gin.Context) {
 		return
 	}
 
+	// Alphanumeric codes are case-insensitive for the user
+	req.OTP = strings.ToUpper(strings.TrimSpace(req.OTP))
+
 	// Validate phone number and OTP
 	if err := validation.ValidateVerifyOTP(req.PhoneNumber, req.OTP); err != nil {
 		domainErr := errors.GetDomainError(err)
@@ -93,7 +162,26 @@ func (h *AuthHandler) VerifyOTP(c *gin.Context) {
 		return
 	}
 
-	response, err := h.authService.VerifyOTP(req.PhoneNumber, req.OTP)
+	// Validate purpose
+	if err := validation.ValidateOTPPurpose(req.Purpose); err != nil {
+		domainErr := errors.GetDomainError(err)
+		c.JSON(domainErr.HTTPStatus, gin.H{
+			"error": domainErr,
+		})
+		return
+	}
+
+	// Validate device name
+	req.DeviceName = strings.TrimSpace(req.DeviceName)
+	if err := validation.ValidateDeviceName(req.DeviceName); err != nil {
+		domainErr := errors.GetDomainError(err)
+		c.JSON(domainErr.HTTPStatus, gin.H{
+			"error": domainErr,
+		})
+		return
+	}
+
+	response, err := h.authService.VerifyOTP(req.PhoneNumber, req.OTP, req.Purpose, clientInfo(c, req.DeviceName))
 	if err != nil {
 		domainErr := errors.GetDomainError(err)
 		c.JSON(domainErr.HTTPStatus, gin.H{
@@ -104,3 +192,302 @@ func (h *AuthHandler) VerifyOTP(c *gin.Context) {
 
 	c.JSON(http.StatusOK, response)
 }
+
+// RefreshToken godoc
+// @Summary Refresh access token
+// @Description Exchange a refresh token for a new access token and refresh token. Each refresh token can be used once; reusing a rotated token revokes every token from the same login.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body models.RefreshTokenRequest true "Refresh token"
+// @Success 200 {object} models.TokenResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Router /auth/refresh [post]
+func (h *AuthHandler) RefreshToken(c *gin.Context) {
+	var req models.RefreshTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(errors.ErrInvalidRequest.HTTPStatus, gin.H{
+			"error": errors.ErrInvalidRequest.WithDetails(err.Error()),
+		})
+		return
+	}
+
+	response, err := h.authService.RefreshToken(strings.TrimSpace(req.RefreshToken), clientInfo(c, ""))
+	if err != nil {
+		domainErr := errors.GetDomainError(err)
+		c.JSON(domainErr.HTTPStatus, gin.H{
+			"error": domainErr,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// Logout godoc
+// @Summary Log out the current session
+// @Description Revoke the access token used for this request and all refresh tokens of its session
+// @Tags auth
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Security BearerAuth
+// @Router /auth/logout [post]
+func (h *AuthHandler) Logout(c *gin.Context) {
+	if err := h.authService.Logout(middleware.GetClaims(c)); err != nil {
+		domainErr := errors.GetDomainError(err)
+		c.JSON(domainErr.HTTPStatus, gin.H{
+			"error": domainErr,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out successfully"})
+}
+
+// LogoutAll godoc
+// @Summary Log out all sessions
+// @Description Revoke every access and refresh token issued to the current user
+// @Tags auth
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Security BearerAuth
+// @Router /auth/logout-all [post]
+func (h *AuthHandler) LogoutAll(c *gin.Context) {
+	if err := h.authService.LogoutAll(middleware.GetClaims(c).UserID()); err != nil {
+		domainErr := errors.GetDomainError(err)
+		c.JSON(domainErr.HTTPStatus, gin.H{
+			"error": domainErr,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out from all sessions"})
+}
+
+// DeactivateAccount godoc
+// @Summary Deactivate the current user's account
+// @Description Deactivate the signed in user's account and revoke all of its tokens and sessions. The phone number cannot be used to sign in afterwards. Requires a recent OTP verification (see /auth/step-up).
+// @Tags users
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Security BearerAuth
+// @Router /users/me [delete]
+func (h *AuthHandler) DeactivateAccount(c *gin.Context) {
+	if err := h.authService.DeactivateAccount(middleware.GetClaims(c).UserID()); err != nil {
+		domainErr := errors.GetDomainError(err)
+		c.JSON(domainErr.HTTPStatus, gin.H{
+			"error": domainErr,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Account deactivated"})
+}
+
+// RequestUserOTP godoc
+// @Summary Request an OTP to confirm an account operation
+// @Description Send an OTP for phone_change, account_deletion or transaction_confirm to the signed in user's own phone number. Login OTPs are requested at /auth/request-otp.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param request body models.UserOTPRequest true "OTP purpose"
+// @Success 200 {object} models.RequestOTPResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 429 {object} map[string]interface{}
+// @Security BearerAuth
+// @Router /users/me/otp [post]
+func (h *AuthHandler) RequestUserOTP(c *gin.Context) {
+	var req models.UserOTPRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(errors.ErrInvalidRequest.HTTPStatus, gin.H{
+			"error": errors.ErrInvalidRequest.WithDetails(err.Error()),
+		})
+		return
+	}
+
+	if err := validation.ValidateUserOTPPurpose(req.Purpose); err != nil {
+		domainErr := errors.GetDomainError(err)
+		c.JSON(domainErr.HTTPStatus, gin.H{
+			"error": domainErr,
+		})
+		return
+	}
+
+	response, err := h.authService.RequestUserOTP(middleware.GetClaims(c).UserID(), req.Purpose)
+	if err != nil {
+		domainErr := errors.GetDomainError(err)
+		c.JSON(domainErr.HTTPStatus, gin.H{
+			"error": domainErr,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// ListSessions godoc
+// @Summary List active sessions
+// @Description List the current user's active sessions, most recently used first. The session of the calling token is marked as current.
+// @Tags sessions
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Security BearerAuth
+// @Router /users/me/sessions [get]
+func (h *AuthHandler) ListSessions(c *gin.Context) {
+	claims := middleware.GetClaims(c)
+	sessions, err := h.authService.ListSessions(claims.UserID(), claims.SessionID)
+	if err != nil {
+		domainErr := errors.GetDomainError(err)
+		c.JSON(domainErr.HTTPStatus, gin.H{
+			"error": domainErr,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"sessions": sessions,
+	})
+}
+
+// RevokeSession godoc
+// @Summary Revoke a session
+// @Description Log out one of the current user's sessions, e.g. of a lost phone. Its tokens stop working immediately.
+// @Tags sessions
+// @Produce json
+// @Param id path string true "Session ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Security BearerAuth
+// @Router /users/me/sessions/{id} [delete]
+func (h *AuthHandler) RevokeSession(c *gin.Context) {
+	sessionID := c.Param("id")
+
+	// Validate session ID
+	if err := validation.ValidateRevokeSession(sessionID); err != nil {
+		domainErr := errors.GetDomainError(err)
+		c.JSON(domainErr.HTTPStatus, gin.H{
+			"error": domainErr,
+		})
+		return
+	}
+
+	if err := h.authService.RevokeSession(middleware.GetClaims(c).UserID(), sessionID); err != nil {
+		domainErr := errors.GetDomainError(err)
+		c.JSON(domainErr.HTTPStatus, gin.H{
+			"error": domainErr,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Session revoked successfully"})
+}
+
+// StepUp godoc
+// @Summary Re-verify the current session with an OTP
+// @Description Verify a login OTP (requested with /auth/request-otp) for the signed in user and return a new access token with a fresh auth_time, for endpoints that require a recent verification. The session and its refresh token are kept; later refreshes carry the new auth_time.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body models.StepUpRequest true "OTP"
+// @Success 200 {object} models.TokenResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Security BearerAuth
+// @Router /auth/step-up [post]
+func (h *AuthHandler) StepUp(c *gin.Context) {
+	var req models.StepUpRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(
+			errors.ErrInvalidRequest.HTTPStatus,
+			gin.H{
+				"error": errors.ErrInvalidRequest.WithDetails(err.Error()),
+			})
+		return
+	}
+
+	// Alphanumeric codes are case-insensitive for the user
+	req.OTP = strings.ToUpper(strings.TrimSpace(req.OTP))
+
+	// Validate OTP
+	if err := validation.ValidateOTP(req.OTP); err != nil {
+		domainErr := errors.GetDomainError(err)
+		c.JSON(domainErr.HTTPStatus, gin.H{
+			"error": domainErr,
+		})
+		return
+	}
+
+	response, err := h.authService.StepUp(middleware.GetClaims(c), req.OTP)
+	if err != nil {
+		domainErr := errors.GetDomainError(err)
+		c.JSON(domainErr.HTTPStatus, gin.H{
+			"error": domainErr,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// Verify godoc
+// @Summary Verify a request for a reverse proxy
+// @Description ForwardAuth (Traefik) and auth_request (nginx) endpoint. Validates the bearer token, or the AUTH_COOKIE_NAME cookie when one is configured, and answers 200 with the principal in the X-User-Id, X-User-Phone, X-Session-Id, X-Client-Id and X-Principal-Type headers, or 401. Any method is accepted, since proxies keep the method of the request they check. Service principals get no X-User-* headers.
+// @Tags auth
+// @Success 200
+// @Header 200 {string} X-User-Id "User ID"
+// @Header 200 {string} X-User-Phone "Phone number of the user"
+// @Header 200 {string} X-Principal-Type "user or service"
+// @Failure 401 {object} map[string]interface{}
+// @Security BearerAuth
+// @Router /auth/verify [get]
+// @Router /auth/verify [post]
+// @Router /auth/verify [put]
+// @Router /auth/verify [patch]
+// @Router /auth/verify [delete]
+func (h *AuthHandler) Verify(c *gin.Context) {
+	claims := middleware.GetClaims(c)
+
+	// Proxies must not reuse the answer for another request
+	c.Header("Cache-Control", "no-store")
+	c.Header("X-Principal-Type", claims.Principal())
+	if !claims.IsService() {
+		c.Header("X-User-Id", claims.UserID())
+		c.Header("X-User-Phone", claims.PhoneNumber)
+		c.Header("X-Session-Id", claims.SessionID)
+	}
+	if claims.ClientID != "" {
+		c.Header("X-Client-Id", claims.ClientID)
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// clientInfo describes the client of the current request
+func clientInfo(c *gin.Context, deviceName string) models.ClientInfo {
+	return models.ClientInfo{
+		DeviceName: deviceName,
+		IPAddress:  c.ClientIP(),
+		UserAgent:  c.Request.UserAgent(),
+	}
+}
+
+// JWKS publishes the public keys access tokens are signed with, so other
+// services can verify tokens without being able to issue them. It is served
+// outside the API base path at /.well-known/jwks.json.
+func (h *AuthHandler) JWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, h.authService.JWKS())
+}
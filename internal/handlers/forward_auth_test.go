@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"otp-auth-service/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// signIn signs the test user in with a phone OTP
+func (rp *testRelyingParty) signIn(t *testing.T) *models.VerifyOTPResponse {
+	t.Helper()
+
+	auth := rp.provider.issuer + APIBasePath + "/auth"
+	rp.postJSON(t, auth+"/request-otp", gin.H{"phone_number": testPhoneNumber}, http.StatusOK, nil)
+
+	var response models.VerifyOTPResponse
+	rp.postJSON(t, auth+"/verify-otp", gin.H{"phone_number": testPhoneNumber, "otp": rp.provider.lastOTP(t)}, http.StatusOK, &response)
+
+	return &response
+}
+
+// verify asks the forward auth endpoint about a request, as a proxy would
+func (rp *testRelyingParty) verify(t *testing.T, header, cookie string, status int) http.Header {
+	t.Helper()
+	return rp.verifyMethod(t, http.MethodGet, header, cookie, status)
+}
+
+// verifyMethod asks the forward auth endpoint with the method of the
+// original request, which proxies keep
+func (rp *testRelyingParty) verifyMethod(t *testing.T, method, header, cookie string, status int) http.Header {
+	t.Helper()
+
+	req, err := http.NewRequest(method, rp.provider.issuer+APIBasePath+"/auth/verify", nil)
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	if header != "" {
+		req.Header.Set("Authorization", header)
+	}
+	if cookie != "" {
+		req.AddCookie(&http.Cookie{Name: testTokenCookie, Value: cookie})
+	}
+
+	return rp.do(t, req, status, nil).Header
+}
+
+func TestForwardAuth_Verify(t *testing.T) {
+	provider := newTestProvider(t)
+	rp := newTestRelyingParty(t, provider, "rp", "")
+	login := rp.signIn(t)
+
+	for name, header := range map[string]http.Header{
+		"bearer token": rp.verify(t, "Bearer "+login.Token, "", http.StatusOK),
+		"cookie":       rp.verify(t, "", login.Token, http.StatusOK),
+	} {
+		if got := header.Get("X-User-Id"); got != login.User.ID {
+			t.Errorf("%s: expected X-User-Id %s, got %q", name, login.User.ID, got)
+		}
+		if got := header.Get("X-User-Phone"); got != testPhoneNumber {
+			t.Errorf("%s: expected X-User-Phone %s, got %q", name, testPhoneNumber, got)
+		}
+		if got := header.Get("X-Principal-Type"); got != models.PrincipalUser {
+			t.Errorf("%s: expected X-Principal-Type user, got %q", name, got)
+		}
+		if got := header.Get("Cache-Control"); got != "no-store" {
+			t.Errorf("%s: expected Cache-Control no-store, got %q", name, got)
+		}
+	}
+
+	// Service principals are described without user headers
+	batch := newTestRelyingParty(t, provider, "batch", testClientSecret)
+	header := rp.verify(t, "Bearer "+batch.clientCredentials(t).AccessToken, "", http.StatusOK)
+	if header.Get("X-Principal-Type") != models.PrincipalService || header.Get("X-Client-Id") != "batch" || header.Get("X-User-Id") != "" {
+		t.Errorf("Unexpected service principal headers: %v", header)
+	}
+}
+
+func TestForwardAuth_AnyMethod(t *testing.T) {
+	provider := newTestProvider(t)
+	rp := newTestRelyingParty(t, provider, "rp", "")
+	login := rp.signIn(t)
+
+	for _, method := range []string{http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete, http.MethodHead} {
+		header := rp.verifyMethod(t, method, "Bearer "+login.Token, "", http.StatusOK)
+		if got := header.Get("X-User-Id"); got != login.User.ID {
+			t.Errorf("%s: expected X-User-Id %s, got %q", method, login.User.ID, got)
+		}
+		rp.verifyMethod(t, method, "Bearer not-a-token", "", http.StatusUnauthorized)
+	}
+}
+
+func TestForwardAuth_Rejects(t *testing.T) {
+	provider := newTestProvider(t)
+	rp := newTestRelyingParty(t, provider, "rp", "")
+	login := rp.signIn(t)
+
+	tests := []struct {
+		name   string
+		header string
+		cookie string
+	}{
+		{name: "no credentials"},
+		{name: "malformed header", header: "Token " + login.Token},
+		{name: "invalid token", header: "Bearer not-a-token"},
+		{name: "invalid cookie", cookie: "not-a-token"},
+		// The header wins over the cookie
+		{name: "invalid header with valid cookie", header: "Bearer not-a-token", cookie: login.Token},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := rp.verify(t, tt.header, tt.cookie, http.StatusUnauthorized)
+			if header.Get("X-User-Id") != "" {
+				t.Errorf("Expected no X-User-Id, got %q", header.Get("X-User-Id"))
+			}
+		})
+	}
+
+	// Logged out tokens are rejected
+	rp.verify(t, "Bearer "+login.Token, "", http.StatusOK)
+	req, err := http.NewRequest(http.MethodPost, provider.issuer+APIBasePath+"/auth/logout", nil)
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+login.Token)
+	rp.do(t, req, http.StatusOK, nil)
+	rp.verify(t, "", login.Token, http.StatusUnauthorized)
+}
@@ -0,0 +1,460 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"otp-auth-service/internal/errors"
+	"otp-auth-service/internal/middleware"
+	"otp-auth-service/internal/models"
+	"otp-auth-service/internal/services"
+	"otp-auth-service/internal/validation"
+
+	"github.com/gin-gonic/gin"
+)
+
+type OAuthHandler struct {
+	oauthService *services.OAuthService
+}
+
+func NewOAuthHandler(oauthService *services.OAuthService) *OAuthHandler {
+	return &OAuthHandler{
+		oauthService: oauthService,
+	}
+}
+
+// Authorize godoc
+// @Summary Start an OAuth authorization request
+// @Description Authorization endpoint of the authorization code flow. Public clients must send a S256 code_challenge. With OAUTH_LOGIN_URL set the browser is redirected to the login UI with a request_id; otherwise the request is returned as JSON. Errors are redirected to the client once client_id and redirect_uri are valid.
+// @Tags oauth
+// @Produce json
+// @Param response_type query string true "Must be code"
+// @Param client_id query string true "Client ID"
+// @Param redirect_uri query string true "Registered redirect URI"
+// @Param scope query string false "Space separated scopes, defaults to all scopes of the client"
+// @Param state query string false "Opaque value returned to the client"
+// @Param code_challenge query string false "PKCE code challenge"
+// @Param code_challenge_method query string false "Must be S256"
+// @Param nonce query string false "Value returned in the ID token of openid requests"
+// @Success 200 {object} models.AuthorizationResponse
+// @Success 302
+// @Failure 400 {object} errors.OAuthError
+// @Router /oauth/authorize [get]
+func (h *OAuthHandler) Authorize(c *gin.Context) {
+	var req models.AuthorizeRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(errors.ErrOAuthInvalidRequest.HTTPStatus, errors.ErrOAuthInvalidRequest.WithDescription(err.Error()))
+		return
+	}
+
+	// Without a valid redirect URI there is nowhere safe to send errors to
+	client, err := h.oauthService.ValidateClient(req.ClientID, req.RedirectURI)
+	if err != nil {
+		oauthErr := errors.GetOAuthError(err)
+		c.JSON(oauthErr.HTTPStatus, oauthErr)
+		return
+	}
+
+	response, err := h.oauthService.Authorize(client, &req)
+	if err != nil {
+		c.Redirect(http.StatusFound, h.oauthService.ErrorRedirectURL(req.RedirectURI, req.State, err))
+		return
+	}
+
+	if loginURL := h.oauthService.LoginURL(response.RequestID); loginURL != "" {
+		c.Redirect(http.StatusFound, loginURL)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// GetAuthorization godoc
+// @Summary Get a pending authorization request
+// @Description Return the client, scopes and next step of a pending authorization request, for the login UI
+// @Tags oauth
+// @Produce json
+// @Param id path string true "Authorization request ID"
+// @Success 200 {object} models.AuthorizationResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /oauth/authorize/{id} [get]
+func (h *OAuthHandler) GetAuthorization(c *gin.Context) {
+	requestID := c.Param("id")
+
+	// Validate request ID
+	if err := validation.ValidateAuthorizationRequestID(requestID); err != nil {
+		domainErr := errors.GetDomainError(err)
+		c.JSON(domainErr.HTTPStatus, gin.H{
+			"error": domainErr,
+		})
+		return
+	}
+
+	response, err := h.oauthService.GetAuthorization(requestID)
+	if err != nil {
+		domainErr := errors.GetDomainError(err)
+		c.JSON(domainErr.HTTPStatus, gin.H{
+			"error": domainErr,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// AuthorizeOTP godoc
+// @Summary Send a login OTP for an authorization request
+// @Description Send a login OTP to the phone number signing in for a pending authorization request
+// @Tags oauth
+// @Accept json
+// @Produce json
+// @Param id path string true "Authorization request ID"
+// @Param request body models.AuthorizeOTPRequest true "Phone number"
+// @Success 200 {object} models.RequestOTPResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 429 {object} map[string]interface{}
+// @Router /oauth/authorize/{id}/otp [post]
+func (h *OAuthHandler) AuthorizeOTP(c *gin.Context) {
+	requestID := c.Param("id")
+
+	var req models.AuthorizeOTPRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(errors.ErrInvalidRequest.HTTPStatus, gin.H{
+			"error": errors.ErrInvalidRequest.WithDetails(err.Error()),
+		})
+		return
+	}
+
+	// Validate request ID and phone number
+	if err := validation.ValidateAuthorizationRequestID(requestID); err != nil {
+		domainErr := errors.GetDomainError(err)
+		c.JSON(domainErr.HTTPStatus, gin.H{
+			"error": domainErr,
+		})
+		return
+	}
+	if err := validation.ValidateRequestOTP(req.PhoneNumber); err != nil {
+		domainErr := errors.GetDomainError(err)
+		c.JSON(domainErr.HTTPStatus, gin.H{
+			"error": domainErr,
+		})
+		return
+	}
+
+	response, err := h.oauthService.RequestOTP(requestID, req.PhoneNumber)
+	if err != nil {
+		domainErr := errors.GetDomainError(err)
+		c.JSON(domainErr.HTTPStatus, gin.H{
+			"error": domainErr,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// AuthorizeVerify godoc
+// @Summary Verify the login OTP of an authorization request
+// @Description Sign the user in for a pending authorization request. Returns consent_required if the user has not yet granted the requested scopes, otherwise the redirect_to URL carrying the authorization code.
+// @Tags oauth
+// @Accept json
+// @Produce json
+// @Param id path string true "Authorization request ID"
+// @Param request body models.AuthorizeVerifyRequest true "OTP"
+// @Success 200 {object} models.AuthorizationResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /oauth/authorize/{id}/verify [post]
+func (h *OAuthHandler) AuthorizeVerify(c *gin.Context) {
+	requestID := c.Param("id")
+
+	var req models.AuthorizeVerifyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(errors.ErrInvalidRequest.HTTPStatus, gin.H{
+			"error": errors.ErrInvalidRequest.WithDetails(err.Error()),
+		})
+		return
+	}
+
+	// Alphanumeric codes are case-insensitive for the user
+	req.OTP = strings.ToUpper(strings.TrimSpace(req.OTP))
+
+	// Validate request ID and OTP
+	if err := validation.ValidateAuthorizationRequestID(requestID); err != nil {
+		domainErr := errors.GetDomainError(err)
+		c.JSON(domainErr.HTTPStatus, gin.H{
+			"error": domainErr,
+		})
+		return
+	}
+	if err := validation.ValidateOTP(req.OTP); err != nil {
+		domainErr := errors.GetDomainError(err)
+		c.JSON(domainErr.HTTPStatus, gin.H{
+			"error": domainErr,
+		})
+		return
+	}
+
+	response, err := h.oauthService.VerifyOTP(requestID, req.OTP, clientInfo(c, ""))
+	if err != nil {
+		domainErr := errors.GetDomainError(err)
+		c.JSON(domainErr.HTTPStatus, gin.H{
+			"error": domainErr,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// AuthorizeConsent godoc
+// @Summary Grant or deny consent for an authorization request
+// @Description Record the signed in user's decision on the requested scopes. Returns the redirect_to URL carrying the authorization code, or access_denied when denied.
+// @Tags oauth
+// @Accept json
+// @Produce json
+// @Param id path string true "Authorization request ID"
+// @Param request body models.AuthorizeConsentRequest true "Decision"
+// @Success 200 {object} models.AuthorizationResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /oauth/authorize/{id}/consent [post]
+func (h *OAuthHandler) AuthorizeConsent(c *gin.Context) {
+	requestID := c.Param("id")
+
+	var req models.AuthorizeConsentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(errors.ErrInvalidRequest.HTTPStatus, gin.H{
+			"error": errors.ErrInvalidRequest.WithDetails(err.Error()),
+		})
+		return
+	}
+
+	// Validate request ID
+	if err := validation.ValidateAuthorizationRequestID(requestID); err != nil {
+		domainErr := errors.GetDomainError(err)
+		c.JSON(domainErr.HTTPStatus, gin.H{
+			"error": domainErr,
+		})
+		return
+	}
+
+	response, err := h.oauthService.Consent(requestID, req.Approve, clientInfo(c, ""))
+	if err != nil {
+		domainErr := errors.GetDomainError(err)
+		c.JSON(domainErr.HTTPStatus, gin.H{
+			"error": domainErr,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// Token godoc
+// @Summary OAuth token endpoint
+// @Description Exchange an authorization code (grant_type=authorization_code) or a refresh token (grant_type=refresh_token) for tokens, or get an access token for a machine client itself (grant_type=client_credentials). Confidential clients authenticate with HTTP Basic, client_secret or a private_key_jwt client_assertion; public clients send client_id and, for codes, the PKCE code_verifier.
+// @Tags oauth
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Param grant_type formData string true "authorization_code, refresh_token or client_credentials"
+// @Param code formData string false "Authorization code"
+// @Param redirect_uri formData string false "Redirect URI of the authorization request"
+// @Param code_verifier formData string false "PKCE code verifier"
+// @Param refresh_token formData string false "Refresh token"
+// @Param scope formData string false "Space separated scopes for client_credentials, defaults to all scopes of the client"
+// @Param client_id formData string false "Client ID, unless sent with HTTP Basic"
+// @Param client_secret formData string false "Client secret, unless sent with HTTP Basic"
+// @Param client_assertion_type formData string false "urn:ietf:params:oauth:client-assertion-type:jwt-bearer"
+// @Param client_assertion formData string false "JWT signed with the client's private key"
+// @Success 200 {object} models.OAuthTokenResponse
+// @Failure 400 {object} errors.OAuthError
+// @Failure 401 {object} errors.OAuthError
+// @Router /oauth/token [post]
+func (h *OAuthHandler) Token(c *gin.Context) {
+	// Token responses must not be cached (RFC 6749 section 5.1)
+	c.Header("Cache-Control", "no-store")
+	c.Header("Pragma", "no-cache")
+
+	var req models.TokenRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(errors.ErrOAuthInvalidRequest.HTTPStatus, errors.ErrOAuthInvalidRequest.WithDescription(err.Error()))
+		return
+	}
+
+	if err := bindClientCredentials(c, &req.ClientID, &req.ClientSecret); err != nil {
+		oauthError(c, err)
+		return
+	}
+
+	response, err := h.oauthService.Token(&req, clientInfo(c, ""))
+	if err != nil {
+		oauthError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// Introspect godoc
+// @Summary OAuth token introspection endpoint
+// @Description Report whether an access or refresh token is active, with its claims (RFC 7662). Only confidential clients may introspect; refresh tokens can only be introspected by the client they were issued to. Inactive tokens only return active=false.
+// @Tags oauth
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Param token formData string true "Token to introspect"
+// @Param token_type_hint formData string false "access_token or refresh_token"
+// @Param client_id formData string false "Client ID, unless sent with HTTP Basic"
+// @Param client_secret formData string false "Client secret, unless sent with HTTP Basic"
+// @Param client_assertion_type formData string false "urn:ietf:params:oauth:client-assertion-type:jwt-bearer"
+// @Param client_assertion formData string false "JWT signed with the client's private key"
+// @Success 200 {object} models.IntrospectionResponse
+// @Failure 400 {object} errors.OAuthError
+// @Failure 401 {object} errors.OAuthError
+// @Router /oauth/introspect [post]
+func (h *OAuthHandler) Introspect(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+
+	var req models.IntrospectionRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(errors.ErrOAuthInvalidRequest.HTTPStatus, errors.ErrOAuthInvalidRequest.WithDescription(err.Error()))
+		return
+	}
+
+	if err := bindClientCredentials(c, &req.ClientID, &req.ClientSecret); err != nil {
+		oauthError(c, err)
+		return
+	}
+
+	response, err := h.oauthService.Introspect(&req)
+	if err != nil {
+		oauthError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// Revoke godoc
+// @Summary OAuth token revocation endpoint
+// @Description Revoke an access or refresh token issued to the calling client (RFC 7009). Revoking a refresh token ends its session. Unknown tokens are ignored, so the response is the same whether or not the token existed.
+// @Tags oauth
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Param token formData string true "Token to revoke"
+// @Param token_type_hint formData string false "access_token or refresh_token"
+// @Param client_id formData string false "Client ID, unless sent with HTTP Basic"
+// @Param client_secret formData string false "Client secret, unless sent with HTTP Basic"
+// @Param client_assertion_type formData string false "urn:ietf:params:oauth:client-assertion-type:jwt-bearer"
+// @Param client_assertion formData string false "JWT signed with the client's private key"
+// @Success 200
+// @Failure 400 {object} errors.OAuthError
+// @Failure 401 {object} errors.OAuthError
+// @Router /oauth/revoke [post]
+func (h *OAuthHandler) Revoke(c *gin.Context) {
+	var req models.RevocationRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(errors.ErrOAuthInvalidRequest.HTTPStatus, errors.ErrOAuthInvalidRequest.WithDescription(err.Error()))
+		return
+	}
+
+	if err := bindClientCredentials(c, &req.ClientID, &req.ClientSecret); err != nil {
+		oauthError(c, err)
+		return
+	}
+
+	if err := h.oauthService.Revoke(&req); err != nil {
+		oauthError(c, err)
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// bindClientCredentials takes client credentials sent with HTTP Basic
+// instead of in the form. They are form encoded (RFC 6749 section 2.3.1).
+func bindClientCredentials(c *gin.Context, clientID, clientSecret *string) error {
+	username, password, ok := c.Request.BasicAuth()
+	if !ok {
+		return nil
+	}
+
+	if *clientSecret != "" {
+		return errors.ErrOAuthInvalidRequest.WithDescription("use only one client authentication method")
+	}
+
+	id, err1 := url.QueryUnescape(username)
+	secret, err2 := url.QueryUnescape(password)
+	if err1 != nil || err2 != nil || (*clientID != "" && *clientID != id) {
+		return errors.ErrOAuthInvalidClient.WithDescription("malformed client credentials")
+	}
+	*clientID, *clientSecret = id, secret
+
+	return nil
+}
+
+// oauthError sends an RFC 6749 error response, challenging the client to
+// authenticate when its credentials were rejected
+func oauthError(c *gin.Context, err error) {
+	oauthErr := errors.GetOAuthError(err)
+	if oauthErr.HTTPStatus == http.StatusUnauthorized {
+		c.Header("WWW-Authenticate", `Basic realm="oauth"`)
+	}
+	c.JSON(oauthErr.HTTPStatus, oauthErr)
+}
+
+// ListConsents godoc
+// @Summary List OAuth consents
+// @Description List the OAuth clients the current user has granted access to, with the granted scopes
+// @Tags oauth
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Security BearerAuth
+// @Router /users/me/consents [get]
+func (h *OAuthHandler) ListConsents(c *gin.Context) {
+	consents, err := h.oauthService.ListConsents(middleware.GetClaims(c).UserID())
+	if err != nil {
+		domainErr := errors.GetDomainError(err)
+		c.JSON(domainErr.HTTPStatus, gin.H{
+			"error": domainErr,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"consents": consents,
+	})
+}
+
+// RevokeConsent godoc
+// @Summary Revoke an OAuth consent
+// @Description Withdraw the current user's consent for a client and log out the client's sessions. The client has to ask for consent again.
+// @Tags oauth
+// @Produce json
+// @Param client_id path string true "Client ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Security BearerAuth
+// @Router /users/me/consents/{client_id} [delete]
+func (h *OAuthHandler) RevokeConsent(c *gin.Context) {
+	if err := h.oauthService.RevokeConsent(middleware.GetClaims(c).UserID(), c.Param("client_id")); err != nil {
+		domainErr := errors.GetDomainError(err)
+		c.JSON(domainErr.HTTPStatus, gin.H{
+			"error": domainErr,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Consent revoked successfully"})
+}
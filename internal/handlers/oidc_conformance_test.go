@@ -0,0 +1,602 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"otp-auth-service/internal/config"
+	"otp-auth-service/internal/delivery"
+	"otp-auth-service/internal/middleware"
+	"otp-auth-service/internal/models"
+	"otp-auth-service/internal/otp"
+	"otp-auth-service/internal/repository"
+	"otp-auth-service/internal/services"
+	"otp-auth-service/internal/signing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/redis/go-redis/v9"
+)
+
+// The tests in this file play an OpenID Connect relying party against the
+// provider served over HTTP, following the checks of OpenID Connect Core
+// 1.0 that a conforming client makes.
+
+const (
+	testPhoneNumber  = "+1234567890"
+	testRedirectURI  = "https://rp.example.com/callback"
+	testClientSecret = "rp-s3cret"
+	testTokenCookie  = "access_token"
+)
+
+type testProvider struct {
+	issuer string
+	outbox string
+	// keyring signs the provider's tokens, for tests that need tokens the
+	// API would not issue
+	keyring *signing.Keyring
+	// users is the provider's user store, for tests that need many users
+	users repository.UserRepository
+}
+
+// newTestProvider serves the API with an RS256 keyring and three clients:
+// a public relying party, a confidential one and a plain OAuth client
+// that may not request openid but may read the user's account. Two
+// machine clients use client_credentials, one of them without the
+// users:read scope.
+func newTestProvider(t *testing.T) *testProvider {
+	t.Helper()
+
+	pemData, err := signing.GenerateKey(signing.AlgorithmRS256)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	key, err := signing.ParseKey(signing.AlgorithmRS256, pemData)
+	if err != nil {
+		t.Fatalf("Failed to parse key: %v", err)
+	}
+
+	return newTestProviderWithKey(t, key)
+}
+
+// newTestProviderWithKey serves the API of newTestProvider, signing tokens
+// with key
+func newTestProviderWithKey(t *testing.T, key *signing.Key) *testProvider {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	keyring, err := signing.NewStaticKeyring(key)
+	if err != nil {
+		t.Fatalf("Failed to create keyring: %v", err)
+	}
+
+	generator, err := otp.NewGenerator(6, otp.AlphabetNumeric)
+	if err != nil {
+		t.Fatalf("Failed to create generator: %v", err)
+	}
+	sealer, err := otp.NewSealer("test-secret")
+	if err != nil {
+		t.Fatalf("Failed to create sealer: %v", err)
+	}
+	policies := make(map[models.OTPPurpose]config.OTPPolicy)
+	for _, purpose := range models.OTPPurposes {
+		policies[purpose] = config.DefaultOTPPolicy()
+	}
+
+	// The issuer is the server's own URL, so it must be known before the
+	// server starts
+	server := httptest.NewUnstartedServer(nil)
+	issuer := "http://" + server.Listener.Addr().String()
+	outbox := filepath.Join(t.TempDir(), "outbox.log")
+
+	userRepo := repository.NewUserRepository()
+	authService := services.NewAuthService(
+		userRepo,
+		repository.NewOTPRepository(client, generator, otp.NewHasher("test-secret"), sealer, policies),
+		repository.NewTokenRepository(client),
+		repository.NewRevocationRepository(client),
+		repository.NewSessionRepository(client),
+		delivery.NewFileSender(outbox),
+		services.AuthConfig{
+			Keyring:         keyring,
+			Issuer:          issuer,
+			Audiences:       []string{"api"},
+			AccessTokenTTL:  15 * time.Minute,
+			RefreshTokenTTL: time.Hour,
+		},
+	)
+
+	secretHash := sha256.Sum256([]byte(testClientSecret))
+	clients := repository.NewClientRepository([]*models.OAuthClient{
+		{ID: "rp", Name: "Relying Party", RedirectURIs: []string{testRedirectURI}, Scopes: []string{"openid", "profile"}},
+		{ID: "rp-confidential", Name: "Backend RP", SecretHash: hex.EncodeToString(secretHash[:]), RedirectURIs: []string{testRedirectURI}, Scopes: []string{"openid"}},
+		{ID: "oauth-only", Name: "API Client", RedirectURIs: []string{testRedirectURI}, Scopes: []string{"profile", models.ScopeAccountRead}, Trusted: true},
+		{ID: "batch", Name: "Batch Jobs", SecretHash: hex.EncodeToString(secretHash[:]), GrantTypes: []string{models.GrantTypeClientCredentials}, Scopes: []string{models.ScopeUsersRead}},
+		{ID: "reporter", Name: "Reports", SecretHash: hex.EncodeToString(secretHash[:]), GrantTypes: []string{models.GrantTypeClientCredentials}, Scopes: []string{"reports"}},
+	})
+	oauthService := services.NewOAuthService(authService, clients,
+		repository.NewAuthorizationRepository(client),
+		repository.NewConsentRepository(client),
+		repository.NewAssertionRepository(client),
+		services.OAuthConfig{
+			Issuer:           issuer,
+			APIURL:           issuer + APIBasePath,
+			AuthorizationTTL: 10 * time.Minute,
+			CodeTTL:          time.Minute,
+		},
+	)
+	userService := services.NewUserService(userRepo)
+
+	router := gin.New()
+	RegisterRoutes(router,
+		NewAuthHandler(authService),
+		NewOAuthHandler(oauthService),
+		NewOIDCHandler(oauthService, userService),
+		NewUserHandler(userService),
+		middleware.AuthMiddleware(authService),
+		middleware.AuthMiddleware(authService, middleware.WithTokenCookie(testTokenCookie)),
+	)
+
+	server.Config.Handler = router
+	server.Start()
+	t.Cleanup(server.Close)
+
+	return &testProvider{issuer: issuer, outbox: outbox, keyring: keyring, users: userRepo}
+}
+
+// lastOTP returns the code of the most recently delivered OTP
+func (p *testProvider) lastOTP(t *testing.T) string {
+	t.Helper()
+
+	records, err := delivery.ReadFile(p.outbox)
+	if err != nil || len(records) == 0 {
+		t.Fatalf("Expected OTP to be delivered, got %v", err)
+	}
+	body := records[len(records)-1].Body
+	return body[len(body)-6:]
+}
+
+// testRelyingParty is an OpenID Connect client configured from discovery
+type testRelyingParty struct {
+	provider     *testProvider
+	clientID     string
+	clientSecret string
+	metadata     *models.DiscoveryDocument
+	http         *http.Client
+}
+
+func newTestRelyingParty(t *testing.T, provider *testProvider, clientID, clientSecret string) *testRelyingParty {
+	t.Helper()
+
+	rp := &testRelyingParty{
+		provider:     provider,
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		// The redirect URI is not served; the RP reads the Location header
+		http: &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		}},
+	}
+	rp.discover(t)
+
+	return rp
+}
+
+// discover loads the provider metadata (OpenID Connect Discovery 1.0 section 4)
+func (rp *testRelyingParty) discover(t *testing.T) {
+	t.Helper()
+
+	var metadata models.DiscoveryDocument
+	rp.getJSON(t, rp.provider.issuer+"/.well-known/openid-configuration", http.StatusOK, &metadata)
+
+	// The issuer in the document must be the one it was fetched from
+	if metadata.Issuer != rp.provider.issuer {
+		t.Fatalf("Expected issuer %s, got %s", rp.provider.issuer, metadata.Issuer)
+	}
+	rp.metadata = &metadata
+}
+
+// authenticate runs the authorization code flow with PKCE and returns the
+// code. The login UI steps are driven through the JSON API.
+func (rp *testRelyingParty) authenticate(t *testing.T, scope, nonce string) (code, verifier string) {
+	t.Helper()
+
+	verifier = randomString(t)
+	sum := sha256.Sum256([]byte(verifier))
+	state := randomString(t)
+
+	params := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {rp.clientID},
+		"redirect_uri":          {testRedirectURI},
+		"scope":                 {scope},
+		"state":                 {state},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(sum[:])},
+		"code_challenge_method": {"S256"},
+	}
+	if nonce != "" {
+		params.Set("nonce", nonce)
+	}
+
+	var pending models.AuthorizationResponse
+	rp.getJSON(t, rp.metadata.AuthorizationEndpoint+"?"+params.Encode(), http.StatusOK, &pending)
+
+	steps := rp.provider.issuer + APIBasePath + "/oauth/authorize/" + pending.RequestID
+	rp.postJSON(t, steps+"/otp", gin.H{"phone_number": testPhoneNumber}, http.StatusOK, nil)
+
+	var response models.AuthorizationResponse
+	rp.postJSON(t, steps+"/verify", gin.H{"otp": rp.provider.lastOTP(t)}, http.StatusOK, &response)
+	if response.Status == models.AuthorizationConsentRequired {
+		rp.postJSON(t, steps+"/consent", gin.H{"approve": true}, http.StatusOK, &response)
+	}
+	if response.Status != models.AuthorizationComplete {
+		t.Fatalf("Expected %s, got %s", models.AuthorizationComplete, response.Status)
+	}
+
+	redirect, err := url.Parse(response.RedirectTo)
+	if err != nil {
+		t.Fatalf("Failed to parse redirect: %v", err)
+	}
+	query := redirect.Query()
+	if got := query.Get("state"); got != state {
+		t.Fatalf("Expected state %s, got %s", state, got)
+	}
+	if got := query.Get("iss"); got != rp.metadata.Issuer {
+		t.Fatalf("Expected iss %s, got %s", rp.metadata.Issuer, got)
+	}
+	if query.Get("code") == "" {
+		t.Fatalf("Expected code in %s", response.RedirectTo)
+	}
+
+	return query.Get("code"), verifier
+}
+
+// exchange redeems a code at the token endpoint
+func (rp *testRelyingParty) exchange(t *testing.T, code, verifier string) *models.OAuthTokenResponse {
+	t.Helper()
+
+	form := url.Values{
+		"grant_type":    {models.GrantTypeAuthorizationCode},
+		"code":          {code},
+		"redirect_uri":  {testRedirectURI},
+		"code_verifier": {verifier},
+	}
+	// Public clients identify themselves in the body
+	if rp.clientSecret == "" {
+		form.Set("client_id", rp.clientID)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, rp.metadata.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if rp.clientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(rp.clientID), url.QueryEscape(rp.clientSecret))
+	}
+
+	var tokens models.OAuthTokenResponse
+	rp.do(t, req, http.StatusOK, &tokens)
+
+	return &tokens
+}
+
+// verifyIDToken validates an ID token as OpenID Connect Core 1.0 section
+// 3.1.3.7 requires and returns its claims
+func (rp *testRelyingParty) verifyIDToken(t *testing.T, raw, nonce string) *models.IDTokenClaims {
+	t.Helper()
+
+	var jwks signing.JWKS
+	rp.getJSON(t, rp.metadata.JWKSURI, http.StatusOK, &jwks)
+
+	claims := &models.IDTokenClaims{}
+	_, err := jwt.ParseWithClaims(raw, claims, func(token *jwt.Token) (interface{}, error) {
+		for _, jwk := range jwks.Keys {
+			if jwk.KeyID == token.Header["kid"] {
+				return rsaPublicKey(jwk)
+			}
+		}
+		return nil, fmt.Errorf("unknown kid %v", token.Header["kid"])
+	},
+		jwt.WithValidMethods(rp.metadata.IDTokenSigningAlgValuesSupported),
+		jwt.WithIssuer(rp.metadata.Issuer),
+		jwt.WithAudience(rp.clientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+	)
+	if err != nil {
+		t.Fatalf("Expected valid ID token, got %v", err)
+	}
+
+	if claims.Subject == "" {
+		t.Error("Expected sub claim")
+	}
+	if claims.AuthorizedParty != rp.clientID {
+		t.Errorf("Expected azp %s, got %s", rp.clientID, claims.AuthorizedParty)
+	}
+	if claims.Nonce != nonce {
+		t.Errorf("Expected nonce %q, got %q", nonce, claims.Nonce)
+	}
+	if claims.AuthTime == nil || claims.AuthTime.After(time.Now()) {
+		t.Errorf("Expected auth_time in the past, got %v", claims.AuthTime)
+	}
+	if claims.ACR != models.ACRPhoneOTP {
+		t.Errorf("Expected acr %s, got %q", models.ACRPhoneOTP, claims.ACR)
+	}
+
+	return claims
+}
+
+func (rp *testRelyingParty) userInfo(t *testing.T, method, accessToken string, status int, v interface{}) *http.Response {
+	t.Helper()
+
+	req, err := http.NewRequest(method, rp.metadata.UserInfoEndpoint, nil)
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+
+	return rp.do(t, req, status, v)
+}
+
+func (rp *testRelyingParty) getJSON(t *testing.T, rawURL string, status int, v interface{}) {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodGet, rawURL, nil)
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	rp.do(t, req, status, v)
+}
+
+func (rp *testRelyingParty) postJSON(t *testing.T, rawURL string, body interface{}, status int, v interface{}) {
+	t.Helper()
+
+	data, err := json.Marshal(body)
+	if err != nil {
+		t.Fatalf("Failed to encode body: %v", err)
+	}
+	req, err := http.NewRequest(http.MethodPost, rawURL, bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	rp.do(t, req, status, v)
+}
+
+// do sends a request, checks its status and decodes the JSON body into v
+func (rp *testRelyingParty) do(t *testing.T, req *http.Request, status int, v interface{}) *http.Response {
+	t.Helper()
+
+	resp, err := rp.http.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s failed: %v", req.Method, req.URL, err)
+	}
+	defer resp.Body.Close()
+
+	var body bytes.Buffer
+	body.ReadFrom(resp.Body)
+	if resp.StatusCode != status {
+		t.Fatalf("%s %s: expected status %d, got %d: %s", req.Method, req.URL.Path, status, resp.StatusCode, body.String())
+	}
+	if v != nil {
+		if err := json.Unmarshal(body.Bytes(), v); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+	}
+
+	return resp
+}
+
+func rsaPublicKey(jwk signing.JWK) (*rsa.PublicKey, error) {
+	if jwk.KeyType != "RSA" {
+		return nil, fmt.Errorf("unexpected key type %s", jwk.KeyType)
+	}
+	n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+	if err != nil {
+		return nil, err
+	}
+	e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+	if err != nil {
+		return nil, err
+	}
+
+	return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+}
+
+// randomString returns a value usable as state, nonce or PKCE verifier
+func randomString(t *testing.T) string {
+	t.Helper()
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		t.Fatalf("Failed to read random bytes: %v", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func TestOIDC_Discovery(t *testing.T) {
+	provider := newTestProvider(t)
+	rp := newTestRelyingParty(t, provider, "rp", "")
+	metadata := rp.metadata
+
+	for name, value := range map[string]string{
+		"authorization_endpoint": metadata.AuthorizationEndpoint,
+		"token_endpoint":         metadata.TokenEndpoint,
+		"userinfo_endpoint":      metadata.UserInfoEndpoint,
+		"introspection_endpoint": metadata.IntrospectionEndpoint,
+		"revocation_endpoint":    metadata.RevocationEndpoint,
+		"jwks_uri":               metadata.JWKSURI,
+	} {
+		if !strings.HasPrefix(value, provider.issuer+"/") {
+			t.Errorf("Expected %s under the issuer, got %s", name, value)
+		}
+	}
+
+	required := map[string]struct {
+		values []string
+		want   string
+	}{
+		"scopes_supported":                      {metadata.ScopesSupported, "openid"},
+		"response_types_supported":              {metadata.ResponseTypesSupported, "code"},
+		"subject_types_supported":               {metadata.SubjectTypesSupported, "public"},
+		"id_token_signing_alg_values_supported": {metadata.IDTokenSigningAlgValuesSupported, "RS256"},
+		"code_challenge_methods_supported":      {metadata.CodeChallengeMethodsSupported, "S256"},
+		"claims_supported":                      {metadata.ClaimsSupported, "phone_number_verified"},
+	}
+	for name, field := range required {
+		if !models.ContainsScopes(field.values, []string{field.want}) {
+			t.Errorf("Expected %s to contain %s, got %v", name, field.want, field.values)
+		}
+	}
+}
+
+func TestOIDC_AuthorizationCodeFlow(t *testing.T) {
+	provider := newTestProvider(t)
+	rp := newTestRelyingParty(t, provider, "rp", "")
+
+	nonce := randomString(t)
+	code, verifier := rp.authenticate(t, "openid profile", nonce)
+	tokens := rp.exchange(t, code, verifier)
+
+	if tokens.IDToken == "" {
+		t.Fatal("Expected id_token in the token response")
+	}
+	if tokens.TokenType != "Bearer" {
+		t.Errorf("Expected token type Bearer, got %s", tokens.TokenType)
+	}
+
+	claims := rp.verifyIDToken(t, tokens.IDToken, nonce)
+	if claims.PhoneNumber != testPhoneNumber {
+		t.Errorf("Expected phone number %s, got %s", testPhoneNumber, claims.PhoneNumber)
+	}
+	if !claims.PhoneNumberVerified {
+		t.Error("Expected phone number to be verified")
+	}
+
+	// The userinfo endpoint accepts GET and POST and must return the same
+	// subject as the ID token
+	for _, method := range []string{http.MethodGet, http.MethodPost} {
+		var userInfo models.UserInfoResponse
+		rp.userInfo(t, method, tokens.AccessToken, http.StatusOK, &userInfo)
+
+		if userInfo.Subject != claims.Subject {
+			t.Errorf("%s: expected sub %s, got %s", method, claims.Subject, userInfo.Subject)
+		}
+		if userInfo.PhoneNumber != testPhoneNumber || !userInfo.PhoneNumberVerified {
+			t.Errorf("%s: expected verified phone number %s, got %+v", method, testPhoneNumber, userInfo)
+		}
+	}
+}
+
+func TestOIDC_ConfidentialClientWithoutNonce(t *testing.T) {
+	provider := newTestProvider(t)
+	rp := newTestRelyingParty(t, provider, "rp-confidential", testClientSecret)
+
+	code, verifier := rp.authenticate(t, "openid", "")
+	tokens := rp.exchange(t, code, verifier)
+
+	rp.verifyIDToken(t, tokens.IDToken, "")
+}
+
+func TestOIDC_NoIDTokenWithoutOpenIDScope(t *testing.T) {
+	provider := newTestProvider(t)
+	rp := newTestRelyingParty(t, provider, "oauth-only", "")
+
+	code, verifier := rp.authenticate(t, "profile", "")
+	tokens := rp.exchange(t, code, verifier)
+
+	if tokens.IDToken != "" {
+		t.Error("Expected no id_token without the openid scope")
+	}
+
+	resp := rp.userInfo(t, http.MethodGet, tokens.AccessToken, http.StatusForbidden, nil)
+	if got := resp.Header.Get("WWW-Authenticate"); !strings.Contains(got, `error="insufficient_scope"`) {
+		t.Errorf("Expected insufficient_scope challenge, got %q", got)
+	}
+}
+
+func TestOIDC_RefusedWithHS256(t *testing.T) {
+	key, err := signing.NewHMACKey("test-secret")
+	if err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	provider := newTestProviderWithKey(t, key)
+	rp := newTestRelyingParty(t, provider, "rp", "")
+
+	// Relying parties could not verify ID tokens signed with the secret
+	if len(rp.metadata.ScopesSupported) != 0 || len(rp.metadata.IDTokenSigningAlgValuesSupported) != 0 {
+		t.Errorf("Expected no OpenID Connect support advertised, got %v and %v",
+			rp.metadata.ScopesSupported, rp.metadata.IDTokenSigningAlgValuesSupported)
+	}
+
+	params := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {"rp"},
+		"redirect_uri":          {testRedirectURI},
+		"scope":                 {"openid profile"},
+		"state":                 {"xyz"},
+		"code_challenge":        {randomString(t)},
+		"code_challenge_method": {"S256"},
+	}
+	req, err := http.NewRequest(http.MethodGet, rp.metadata.AuthorizationEndpoint+"?"+params.Encode(), nil)
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	resp := rp.do(t, req, http.StatusFound, nil)
+	redirect, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil || redirect.Query().Get("error") != "invalid_scope" {
+		t.Errorf("Expected invalid_scope redirect, got %q", resp.Header.Get("Location"))
+	}
+
+	// Plain OAuth keeps working
+	code, verifier := rp.authenticate(t, "profile", "")
+	if tokens := rp.exchange(t, code, verifier); tokens.AccessToken == "" || tokens.IDToken != "" {
+		t.Errorf("Expected an access token without id_token, got %+v", tokens)
+	}
+}
+
+func TestOIDC_UserInfoRejectsInvalidTokens(t *testing.T) {
+	provider := newTestProvider(t)
+	rp := newTestRelyingParty(t, provider, "rp", "")
+
+	code, verifier := rp.authenticate(t, "openid", "")
+	tokens := rp.exchange(t, code, verifier)
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{name: "malformed token", token: "not-a-token"},
+		// ID tokens are addressed to the client, not to the API
+		{name: "ID token", token: tokens.IDToken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := rp.userInfo(t, http.MethodGet, tt.token, http.StatusUnauthorized, nil)
+			if got := resp.Header.Get("WWW-Authenticate"); !strings.Contains(got, `error="invalid_token"`) {
+				t.Errorf("Expected invalid_token challenge, got %q", got)
+			}
+		})
+	}
+}
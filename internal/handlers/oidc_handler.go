@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"otp-auth-service/internal/errors"
+	"otp-auth-service/internal/middleware"
+	"otp-auth-service/internal/models"
+	"otp-auth-service/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type OIDCHandler struct {
+	oauthService *services.OAuthService
+	userService  *services.UserService
+}
+
+func NewOIDCHandler(oauthService *services.OAuthService, userService *services.UserService) *OIDCHandler {
+	return &OIDCHandler{
+		oauthService: oauthService,
+		userService:  userService,
+	}
+}
+
+// Discovery publishes the OpenID Provider metadata, so relying parties can
+// configure themselves from the issuer URL. It is served outside the API
+// base path at /.well-known/openid-configuration.
+func (h *OIDCHandler) Discovery(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, h.oauthService.Discovery())
+}
+
+// UserInfo godoc
+// @Summary OpenID Connect userinfo endpoint
+// @Description Return the claims of the user an access token was issued to. Tokens issued to OAuth clients must grant the openid scope.
+// @Tags oauth
+// @Produce json
+// @Success 200 {object} models.UserInfoResponse
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Security BearerAuth
+// @Router /oauth/userinfo [get]
+// @Router /oauth/userinfo [post]
+func (h *OIDCHandler) UserInfo(c *gin.Context) {
+	claims := middleware.GetClaims(c)
+
+	// First-party tokens carry no scope and may read the user's own claims
+	if claims.ClientID != "" && !models.ContainsScopes(models.ParseScope(claims.Scope), []string{models.ScopeOpenID}) {
+		c.Header("WWW-Authenticate", fmt.Sprintf(`Bearer error="insufficient_scope", scope="%s"`, models.ScopeOpenID))
+		c.JSON(errors.ErrInsufficientScope.HTTPStatus, gin.H{
+			"error": errors.ErrInsufficientScope.WithDetails("openid scope is required"),
+		})
+		return
+	}
+
+	userInfo, err := h.userService.GetUserInfo(claims.UserID())
+	if err != nil {
+		domainErr := errors.GetDomainError(err)
+		c.JSON(domainErr.HTTPStatus, gin.H{
+			"error": domainErr,
+		})
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, userInfo)
+}
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"time"
+
+	"otp-auth-service/internal/middleware"
+	"otp-auth-service/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APIBasePath is the path the API routes are served under
+const APIBasePath = "/api/v1"
+
+// FreshAuthMaxAge is how recently users must have verified an OTP for
+// sensitive endpoints; older sessions step up at /auth/step-up first
+const FreshAuthMaxAge = 5 * time.Minute
+
+// RegisterRoutes mounts the API routes and the /.well-known documents.
+// verifyMiddleware authenticates /auth/verify; unlike authMiddleware it
+// may accept a token cookie, as proxies forward browser requests there.
+func RegisterRoutes(router gin.IRouter, authHandler *AuthHandler, oauthHandler *OAuthHandler, oidcHandler *OIDCHandler, userHandler *UserHandler, authMiddleware, verifyMiddleware gin.HandlerFunc) {
+	api := router.Group(APIBasePath)
+	{
+		// Auth routes
+		auth := api.Group("/auth")
+		{
+			auth.POST("/request-otp", authHandler.RequestOTP)
+			auth.POST("/resend-otp", authHandler.ResendOTP)
+			auth.POST("/verify-otp", authHandler.VerifyOTP)
+			auth.POST("/refresh", authHandler.RefreshToken)
+			auth.POST("/logout", authMiddleware, middleware.RequireUser(), authHandler.Logout)
+			auth.POST("/logout-all", authMiddleware, middleware.RequireUser(), middleware.RequireScope(models.ScopeAccountWrite), authHandler.LogoutAll)
+			auth.POST("/step-up", authMiddleware, middleware.RequireUser(), authHandler.StepUp)
+			// Proxies ask with the method of the request they check
+			auth.Any("/verify", verifyMiddleware, authHandler.Verify)
+		}
+
+		// OAuth and OpenID Connect routes
+		oauth := api.Group("/oauth")
+		{
+			oauth.GET("/authorize", oauthHandler.Authorize)
+			oauth.GET("/authorize/:id", oauthHandler.GetAuthorization)
+			oauth.POST("/authorize/:id/otp", oauthHandler.AuthorizeOTP)
+			oauth.POST("/authorize/:id/verify", oauthHandler.AuthorizeVerify)
+			oauth.POST("/authorize/:id/consent", oauthHandler.AuthorizeConsent)
+			oauth.POST("/token", oauthHandler.Token)
+			oauth.POST("/introspect", oauthHandler.Introspect)
+			oauth.POST("/revoke", oauthHandler.Revoke)
+			oauth.GET("/userinfo", authMiddleware, middleware.RequireUser(), oidcHandler.UserInfo)
+			oauth.POST("/userinfo", authMiddleware, middleware.RequireUser(), oidcHandler.UserInfo)
+		}
+
+		// User routes (protected). Service principals and OAuth clients
+		// need the users:read scope to read users; users viewing other
+		// users' data need a recent OTP verification. Users manage their
+		// own account under /me, which OAuth clients reach with the
+		// account:read and account:write scopes.
+		users := api.Group("/users")
+		users.Use(authMiddleware)
+		{
+			users.GET("/", middleware.RequireScope(models.ScopeUsersRead), middleware.RequireFreshAuth(FreshAuthMaxAge), userHandler.GetUsers)
+			users.GET("/:id", middleware.RequireScope(models.ScopeUsersRead), middleware.RequireFreshAuth(FreshAuthMaxAge), userHandler.GetUser)
+
+			readAccount := middleware.RequireScope(models.ScopeAccountRead)
+			writeAccount := middleware.RequireScope(models.ScopeAccountWrite)
+			me := users.Group("/me", middleware.RequireUser())
+			me.GET("", readAccount, userHandler.GetMe)
+			me.PATCH("", writeAccount, userHandler.UpdateMe)
+			me.DELETE("", writeAccount, middleware.RequireFreshAuth(FreshAuthMaxAge), authHandler.DeactivateAccount)
+			me.POST("/otp", writeAccount, authHandler.RequestUserOTP)
+			me.GET("/sessions", readAccount, authHandler.ListSessions)
+			me.DELETE("/sessions/:id", writeAccount, authHandler.RevokeSession)
+			me.GET("/consents", readAccount, oauthHandler.ListConsents)
+			me.DELETE("/consents/:client_id", writeAccount, oauthHandler.RevokeConsent)
+		}
+	}
+
+	// Public keys for token verification and OpenID Provider metadata
+	router.GET("/.well-known/jwks.json", authHandler.JWKS)
+	router.GET("/.well-known/openid-configuration", oidcHandler.Discovery)
+}
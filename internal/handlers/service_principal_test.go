@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"otp-auth-service/internal/errors"
+	"otp-auth-service/internal/models"
+)
+
+// clientCredentials gets an access token for a machine client
+func (rp *testRelyingParty) clientCredentials(t *testing.T) *models.OAuthTokenResponse {
+	t.Helper()
+
+	form := url.Values{"grant_type": {models.GrantTypeClientCredentials}}
+	req, err := http.NewRequest(http.MethodPost, rp.metadata.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(url.QueryEscape(rp.clientID), url.QueryEscape(rp.clientSecret))
+
+	var tokens models.OAuthTokenResponse
+	rp.do(t, req, http.StatusOK, &tokens)
+
+	return &tokens
+}
+
+// bearer sends a request authenticated with accessToken
+func (rp *testRelyingParty) bearer(t *testing.T, method, path, accessToken string, status int, v interface{}) *http.Response {
+	t.Helper()
+
+	req, err := http.NewRequest(method, rp.provider.issuer+APIBasePath+path, nil)
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+
+	return rp.do(t, req, status, v)
+}
+
+func TestServicePrincipal_Access(t *testing.T) {
+	provider := newTestProvider(t)
+	rp := newTestRelyingParty(t, provider, "batch", testClientSecret)
+
+	tokens := rp.clientCredentials(t)
+	if tokens.RefreshToken != "" || tokens.Scope != models.ScopeUsersRead {
+		t.Errorf("Expected a users:read access token only, got %+v", tokens)
+	}
+
+	rp.bearer(t, http.MethodGet, "/users/", tokens.AccessToken, http.StatusOK, nil)
+
+	// Endpoints acting on the signed in user are closed to services
+	for _, route := range []struct{ method, path string }{
+		{http.MethodGet, "/users/me/sessions"},
+		{http.MethodGet, "/users/me/consents"},
+		{http.MethodPost, "/auth/logout"},
+		{http.MethodGet, "/oauth/userinfo"},
+	} {
+		var body struct {
+			Error errors.DomainError `json:"error"`
+		}
+		rp.bearer(t, route.method, route.path, tokens.AccessToken, http.StatusForbidden, &body)
+		if body.Error.Code != "USER_REQUIRED" {
+			t.Errorf("%s %s: expected USER_REQUIRED, got %q", route.method, route.path, body.Error.Code)
+		}
+	}
+
+	// Introspection tells the principal types apart
+	form := url.Values{"token": {tokens.AccessToken}}
+	req, err := http.NewRequest(http.MethodPost, rp.metadata.IntrospectionEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth("batch", testClientSecret)
+
+	var introspection models.IntrospectionResponse
+	rp.do(t, req, http.StatusOK, &introspection)
+	if !introspection.Active || introspection.PrincipalType != models.PrincipalService || introspection.Subject != "batch" {
+		t.Errorf("Expected active service token, got %+v", introspection)
+	}
+}
+
+func TestServicePrincipal_InsufficientScope(t *testing.T) {
+	provider := newTestProvider(t)
+	rp := newTestRelyingParty(t, provider, "reporter", testClientSecret)
+
+	tokens := rp.clientCredentials(t)
+	resp := rp.bearer(t, http.MethodGet, "/users/", tokens.AccessToken, http.StatusForbidden, nil)
+	if got := resp.Header.Get("WWW-Authenticate"); !strings.Contains(got, `error="insufficient_scope"`) || !strings.Contains(got, `scope="users:read"`) {
+		t.Errorf("Expected insufficient_scope challenge, got %q", got)
+	}
+}
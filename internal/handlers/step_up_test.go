@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"otp-auth-service/internal/errors"
+	"otp-auth-service/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// staleToken re-signs an access token as if its user had verified the OTP
+// an hour ago
+func (p *testProvider) staleToken(t *testing.T, token string) string {
+	t.Helper()
+
+	claims := &models.Claims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(token, claims); err != nil {
+		t.Fatalf("Failed to parse token: %v", err)
+	}
+	claims.AuthTime = jwt.NewNumericDate(time.Now().Add(-time.Hour))
+
+	stale, err := p.keyring.Sign(claims)
+	if err != nil {
+		t.Fatalf("Failed to sign token: %v", err)
+	}
+	return stale
+}
+
+func TestStepUp_FreshAuthRequired(t *testing.T) {
+	provider := newTestProvider(t)
+	rp := newTestRelyingParty(t, provider, "rp", "")
+	login := rp.signIn(t)
+
+	// A fresh login can view other users' data right away
+	rp.bearer(t, http.MethodGet, "/users/", login.Token, http.StatusOK, nil)
+
+	stale := provider.staleToken(t, login.Token)
+	var body struct {
+		Error errors.DomainError `json:"error"`
+	}
+	resp := rp.bearer(t, http.MethodGet, "/users/"+login.User.ID, stale, http.StatusUnauthorized, &body)
+	if body.Error.Code != "STEP_UP_REQUIRED" {
+		t.Errorf("Expected STEP_UP_REQUIRED, got %q", body.Error.Code)
+	}
+	if got := resp.Header.Get("WWW-Authenticate"); !strings.Contains(got, `error="insufficient_user_authentication"`) || !strings.Contains(got, "max_age=300") {
+		t.Errorf("Expected step-up challenge, got %q", got)
+	}
+
+	// Stepping up keeps the session and returns a fresh access token
+	auth := provider.issuer + APIBasePath + "/auth"
+	rp.postJSON(t, auth+"/request-otp", gin.H{"phone_number": testPhoneNumber}, http.StatusOK, nil)
+	req := stepUpRequest(t, auth, stale, rp.provider.lastOTP(t))
+	var stepped models.TokenResponse
+	rp.do(t, req, http.StatusOK, &stepped)
+	if stepped.RefreshToken != "" {
+		t.Error("Expected no new refresh token")
+	}
+
+	rp.bearer(t, http.MethodGet, "/users/"+login.User.ID, stepped.Token, http.StatusOK, nil)
+	rp.bearer(t, http.MethodGet, "/users/me/sessions", stepped.Token, http.StatusOK, nil)
+}
+
+func TestStepUp_Rejects(t *testing.T) {
+	provider := newTestProvider(t)
+	rp := newTestRelyingParty(t, provider, "rp", "")
+	login := rp.signIn(t)
+	auth := provider.issuer + APIBasePath + "/auth"
+
+	rp.do(t, stepUpRequest(t, auth, login.Token, "12"), http.StatusBadRequest, nil)
+	rp.do(t, stepUpRequest(t, auth, login.Token, "000000"), http.StatusUnauthorized, nil)
+	rp.do(t, stepUpRequest(t, auth, "", "000000"), http.StatusUnauthorized, nil)
+
+	batch := newTestRelyingParty(t, provider, "batch", testClientSecret)
+	rp.do(t, stepUpRequest(t, auth, batch.clientCredentials(t).AccessToken, "000000"), http.StatusForbidden, nil)
+}
+
+func stepUpRequest(t *testing.T, auth, token, otp string) *http.Request {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodPost, auth+"/step-up", strings.NewReader(`{"otp": "`+otp+`"}`))
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return req
+}
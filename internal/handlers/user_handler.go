@@ -4,6 +4,8 @@ import (
 	"net/http"
 
 	"otp-auth-service/internal/errors"
+	"otp-auth-service/internal/middleware"
+	"otp-auth-service/internal/models"
 	"otp-auth-service/internal/services"
 	"otp-auth-service/internal/validation"
 
@@ -22,25 +24,37 @@ func NewUserHandler(userService *services.UserService) *UserHandler {
 
 // GetUsers godoc
 // @Summary Get list of users
-// @Description Retrieve paginated list of users with optional search
+// @Description Retrieve paginated list of users with optional search and filters. Users are listed in a stable order; responses include a next_cursor while more users follow.
 // @Tags users
 // @Accept json
 // @Produce json
 // @Param page query int false "Page number (default: 1)"
 // @Param limit query int false "Items per page (default: 10, max: 100)"
-// @Param search query string false "Search by phone number"
+// @Param search query string false "Search by phone number: a prefix starting with + (e.g. +4915), or digits found anywhere in the number"
+// @Param is_active query bool false "Only active or only deactivated users"
+// @Param registered_after query string false "Registered at or after this RFC 3339 time or date"
+// @Param registered_before query string false "Registered before this RFC 3339 time or date"
+// @Param last_login_after query string false "Last logged in at or after this RFC 3339 time or date"
+// @Param last_login_before query string false "Last logged in before this RFC 3339 time or date"
+// @Param sort query string false "Sort field" Enums(registered_at, last_login_at, phone_number) default(registered_at)
+// @Param order query string false "Sort order" Enums(asc, desc) default(asc)
+// @Param cursor query string false "next_cursor of the previous page; continues after it instead of using page. Repeat the search and filters with it."
 // @Success 200 {object} map[string]interface{}
 // @Failure 400 {object} map[string]interface{}
 // @Failure 401 {object} map[string]interface{}
 // @Security BearerAuth
 // @Router /users [get]
 func (h *UserHandler) GetUsers(c *gin.Context) {
-	page := c.Query("page")
-	limit := c.Query("limit")
-	search := c.Query("search")
+	var req models.GetUsersRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(errors.ErrInvalidRequest.HTTPStatus, gin.H{
+			"error": errors.ErrInvalidRequest.WithDetails(err.Error()),
+		})
+		return
+	}
 
 	// Validate query parameters
-	if err := validation.ValidateGetUsers(page, limit, search); err != nil {
+	if err := validation.ValidateGetUsers(&req); err != nil {
 		domainErr := errors.GetDomainError(err)
 		c.JSON(domainErr.HTTPStatus, gin.H{
 			"error": domainErr,
@@ -48,7 +62,7 @@ func (h *UserHandler) GetUsers(c *gin.Context) {
 		return
 	}
 
-	users, total, err := h.userService.GetUsers(page, limit, search)
+	users, total, nextCursor, err := h.userService.GetUsers(&req)
 	if err != nil {
 		domainErr := errors.GetDomainError(err)
 		c.JSON(domainErr.HTTPStatus, gin.H{
@@ -57,12 +71,17 @@ func (h *UserHandler) GetUsers(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	response := gin.H{
 		"users": users,
 		"total": total,
-		"page":  page,
-		"limit": limit,
-	})
+		"page":  req.Page,
+		"limit": req.Limit,
+	}
+	if nextCursor != "" {
+		response["next_cursor"] = nextCursor
+	}
+
+	c.JSON(http.StatusOK, response)
 }
 
 // GetUser godoc
@@ -101,3 +120,69 @@ func (h *UserHandler) GetUser(c *gin.Context) {
 
 	c.JSON(http.StatusOK, user)
 }
+
+// GetMe godoc
+// @Summary Get the current user
+// @Description Retrieve the signed in user's details
+// @Tags users
+// @Produce json
+// @Success 200 {object} models.UserResponse
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Security BearerAuth
+// @Router /users/me [get]
+func (h *UserHandler) GetMe(c *gin.Context) {
+	user, err := h.userService.GetUser(middleware.GetClaims(c).UserID())
+	if err != nil {
+		domainErr := errors.GetDomainError(err)
+		c.JSON(domainErr.HTTPStatus, gin.H{
+			"error": domainErr,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+// UpdateMe godoc
+// @Summary Update the current user's profile
+// @Description Change the signed in user's name and locale. Omitted fields are kept; empty strings clear them.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param request body models.UpdateProfileRequest true "Profile fields"
+// @Success 200 {object} models.UserResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Security BearerAuth
+// @Router /users/me [patch]
+func (h *UserHandler) UpdateMe(c *gin.Context) {
+	var req models.UpdateProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(errors.ErrInvalidRequest.HTTPStatus, gin.H{
+			"error": errors.ErrInvalidRequest.WithDetails(err.Error()),
+		})
+		return
+	}
+
+	// Validate profile fields
+	if err := validation.ValidateUpdateProfile(&req); err != nil {
+		domainErr := errors.GetDomainError(err)
+		c.JSON(domainErr.HTTPStatus, gin.H{
+			"error": domainErr,
+		})
+		return
+	}
+
+	user, err := h.userService.UpdateProfile(middleware.GetClaims(c).UserID(), &req)
+	if err != nil {
+		domainErr := errors.GetDomainError(err)
+		c.JSON(domainErr.HTTPStatus, gin.H{
+			"error": domainErr,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
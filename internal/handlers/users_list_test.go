@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"otp-auth-service/internal/errors"
+	"otp-auth-service/internal/models"
+)
+
+type usersListResponse struct {
+	Users      []*models.UserResponse `json:"users"`
+	Total      int                    `json:"total"`
+	NextCursor string                 `json:"next_cursor"`
+}
+
+// seedUsers creates count users with phone numbers +1555000001 upwards,
+// registered a minute apart
+func (p *testProvider) seedUsers(t *testing.T, count int) {
+	t.Helper()
+
+	registeredAt := time.Now().Add(-time.Hour)
+	for i := 1; i <= count; i++ {
+		user := models.NewUser(fmt.Sprintf("+1555%06d", i))
+		user.RegisteredAt = registeredAt.Add(time.Duration(i) * time.Minute)
+		if err := p.users.Create(user); err != nil {
+			t.Fatalf("Failed to create user: %v", err)
+		}
+	}
+}
+
+func TestUsersList_CursorPagination(t *testing.T) {
+	provider := newTestProvider(t)
+	provider.seedUsers(t, 5)
+	rp := newTestRelyingParty(t, provider, "batch", testClientSecret)
+	token := rp.clientCredentials(t).AccessToken
+
+	var phones []string
+	query := url.Values{"sort": {"phone_number"}, "order": {"desc"}, "limit": {"2"}}
+	for pages := 0; ; pages++ {
+		if pages == 5 {
+			t.Fatal("Expected pagination to end")
+		}
+
+		var page usersListResponse
+		rp.bearer(t, http.MethodGet, "/users/?"+query.Encode(), token, http.StatusOK, &page)
+		if page.Total != 5 {
+			t.Errorf("Expected total 5, got %d", page.Total)
+		}
+		for _, user := range page.Users {
+			phones = append(phones, user.PhoneNumber)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+
+		// The cursor alone continues in the same order
+		query = url.Values{"cursor": {page.NextCursor}, "limit": {"2"}}
+	}
+
+	want := []string{"+1555000005", "+1555000004", "+1555000003", "+1555000002", "+1555000001"}
+	if fmt.Sprint(phones) != fmt.Sprint(want) {
+		t.Errorf("Expected %v, got %v", want, phones)
+	}
+}
+
+func TestUsersList_SearchAndFilter(t *testing.T) {
+	provider := newTestProvider(t)
+	provider.seedUsers(t, 12)
+	rp := newTestRelyingParty(t, provider, "batch", testClientSecret)
+	token := rp.clientCredentials(t).AccessToken
+
+	deactivated, err := provider.users.GetByPhoneNumber("+1555000011")
+	if err != nil {
+		t.Fatalf("Failed to get user: %v", err)
+	}
+	deactivated.IsActive = false
+	if err := provider.users.Update(deactivated); err != nil {
+		t.Fatalf("Failed to update user: %v", err)
+	}
+
+	// Users 1 and 10 to 12 contain 00001; user 10 registered before the cutoff
+	cutoff := time.Now().Add(-time.Hour + 10*time.Minute + 30*time.Second)
+	query := url.Values{
+		"search":           {"00001"},
+		"is_active":        {"true"},
+		"registered_after": {cutoff.UTC().Format(time.RFC3339)},
+		"sort":             {"phone_number"},
+	}
+	var page usersListResponse
+	rp.bearer(t, http.MethodGet, "/users/?"+query.Encode(), token, http.StatusOK, &page)
+
+	var phones []string
+	for _, user := range page.Users {
+		phones = append(phones, user.PhoneNumber)
+	}
+	want := []string{"+1555000012"}
+	if page.Total != 1 || fmt.Sprint(phones) != fmt.Sprint(want) {
+		t.Errorf("Expected %v, got %v of %d", want, phones, page.Total)
+	}
+
+	rp.bearer(t, http.MethodGet, "/users/?search=%2B155500000", token, http.StatusOK, &page)
+	if page.Total != 9 {
+		t.Errorf("Expected 9 users with the prefix, got %d", page.Total)
+	}
+}
+
+func TestUsersList_Rejects(t *testing.T) {
+	provider := newTestProvider(t)
+	provider.seedUsers(t, 3)
+	rp := newTestRelyingParty(t, provider, "batch", testClientSecret)
+	token := rp.clientCredentials(t).AccessToken
+
+	var first usersListResponse
+	rp.bearer(t, http.MethodGet, "/users/?limit=1&sort=last_login_at", token, http.StatusOK, &first)
+	if first.NextCursor == "" {
+		t.Fatal("Expected next_cursor")
+	}
+
+	tests := []struct {
+		name    string
+		query   url.Values
+		code    string
+		details string
+	}{
+		{name: "unknown sort field", query: url.Values{"sort": {"id"}}, code: "INVALID_SORT"},
+		{name: "unknown order", query: url.Values{"order": {"newest"}}, code: "INVALID_SORT", details: "order must be asc or desc"},
+		{name: "malformed cursor", query: url.Values{"cursor": {"abc"}}, code: "INVALID_CURSOR"},
+		{name: "cursor of another sort", query: url.Values{"cursor": {first.NextCursor}, "sort": {"phone_number"}}, code: "INVALID_CURSOR", details: "cursor was issued for another sort order"},
+		{name: "cursor with page", query: url.Values{"cursor": {first.NextCursor}, "page": {"2"}}, code: "INVALID_PAGINATION"},
+		{name: "unknown status", query: url.Values{"is_active": {"sometimes"}}, code: "INVALID_FILTER"},
+		{name: "malformed date", query: url.Values{"registered_after": {"last week"}}, code: "INVALID_FILTER"},
+		{name: "empty date range", query: url.Values{"last_login_after": {"2024-02-01"}, "last_login_before": {"2024-01-01"}}, code: "INVALID_FILTER"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body struct {
+				Error errors.DomainError `json:"error"`
+			}
+			rp.bearer(t, http.MethodGet, "/users/?"+tt.query.Encode(), token, http.StatusBadRequest, &body)
+			if body.Error.Code != tt.code {
+				t.Errorf("Expected %s, got %q", tt.code, body.Error.Code)
+			}
+			if tt.details != "" && body.Error.Details != tt.details {
+				t.Errorf("Expected details %q, got %q", tt.details, body.Error.Details)
+			}
+		})
+	}
+}
@@ -0,0 +1,188 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"otp-auth-service/internal/errors"
+	"otp-auth-service/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// patchMe sends a profile update with the raw JSON body
+func (rp *testRelyingParty) patchMe(t *testing.T, token, body string, status int, v interface{}) {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodPatch, rp.provider.issuer+APIBasePath+"/users/me", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	rp.do(t, req, status, v)
+}
+
+func TestUsersMe_Profile(t *testing.T) {
+	provider := newTestProvider(t)
+	rp := newTestRelyingParty(t, provider, "rp", "")
+	login := rp.signIn(t)
+
+	var me models.UserResponse
+	rp.bearer(t, http.MethodGet, "/users/me", login.Token, http.StatusOK, &me)
+	if me.ID != login.User.ID || me.PhoneNumber != testPhoneNumber || !me.IsActive {
+		t.Errorf("Expected the signed in user, got %+v", me)
+	}
+
+	rp.patchMe(t, login.Token, `{"name": "  Jane Doe ", "locale": "de-DE"}`, http.StatusOK, &me)
+	if me.Name != "Jane Doe" || me.Locale != "de-DE" {
+		t.Errorf("Expected updated profile, got %+v", me)
+	}
+
+	// Omitted fields are kept
+	rp.patchMe(t, login.Token, `{"locale": "en"}`, http.StatusOK, nil)
+	rp.bearer(t, http.MethodGet, "/users/me", login.Token, http.StatusOK, &me)
+	if me.Name != "Jane Doe" || me.Locale != "en" {
+		t.Errorf("Expected name to be kept, got %+v", me)
+	}
+
+	tests := []struct {
+		name string
+		body string
+		code string
+	}{
+		{name: "malformed JSON", body: `{"name": `, code: "INVALID_REQUEST"},
+		{name: "no fields", body: `{}`, code: "INVALID_PROFILE"},
+		{name: "invalid locale", body: `{"locale": "German"}`, code: "INVALID_PROFILE"},
+		{name: "name too long", body: `{"name": "` + strings.Repeat("a", 101) + `"}`, code: "INVALID_PROFILE"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body struct {
+				Error errors.DomainError `json:"error"`
+			}
+			rp.patchMe(t, login.Token, tt.body, http.StatusBadRequest, &body)
+			if body.Error.Code != tt.code {
+				t.Errorf("Expected %s, got %q", tt.code, body.Error.Code)
+			}
+		})
+	}
+
+	// Service principals have no user of their own
+	batch := newTestRelyingParty(t, provider, "batch", testClientSecret)
+	rp.bearer(t, http.MethodGet, "/users/me", batch.clientCredentials(t).AccessToken, http.StatusForbidden, nil)
+	rp.bearer(t, http.MethodGet, "/users/me", "", http.StatusUnauthorized, nil)
+}
+
+func TestUsersMe_Deactivate(t *testing.T) {
+	provider := newTestProvider(t)
+	rp := newTestRelyingParty(t, provider, "rp", "")
+	login := rp.signIn(t)
+
+	var body struct {
+		Error errors.DomainError `json:"error"`
+	}
+	rp.bearer(t, http.MethodDelete, "/users/me", provider.staleToken(t, login.Token), http.StatusUnauthorized, &body)
+	if body.Error.Code != "STEP_UP_REQUIRED" {
+		t.Errorf("Expected STEP_UP_REQUIRED, got %q", body.Error.Code)
+	}
+
+	rp.bearer(t, http.MethodDelete, "/users/me", login.Token, http.StatusOK, nil)
+
+	// Tokens stop working and the number cannot sign in again
+	rp.bearer(t, http.MethodGet, "/users/me", login.Token, http.StatusUnauthorized, nil)
+	auth := provider.issuer + APIBasePath + "/auth"
+	rp.postJSON(t, auth+"/refresh", gin.H{"refresh_token": login.RefreshToken}, http.StatusUnauthorized, nil)
+
+	rp.postJSON(t, auth+"/request-otp", gin.H{"phone_number": testPhoneNumber}, http.StatusOK, nil)
+	rp.postJSON(t, auth+"/verify-otp", gin.H{"phone_number": testPhoneNumber, "otp": provider.lastOTP(t)}, http.StatusForbidden, &body)
+	if body.Error.Code != "USER_DEACTIVATED" {
+		t.Errorf("Expected USER_DEACTIVATED, got %q", body.Error.Code)
+	}
+
+	user, err := provider.users.GetByID(login.User.ID)
+	if err != nil || user.IsActive {
+		t.Errorf("Expected deactivated user, got %+v (%v)", user, err)
+	}
+}
+
+func TestUsersMe_RequestOTP(t *testing.T) {
+	provider := newTestProvider(t)
+	rp := newTestRelyingParty(t, provider, "rp", "")
+	login := rp.signIn(t)
+
+	var body struct {
+		Error errors.DomainError `json:"error"`
+	}
+
+	// Anonymous callers only get login OTPs
+	auth := provider.issuer + APIBasePath + "/auth"
+	for _, path := range []string{"/request-otp", "/resend-otp"} {
+		rp.postJSON(t, auth+path, gin.H{"phone_number": testPhoneNumber, "purpose": models.PurposeAccountDeletion}, http.StatusBadRequest, &body)
+		if body.Error.Code != "INVALID_OTP_PURPOSE" {
+			t.Errorf("Expected INVALID_OTP_PURPOSE from %s, got %q", path, body.Error.Code)
+		}
+	}
+
+	req, err := http.NewRequest(http.MethodPost, provider.issuer+APIBasePath+"/users/me/otp", strings.NewReader(`{"purpose": "account_deletion"}`))
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+login.Token)
+
+	var response models.RequestOTPResponse
+	rp.do(t, req, http.StatusOK, &response)
+	if response.PhoneNumber != testPhoneNumber || response.Purpose != models.PurposeAccountDeletion {
+		t.Errorf("Expected account_deletion OTP to the user's phone, got %+v", response)
+	}
+
+	// Login OTPs are not sent to signed in users
+	req, err = http.NewRequest(http.MethodPost, provider.issuer+APIBasePath+"/users/me/otp", strings.NewReader(`{"purpose": "login"}`))
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+login.Token)
+	rp.do(t, req, http.StatusBadRequest, &body)
+	if body.Error.Code != "INVALID_OTP_PURPOSE" {
+		t.Errorf("Expected INVALID_OTP_PURPOSE, got %q", body.Error.Code)
+	}
+
+	rp.bearer(t, http.MethodPost, "/users/me/otp", "", http.StatusUnauthorized, nil)
+}
+
+func TestUsersMe_OAuthClientScopes(t *testing.T) {
+	provider := newTestProvider(t)
+
+	// Tokens of OAuth clients are limited to the scopes they were granted
+	rp := newTestRelyingParty(t, provider, "rp", "")
+	code, verifier := rp.authenticate(t, "openid profile", "")
+	tokens := rp.exchange(t, code, verifier)
+	for _, route := range []struct{ method, path, scope string }{
+		{http.MethodGet, "/users/me", models.ScopeAccountRead},
+		{http.MethodGet, "/users/me/sessions", models.ScopeAccountRead},
+		{http.MethodDelete, "/users/me", models.ScopeAccountWrite},
+		{http.MethodGet, "/users/", models.ScopeUsersRead},
+	} {
+		var body struct {
+			Error errors.DomainError `json:"error"`
+		}
+		resp := rp.bearer(t, route.method, route.path, tokens.AccessToken, http.StatusForbidden, &body)
+		if body.Error.Code != "INSUFFICIENT_SCOPE" || !strings.Contains(resp.Header.Get("WWW-Authenticate"), `scope="`+route.scope+`"`) {
+			t.Errorf("%s %s: expected INSUFFICIENT_SCOPE for %s, got %q", route.method, route.path, route.scope, body.Error.Code)
+		}
+	}
+
+	client := newTestRelyingParty(t, provider, "oauth-only", "")
+	code, verifier = client.authenticate(t, "profile "+models.ScopeAccountRead, "")
+	tokens = client.exchange(t, code, verifier)
+	client.bearer(t, http.MethodGet, "/users/me", tokens.AccessToken, http.StatusOK, nil)
+	client.patchMe(t, tokens.AccessToken, `{"name": "Jane Doe"}`, http.StatusForbidden, nil)
+
+	// The service's own apps are not limited by scopes
+	login := rp.signIn(t)
+	rp.patchMe(t, login.Token, `{"name": "Jane Doe"}`, http.StatusOK, nil)
+}
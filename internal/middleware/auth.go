@@ -1,19 +1,48 @@
 package middleware
 
 import (
+	"fmt"
+	"net/http"
 	"strings"
+	"time"
 
 	"otp-auth-service/internal/errors"
+	"otp-auth-service/internal/models"
 	"otp-auth-service/internal/services"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
 )
 
-func AuthMiddleware(jwtSecret string) gin.HandlerFunc {
+// AuthOption configures AuthMiddleware
+type AuthOption func(*authOptions)
+
+type authOptions struct {
+	tokenCookie string
+}
+
+// WithTokenCookie also accepts an access token from the named cookie when
+// the request has no Authorization header. An empty name disables it.
+func WithTokenCookie(name string) AuthOption {
+	return func(o *authOptions) {
+		o.tokenCookie = name
+	}
+}
+
+func AuthMiddleware(authService *services.AuthService, opts ...AuthOption) gin.HandlerFunc {
+	var options authOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
+		if authHeader == "" && options.tokenCookie != "" {
+			if token, err := c.Cookie(options.tokenCookie); err == nil && token != "" {
+				authHeader = "Bearer " + token
+			}
+		}
 		if authHeader == "" {
+			c.Header("WWW-Authenticate", "Bearer")
 			c.JSON(errors.ErrMissingAuthHeader.HTTPStatus, gin.H{
 				"error": errors.ErrMissingAuthHeader,
 			})
@@ -23,6 +52,7 @@ func AuthMiddleware(jwtSecret string) gin.HandlerFunc {
 
 		// Check if the header starts with "Bearer "
 		if !strings.HasPrefix(authHeader, "Bearer ") {
+			c.Header("WWW-Authenticate", "Bearer")
 			c.JSON(errors.ErrInvalidAuthFormat.HTTPStatus, gin.H{
 				"error": errors.ErrInvalidAuthFormat,
 			})
@@ -33,11 +63,13 @@ func AuthMiddleware(jwtSecret string) gin.HandlerFunc {
 		// Extract the token
 		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
 
-		// Validate the token
-		authService := services.NewAuthService(nil, nil, jwtSecret)
-		token, err := authService.ValidateToken(tokenString)
+		// Validate the token and make sure it was not revoked
+		claims, err := authService.ValidateToken(tokenString)
 		if err != nil {
 			domainErr := errors.GetDomainError(err)
+			if domainErr.HTTPStatus == http.StatusUnauthorized {
+				c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+			}
 			c.JSON(domainErr.HTTPStatus, gin.H{
 				"error": domainErr,
 			})
@@ -45,23 +77,83 @@ func AuthMiddleware(jwtSecret string) gin.HandlerFunc {
 			return
 		}
 
-		// Extract claims
-		claims, ok := token.Claims.(jwt.MapClaims)
-		if !ok {
-			c.JSON(errors.ErrInvalidToken.HTTPStatus, gin.H{
-				"error": errors.ErrInvalidToken,
+		// Make the claims available to handlers. Tokens of service
+		// principals are accepted too; routes that act on a user add
+		// RequireUser.
+		c.Set(claimsContextKey, claims)
+
+		c.Next()
+	}
+}
+
+// RequireUser rejects service principals, for endpoints that act on the
+// signed in user. It must run after AuthMiddleware.
+func RequireUser() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if claims := GetClaims(c); claims == nil || claims.IsService() {
+			c.JSON(errors.ErrUserRequired.HTTPStatus, gin.H{
+				"error": errors.ErrUserRequired,
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// RequireScope only lets service principals and tokens of OAuth clients
+// through if the token grants scope. First-party user tokens are not
+// restricted by it. It must run after AuthMiddleware.
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims := GetClaims(c)
+		if claims == nil || (!claims.IsFirstParty() && !models.ContainsScopes(models.ParseScope(claims.Scope), []string{scope})) {
+			c.Header("WWW-Authenticate", fmt.Sprintf(`Bearer error="insufficient_scope", scope="%s"`, scope))
+			c.JSON(errors.ErrInsufficientScope.HTTPStatus, gin.H{
+				"error": errors.ErrInsufficientScope.WithDetails(scope + " scope is required"),
 			})
 			c.Abort()
 			return
 		}
 
-		// Set user information in context
-		userID, _ := claims["user_id"].(string)
-		phoneNumber, _ := claims["phone_number"].(string)
+		c.Next()
+	}
+}
 
-		c.Set("user_id", userID)
-		c.Set("phone_number", phoneNumber)
+// RequireFreshAuth rejects user tokens whose OTP verification (auth_time)
+// is older than maxAge, for sensitive operations. The client verifies a
+// new OTP at /auth/step-up and retries with the token it gets. Service
+// principals authenticate for every token and are let through. It must
+// run after AuthMiddleware.
+func RequireFreshAuth(maxAge time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims := GetClaims(c)
+		if claims == nil || (!claims.IsService() && !claims.AuthenticatedWithin(maxAge)) {
+			// RFC 9470 step-up challenge
+			c.Header("WWW-Authenticate", fmt.Sprintf(`Bearer error="insufficient_user_authentication", error_description="A recent OTP verification is required", max_age=%d`, int(maxAge.Seconds())))
+			c.JSON(errors.ErrStepUpRequired.HTTPStatus, gin.H{
+				"error": errors.ErrStepUpRequired.WithDetails(fmt.Sprintf("verify an OTP at /auth/step-up; max age is %s", maxAge)),
+			})
+			c.Abort()
+			return
+		}
 
 		c.Next()
 	}
 }
+
+// claimsContextKey is the gin context key AuthMiddleware stores claims under
+const claimsContextKey = "auth_claims"
+
+// GetClaims returns the claims of the authenticated request, or nil when
+// AuthMiddleware did not run for it
+func GetClaims(c *gin.Context) *models.Claims {
+	value, ok := c.Get(claimsContextKey)
+	if !ok {
+		return nil
+	}
+
+	claims, _ := value.(*models.Claims)
+	return claims
+}
@@ -0,0 +1,65 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestClaims_Validate(t *testing.T) {
+	now := jwt.NewNumericDate(time.Now())
+	valid := func() *Claims {
+		return &Claims{RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   "user-1",
+			ID:        "token-1",
+			IssuedAt:  now,
+			ExpiresAt: now,
+		}}
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(c *Claims)
+		wantErr bool
+	}{
+		{name: "valid", modify: func(c *Claims) {}},
+		{name: "no subject", modify: func(c *Claims) { c.Subject = "" }, wantErr: true},
+		{name: "no ID", modify: func(c *Claims) { c.ID = "" }, wantErr: true},
+		{name: "no issue time", modify: func(c *Claims) { c.IssuedAt = nil }, wantErr: true},
+		{name: "no expiry", modify: func(c *Claims) { c.ExpiresAt = nil }, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := valid()
+			tt.modify(claims)
+
+			err := claims.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestClaims_AuthenticatedWithin(t *testing.T) {
+	tests := []struct {
+		name     string
+		authTime *jwt.NumericDate
+		want     bool
+	}{
+		{name: "recent", authTime: jwt.NewNumericDate(time.Now().Add(-time.Minute)), want: true},
+		{name: "too old", authTime: jwt.NewNumericDate(time.Now().Add(-10 * time.Minute)), want: false},
+		{name: "unknown", authTime: nil, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := &Claims{AuthTime: tt.authTime}
+			if got := claims.AuthenticatedWithin(5 * time.Minute); got != tt.want {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,291 @@
+package models
+
+import (
+	"crypto"
+	"strings"
+	"time"
+)
+
+// OAuth grant types accepted by /oauth/token
+const (
+	GrantTypeAuthorizationCode = "authorization_code"
+	GrantTypeRefreshToken      = "refresh_token"
+	GrantTypeClientCredentials = "client_credentials"
+)
+
+// ClientAssertionTypeJWTBearer marks a private_key_jwt client assertion
+// (RFC 7523 section 2.2)
+const ClientAssertionTypeJWTBearer = "urn:ietf:params:oauth:client-assertion-type:jwt-bearer"
+
+// Scopes of the API's own endpoints. OAuth clients and service principals
+// need them; tokens of the service's own apps are not limited by scopes.
+const (
+	// ScopeUsersRead lets clients read users
+	ScopeUsersRead = "users:read"
+	// ScopeAccountRead lets clients read the signed in user's profile,
+	// sessions and consents
+	ScopeAccountRead = "account:read"
+	// ScopeAccountWrite lets clients change the signed in user's profile,
+	// request OTPs for it, end its sessions and deactivate it
+	ScopeAccountWrite = "account:write"
+)
+
+// Token type hints of introspection and revocation requests
+const (
+	TokenTypeHintAccessToken  = "access_token"
+	TokenTypeHintRefreshToken = "refresh_token"
+)
+
+// CodeChallengeMethodS256 is the only PKCE method accepted; plain would
+// send the verifier in the clear
+const CodeChallengeMethodS256 = "S256"
+
+// OAuthClient is an application that signs users in through the OAuth
+// authorization code flow, or a machine client acting on its own behalf
+// through the client_credentials grant. Clients are registered in the
+// config file.
+type OAuthClient struct {
+	ID   string `json:"client_id"`
+	Name string `json:"name"`
+	// SecretHash is the hex encoded SHA-256 of the client secret. Public
+	// clients (browser and mobile apps) have none and must use PKCE.
+	SecretHash string `json:"secret_hash,omitempty"`
+	// PublicKeyFile is a PEM public key the client signs private_key_jwt
+	// assertions with, as an alternative to a secret. PublicKey is loaded
+	// from it when the config is validated.
+	PublicKeyFile string           `json:"public_key_file,omitempty"`
+	PublicKey     crypto.PublicKey `json:"-"`
+	// GrantTypes the client may use; defaults to authorization_code and
+	// refresh_token
+	GrantTypes   []string `json:"grant_types,omitempty"`
+	RedirectURIs []string `json:"redirect_uris,omitempty"`
+	// Scopes the client may request; requests without a scope get all of them
+	Scopes []string `json:"scopes"`
+	// Trusted first-party clients are not asked for consent
+	Trusted bool `json:"trusted,omitempty"`
+}
+
+// IsPublic reports whether the client has no credentials to authenticate with
+func (c *OAuthClient) IsPublic() bool {
+	return c.SecretHash == "" && c.PublicKey == nil
+}
+
+// AllowsGrantType reports whether the client may use the grant type
+func (c *OAuthClient) AllowsGrantType(grantType string) bool {
+	if len(c.GrantTypes) == 0 {
+		return grantType == GrantTypeAuthorizationCode || grantType == GrantTypeRefreshToken
+	}
+	return ContainsScopes(c.GrantTypes, []string{grantType})
+}
+
+// HasRedirectURI reports whether uri is registered for the client. URIs are
+// compared exactly, as partial matching enables open redirects.
+func (c *OAuthClient) HasRedirectURI(uri string) bool {
+	for _, registered := range c.RedirectURIs {
+		if registered == uri {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsScopes reports whether the client may request every scope
+func (c *OAuthClient) AllowsScopes(scopes []string) bool {
+	return ContainsScopes(c.Scopes, scopes)
+}
+
+// AuthorizationRequest is a pending /oauth/authorize request while the
+// user signs in and grants consent
+type AuthorizationRequest struct {
+	ID                  string   `json:"id"`
+	ClientID            string   `json:"client_id"`
+	RedirectURI         string   `json:"redirect_uri"`
+	Scopes              []string `json:"scopes"`
+	State               string   `json:"state,omitempty"`
+	CodeChallenge       string   `json:"code_challenge,omitempty"`
+	CodeChallengeMethod string   `json:"code_challenge_method,omitempty"`
+	Nonce               string   `json:"nonce,omitempty"`
+	// PhoneNumber is set once a login OTP has been sent
+	PhoneNumber string `json:"phone_number,omitempty"`
+	// UserID and AuthTime are set once the user has verified the OTP
+	UserID    string    `json:"user_id,omitempty"`
+	AuthTime  time.Time `json:"auth_time,omitempty"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// AuthorizationCode is the server-side record of an issued code. It holds
+// what the token request must match and the browser the user signed in from.
+type AuthorizationCode struct {
+	ClientID            string    `json:"client_id"`
+	RedirectURI         string    `json:"redirect_uri"`
+	UserID              string    `json:"user_id"`
+	Scopes              []string  `json:"scopes"`
+	CodeChallenge       string    `json:"code_challenge,omitempty"`
+	CodeChallengeMethod string    `json:"code_challenge_method,omitempty"`
+	Nonce               string    `json:"nonce,omitempty"`
+	AuthTime            time.Time `json:"auth_time"`
+	IPAddress           string    `json:"ip_address"`
+	UserAgent           string    `json:"user_agent"`
+	ExpiresAt           time.Time `json:"expires_at"`
+}
+
+// Consent records the scopes a user has granted to a client
+type Consent struct {
+	UserID    string    `json:"user_id"`
+	ClientID  string    `json:"client_id"`
+	Scopes    []string  `json:"scopes"`
+	GrantedAt time.Time `json:"granted_at"`
+}
+
+// AuthorizeRequest holds the query parameters of /oauth/authorize
+type AuthorizeRequest struct {
+	ResponseType        string `form:"response_type"`
+	ClientID            string `form:"client_id"`
+	RedirectURI         string `form:"redirect_uri"`
+	Scope               string `form:"scope"`
+	State               string `form:"state"`
+	CodeChallenge       string `form:"code_challenge"`
+	CodeChallengeMethod string `form:"code_challenge_method"`
+	Nonce               string `form:"nonce"`
+}
+
+type AuthorizeOTPRequest struct {
+	PhoneNumber string `json:"phone_number" binding:"required"`
+}
+
+type AuthorizeVerifyRequest struct {
+	OTP string `json:"otp" binding:"required"`
+}
+
+type AuthorizeConsentRequest struct {
+	Approve bool `json:"approve"`
+}
+
+// Authorization request states reported to the login UI
+const (
+	AuthorizationLoginRequired   = "login_required"
+	AuthorizationConsentRequired = "consent_required"
+	AuthorizationComplete        = "complete"
+)
+
+// AuthorizationResponse tells the login UI what the authorization request
+// needs next. Once complete, the UI sends the browser to RedirectTo.
+type AuthorizationResponse struct {
+	RequestID  string   `json:"request_id"`
+	Status     string   `json:"status"`
+	ClientID   string   `json:"client_id"`
+	ClientName string   `json:"client_name"`
+	Scopes     []string `json:"scopes"`
+	RedirectTo string   `json:"redirect_to,omitempty"`
+}
+
+// TokenRequest holds the form parameters of /oauth/token
+type TokenRequest struct {
+	GrantType    string `form:"grant_type"`
+	Code         string `form:"code"`
+	RedirectURI  string `form:"redirect_uri"`
+	CodeVerifier string `form:"code_verifier"`
+	RefreshToken string `form:"refresh_token"`
+	// Scope is requested by the client_credentials grant
+	Scope               string `form:"scope"`
+	ClientID            string `form:"client_id"`
+	ClientSecret        string `form:"client_secret"`
+	ClientAssertionType string `form:"client_assertion_type"`
+	ClientAssertion     string `form:"client_assertion"`
+}
+
+// ClientCredentials authenticate a client at the token, introspection and
+// revocation endpoints: a client_id alone for public clients, a secret, or
+// a private_key_jwt assertion
+type ClientCredentials struct {
+	ClientID            string
+	ClientSecret        string
+	ClientAssertionType string
+	ClientAssertion     string
+}
+
+// OAuthTokenResponse is the RFC 6749 token response
+type OAuthTokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	Scope        string `json:"scope,omitempty"`
+	// IDToken is issued when the openid scope was granted
+	IDToken string `json:"id_token,omitempty"`
+}
+
+// IntrospectionRequest holds the form parameters of /oauth/introspect (RFC 7662)
+type IntrospectionRequest struct {
+	Token               string `form:"token"`
+	TokenTypeHint       string `form:"token_type_hint"`
+	ClientID            string `form:"client_id"`
+	ClientSecret        string `form:"client_secret"`
+	ClientAssertionType string `form:"client_assertion_type"`
+	ClientAssertion     string `form:"client_assertion"`
+}
+
+// IntrospectionResponse describes a token. Inactive tokens only report
+// active=false, so callers learn nothing about why.
+type IntrospectionResponse struct {
+	Active        bool     `json:"active"`
+	Scope         string   `json:"scope,omitempty"`
+	ClientID      string   `json:"client_id,omitempty"`
+	PrincipalType string   `json:"principal_type,omitempty"`
+	TokenType     string   `json:"token_type,omitempty"`
+	ExpiresAt     int64    `json:"exp,omitempty"`
+	IssuedAt      int64    `json:"iat,omitempty"`
+	NotBefore     int64    `json:"nbf,omitempty"`
+	Subject       string   `json:"sub,omitempty"`
+	Audience      []string `json:"aud,omitempty"`
+	Issuer        string   `json:"iss,omitempty"`
+	TokenID       string   `json:"jti,omitempty"`
+	SessionID     string   `json:"sid,omitempty"`
+	PhoneNumber   string   `json:"phone_number,omitempty"`
+	AuthTime      int64    `json:"auth_time,omitempty"`
+	ACR           string   `json:"acr,omitempty"`
+}
+
+// RevocationRequest holds the form parameters of /oauth/revoke (RFC 7009)
+type RevocationRequest struct {
+	Token               string `form:"token"`
+	TokenTypeHint       string `form:"token_type_hint"`
+	ClientID            string `form:"client_id"`
+	ClientSecret        string `form:"client_secret"`
+	ClientAssertionType string `form:"client_assertion_type"`
+	ClientAssertion     string `form:"client_assertion"`
+}
+
+type ConsentResponse struct {
+	ClientID   string    `json:"client_id"`
+	ClientName string    `json:"client_name"`
+	Scopes     []string  `json:"scopes"`
+	GrantedAt  time.Time `json:"granted_at"`
+}
+
+// ParseScope splits a space separated scope parameter
+func ParseScope(scope string) []string {
+	return strings.Fields(scope)
+}
+
+// FormatScope joins scopes into a scope parameter
+func FormatScope(scopes []string) string {
+	return strings.Join(scopes, " ")
+}
+
+// ContainsScopes reports whether every scope in requested is granted
+func ContainsScopes(granted, requested []string) bool {
+	for _, scope := range requested {
+		found := false
+		for _, g := range granted {
+			if g == scope {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
@@ -2,32 +2,105 @@ package models
 
 import "time"
 
+// OTPPurpose scopes an OTP to the flow it was issued for
+type OTPPurpose string
+
+const (
+	PurposeLogin              OTPPurpose = "login"
+	PurposePhoneChange        OTPPurpose = "phone_change"
+	PurposeAccountDeletion    OTPPurpose = "account_deletion"
+	PurposeTransactionConfirm OTPPurpose = "transaction_confirm"
+)
+
+// OTPPurposes lists every supported purpose
+var OTPPurposes = []OTPPurpose{
+	PurposeLogin,
+	PurposePhoneChange,
+	PurposeAccountDeletion,
+	PurposeTransactionConfirm,
+}
+
+// IsValid reports whether p is a supported purpose
+func (p OTPPurpose) IsValid() bool {
+	for _, purpose := range OTPPurposes {
+		if p == purpose {
+			return true
+		}
+	}
+	return false
+}
+
 type OTP struct {
-	PhoneNumber string    `json:"phone_number"`
-	Code        string    `json:"code"`
-	ExpiresAt   time.Time `json:"expires_at"`
-	Attempts    int       `json:"attempts"`
+	PhoneNumber string     `json:"phone_number"`
+	Purpose     OTPPurpose `json:"purpose"`
+	// CodeHash is the keyed HMAC of the code; the plaintext is never stored
+	CodeHash string `json:"code_hash,omitempty"`
+	// Code is only present on records written before codes were hashed and
+	// is kept so that those OTPs can still be verified until they expire
+	Code string `json:"code,omitempty"`
+	// SealedCode is the code encrypted with a server key so it can be re-sent
+	SealedCode string    `json:"sealed_code,omitempty"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Attempts   int       `json:"attempts"`
+}
+
+// IssuedOTP is a code that is ready to be delivered to the user
+type IssuedOTP struct {
+	Code       string
+	ExpiresIn  time.Duration
+	RetryAfter time.Duration
 }
 
 type RequestOTPRequest struct {
 	PhoneNumber string `json:"phone_number" binding:"required"`
+	// Purpose defaults to login
+	Purpose OTPPurpose `json:"purpose"`
+}
+
+// UserOTPRequest asks for an OTP to the signed in user's own phone number,
+// for confirming a flow other than login
+type UserOTPRequest struct {
+	Purpose OTPPurpose `json:"purpose" binding:"required" example:"account_deletion"`
 }
 
 type RequestOTPResponse struct {
-	Message     string `json:"message"`
-	PhoneNumber string `json:"phone_number"`
+	Message     string     `json:"message"`
+	PhoneNumber string     `json:"phone_number"`
+	Purpose     OTPPurpose `json:"purpose"`
+	ExpiresIn   int        `json:"expires_in"`
+	RetryAfter  int        `json:"retry_after"`
+}
+
+type ResendOTPRequest struct {
+	PhoneNumber string     `json:"phone_number" binding:"required"`
+	Channel     string     `json:"channel"`
+	Purpose     OTPPurpose `json:"purpose"`
+}
+
+type ResendOTPResponse struct {
+	Message     string     `json:"message"`
+	PhoneNumber string     `json:"phone_number"`
+	Purpose     OTPPurpose `json:"purpose"`
+	Channel     string     `json:"channel"`
+	ExpiresIn   int        `json:"expires_in"`
+	RetryAfter  int        `json:"retry_after"`
 }
 
 type VerifyOTPRequest struct {
-	PhoneNumber string `json:"phone_number" binding:"required"`
-	OTP         string `json:"otp" binding:"required"`
+	PhoneNumber string     `json:"phone_number" binding:"required"`
+	OTP         string     `json:"otp" binding:"required"`
+	Purpose     OTPPurpose `json:"purpose"`
+	// DeviceName labels the session in the session list, e.g. "Pixel 8"
+	DeviceName string `json:"device_name,omitempty"`
 }
 
 type VerifyOTPResponse struct {
-	Message   string        `json:"message"`
-	Token     string        `json:"token"`
-	User      *UserResponse `json:"user"`
-	IsNewUser bool          `json:"is_new_user"`
+	Message      string        `json:"message"`
+	Token        string        `json:"token"`
+	RefreshToken string        `json:"refresh_token"`
+	ExpiresIn    int           `json:"expires_in"`
+	User         *UserResponse `json:"user"`
+	IsNewUser    bool          `json:"is_new_user"`
 }
 
 type AuthResponse struct {
@@ -0,0 +1,72 @@
+package models
+
+import "time"
+
+// Session is one login of a user on a device. Its ID is the refresh token
+// family ID and is carried in the sid claim of access tokens.
+type Session struct {
+	ID         string `json:"id"`
+	UserID     string `json:"user_id"`
+	DeviceName string `json:"device_name,omitempty"`
+	IPAddress  string `json:"ip_address"`
+	UserAgent  string `json:"user_agent"`
+	// ClientID is the OAuth client the user signed in to, if any
+	ClientID   string    `json:"client_id,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	// AuthTime is when the user last verified an OTP in this session, on
+	// login or on step-up, and ACR how they did. Access tokens of the
+	// session carry both.
+	AuthTime time.Time `json:"auth_time,omitempty"`
+	ACR      string    `json:"acr,omitempty"`
+}
+
+// ClientInfo describes the client a request came from
+type ClientInfo struct {
+	DeviceName string
+	IPAddress  string
+	UserAgent  string
+	// ClientID is set for sign-ins through an OAuth client
+	ClientID string
+}
+
+type SessionResponse struct {
+	ID         string    `json:"id"`
+	DeviceName string    `json:"device_name,omitempty"`
+	IPAddress  string    `json:"ip_address"`
+	UserAgent  string    `json:"user_agent"`
+	ClientID   string    `json:"client_id,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	Current    bool      `json:"current"`
+}
+
+func NewSession(id, userID string, client ClientInfo, expiresAt time.Time) *Session {
+	now := time.Now()
+	return &Session{
+		ID:         id,
+		UserID:     userID,
+		DeviceName: client.DeviceName,
+		IPAddress:  client.IPAddress,
+		UserAgent:  client.UserAgent,
+		ClientID:   client.ClientID,
+		CreatedAt:  now,
+		LastSeenAt: now,
+		ExpiresAt:  expiresAt,
+	}
+}
+
+// ToResponse marks the session as current if it is the one making the request
+func (s *Session) ToResponse(currentSessionID string) *SessionResponse {
+	return &SessionResponse{
+		ID:         s.ID,
+		DeviceName: s.DeviceName,
+		IPAddress:  s.IPAddress,
+		UserAgent:  s.UserAgent,
+		ClientID:   s.ClientID,
+		CreatedAt:  s.CreatedAt,
+		LastSeenAt: s.LastSeenAt,
+		Current:    s.ID == currentSessionID,
+	}
+}
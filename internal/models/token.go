@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// RefreshToken is the server-side record of an opaque refresh token.
+// All tokens issued from one login share a FamilyID.
+type RefreshToken struct {
+	UserID      string    `json:"user_id"`
+	PhoneNumber string    `json:"phone_number"`
+	FamilyID    string    `json:"family_id"`
+	IssuedAt    time.Time `json:"issued_at"`
+	ExpiresAt   time.Time `json:"expires_at"`
+	// ClientID and Scopes are set for tokens issued to OAuth clients
+	ClientID string   `json:"client_id,omitempty"`
+	Scopes   []string `json:"scopes,omitempty"`
+	// Rotated is set once the token has been exchanged for a new one
+	Rotated bool `json:"rotated,omitempty"`
+}
+
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// StepUpRequest re-verifies the user of a session with a login OTP
+type StepUpRequest struct {
+	OTP string `json:"otp" binding:"required"`
+}
+
+type TokenResponse struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+	Scope        string `json:"scope,omitempty"`
+}
@@ -12,18 +12,29 @@ type User struct {
 	RegisteredAt time.Time `json:"registered_at"`
 	LastLoginAt  time.Time `json:"last_login_at"`
 	IsActive     bool      `json:"is_active"`
+	Name         string    `json:"name"`
+	Locale       string    `json:"locale"`
 }
 
 type CreateUserRequest struct {
 	PhoneNumber string `json:"phone_number" binding:"required"`
 }
 
+// UpdateProfileRequest changes the profile of the current user. Omitted
+// fields are kept; empty strings clear them.
+type UpdateProfileRequest struct {
+	Name   *string `json:"name" example:"Jane Doe"`
+	Locale *string `json:"locale" example:"de-DE"`
+}
+
 type UserResponse struct {
 	ID           string    `json:"id"`
 	PhoneNumber  string    `json:"phone_number"`
 	RegisteredAt time.Time `json:"registered_at"`
 	LastLoginAt  time.Time `json:"last_login_at"`
 	IsActive     bool      `json:"is_active"`
+	Name         string    `json:"name"`
+	Locale       string    `json:"locale"`
 }
 
 func NewUser(phoneNumber string) *User {
@@ -43,5 +54,7 @@ func (u *User) ToResponse() *UserResponse {
 		RegisteredAt: u.RegisteredAt,
 		LastLoginAt:  u.LastLoginAt,
 		IsActive:     u.IsActive,
+		Name:         u.Name,
+		Locale:       u.Locale,
 	}
 }
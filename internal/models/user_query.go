@@ -0,0 +1,215 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Sort fields of the users list
+const (
+	UserSortRegisteredAt = "registered_at"
+	UserSortLastLoginAt  = "last_login_at"
+	UserSortPhoneNumber  = "phone_number"
+)
+
+// UserSortFields are the fields users can be sorted by
+var UserSortFields = []string{UserSortRegisteredAt, UserSortLastLoginAt, UserSortPhoneNumber}
+
+// Sort orders of the users list
+const (
+	SortOrderAsc  = "asc"
+	SortOrderDesc = "desc"
+)
+
+// GetUsersRequest holds the query parameters of GET /users
+type GetUsersRequest struct {
+	Page   string `form:"page"`
+	Limit  string `form:"limit"`
+	Search string `form:"search"`
+	Sort   string `form:"sort"`
+	Order  string `form:"order"`
+	Cursor string `form:"cursor"`
+
+	IsActive         string `form:"is_active"`
+	RegisteredAfter  string `form:"registered_after"`
+	RegisteredBefore string `form:"registered_before"`
+	LastLoginAfter   string `form:"last_login_after"`
+	LastLoginBefore  string `form:"last_login_before"`
+}
+
+// UserQuery selects a page of users. Users are ordered by Sort and then by
+// ID, so the order is stable even where sort values are equal. With a
+// Cursor, the page starts after the user the cursor points at and Page is
+// ignored.
+type UserQuery struct {
+	Page  int
+	Limit int
+	// Search matches phone numbers: a term starting with + is a prefix of
+	// the number, digits alone are found anywhere in it
+	Search string
+	Filter UserFilter
+	Sort   string
+	Desc   bool
+	Cursor *UserCursor
+}
+
+// UserFilter restricts the users a query matches. Zero fields do not
+// restrict; time ranges include their start and exclude their end.
+type UserFilter struct {
+	IsActive         *bool
+	RegisteredAfter  time.Time
+	RegisteredBefore time.Time
+	LastLoginAfter   time.Time
+	LastLoginBefore  time.Time
+}
+
+// IsZero reports whether the filter matches every user
+func (f UserFilter) IsZero() bool {
+	return f.IsActive == nil && f.RegisteredAfter.IsZero() && f.RegisteredBefore.IsZero() &&
+		f.LastLoginAfter.IsZero() && f.LastLoginBefore.IsZero()
+}
+
+// Matches reports whether user passes the filter
+func (f UserFilter) Matches(user *User) bool {
+	if f.IsActive != nil && user.IsActive != *f.IsActive {
+		return false
+	}
+	return inRange(user.RegisteredAt, f.RegisteredAfter, f.RegisteredBefore) &&
+		inRange(user.LastLoginAt, f.LastLoginAfter, f.LastLoginBefore)
+}
+
+func inRange(t, after, before time.Time) bool {
+	return (after.IsZero() || !t.Before(after)) && (before.IsZero() || t.Before(before))
+}
+
+// Filter parses the filter parameters of the request
+func (r *GetUsersRequest) Filter() (UserFilter, error) {
+	var filter UserFilter
+
+	if r.IsActive != "" {
+		active, err := strconv.ParseBool(r.IsActive)
+		if err != nil {
+			return filter, fmt.Errorf("is_active must be true or false")
+		}
+		filter.IsActive = &active
+	}
+
+	for _, param := range []struct {
+		name  string
+		value string
+		t     *time.Time
+	}{
+		{"registered_after", r.RegisteredAfter, &filter.RegisteredAfter},
+		{"registered_before", r.RegisteredBefore, &filter.RegisteredBefore},
+		{"last_login_after", r.LastLoginAfter, &filter.LastLoginAfter},
+		{"last_login_before", r.LastLoginBefore, &filter.LastLoginBefore},
+	} {
+		if param.value == "" {
+			continue
+		}
+		t, err := ParseUserTime(param.value)
+		if err != nil {
+			return filter, fmt.Errorf("%s: %v", param.name, err)
+		}
+		*param.t = t
+	}
+
+	if !filter.RegisteredAfter.IsZero() && !filter.RegisteredBefore.IsZero() && !filter.RegisteredAfter.Before(filter.RegisteredBefore) {
+		return filter, fmt.Errorf("registered_after must be before registered_before")
+	}
+	if !filter.LastLoginAfter.IsZero() && !filter.LastLoginBefore.IsZero() && !filter.LastLoginAfter.Before(filter.LastLoginBefore) {
+		return filter, fmt.Errorf("last_login_after must be before last_login_before")
+	}
+
+	return filter, nil
+}
+
+// ParseUserTime parses a time filter parameter, an RFC 3339 timestamp or a
+// date (midnight UTC)
+func ParseUserTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is neither an RFC 3339 time nor a date", value)
+	}
+	return t, nil
+}
+
+// UserPage is a page of users, the number of users matching the query and
+// whether more users follow the page
+type UserPage struct {
+	Users   []*User
+	Total   int
+	HasMore bool
+}
+
+// UserCursor is the position of a user in a sort order. It is handed to
+// clients as an opaque string.
+type UserCursor struct {
+	Sort  string `json:"s"`
+	Desc  bool   `json:"d,omitempty"`
+	Value string `json:"v"`
+	ID    string `json:"i"`
+}
+
+// NewUserCursor returns the position of user when sorting by sort
+func NewUserCursor(user *User, sort string, desc bool) *UserCursor {
+	cursor := &UserCursor{Sort: sort, Desc: desc, ID: user.ID}
+	switch sort {
+	case UserSortPhoneNumber:
+		cursor.Value = user.PhoneNumber
+	case UserSortLastLoginAt:
+		cursor.Value = user.LastLoginAt.UTC().Format(time.RFC3339Nano)
+	default:
+		cursor.Value = user.RegisteredAt.UTC().Format(time.RFC3339Nano)
+	}
+	return cursor
+}
+
+// ParseUserCursor decodes a cursor returned as next_cursor
+func ParseUserCursor(s string) (*UserCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("malformed cursor")
+	}
+
+	var cursor UserCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, fmt.Errorf("malformed cursor")
+	}
+	if cursor.ID == "" || !isUserSortField(cursor.Sort) {
+		return nil, fmt.Errorf("malformed cursor")
+	}
+	if cursor.Sort != UserSortPhoneNumber {
+		if _, err := cursor.Time(); err != nil {
+			return nil, fmt.Errorf("malformed cursor")
+		}
+	}
+
+	return &cursor, nil
+}
+
+// Encode returns the cursor as an opaque string
+func (c *UserCursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// Time returns the value of a cursor of a time field
+func (c *UserCursor) Time() (time.Time, error) {
+	return time.Parse(time.RFC3339Nano, c.Value)
+}
+
+func isUserSortField(field string) bool {
+	for _, f := range UserSortFields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
@@ -133,3 +133,66 @@ func TestUser_ImmutableFields(t *testing.T) {
 		t.Error("Original ID should not be affected")
 	}
 }
+
+func TestUserCursor(t *testing.T) {
+	user := NewUser("+1234567890")
+
+	for _, sort := range UserSortFields {
+		encoded := NewUserCursor(user, sort, true).Encode()
+		cursor, err := ParseUserCursor(encoded)
+		if err != nil {
+			t.Fatalf("Expected no error for %s, got %v", sort, err)
+		}
+		if cursor.Sort != sort || !cursor.Desc || cursor.ID != user.ID {
+			t.Errorf("Unexpected cursor for %s: %+v", sort, cursor)
+		}
+	}
+
+	cursor, _ := ParseUserCursor(NewUserCursor(user, UserSortLastLoginAt, false).Encode())
+	if at, err := cursor.Time(); err != nil || !at.Equal(user.LastLoginAt) {
+		t.Errorf("Expected last login time %v, got %v (%v)", user.LastLoginAt, at, err)
+	}
+
+	for _, invalid := range []string{"", "not base64!", "e30", (&UserCursor{Sort: "id", ID: "x"}).Encode(), (&UserCursor{Sort: UserSortRegisteredAt, Value: "yesterday", ID: "x"}).Encode()} {
+		if _, err := ParseUserCursor(invalid); err == nil {
+			t.Errorf("Expected error for cursor %q", invalid)
+		}
+	}
+}
+
+func TestGetUsersRequest_Filter(t *testing.T) {
+	req := GetUsersRequest{IsActive: "true", RegisteredAfter: "2024-01-01", LastLoginBefore: "2024-01-02T15:04:05+02:00"}
+	filter, err := req.Filter()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if filter.IsActive == nil || !*filter.IsActive {
+		t.Errorf("Expected active filter, got %v", filter.IsActive)
+	}
+	if want := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC); !filter.RegisteredAfter.Equal(want) {
+		t.Errorf("Expected %v, got %v", want, filter.RegisteredAfter)
+	}
+	if want := time.Date(2024, 1, 2, 13, 4, 5, 0, time.UTC); !filter.LastLoginBefore.Equal(want) {
+		t.Errorf("Expected %v, got %v", want, filter.LastLoginBefore)
+	}
+
+	user := NewUser("+1234567890")
+	user.RegisteredAt = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	user.LastLoginAt = time.Date(2024, 1, 2, 13, 4, 4, 0, time.UTC)
+	if !filter.Matches(user) {
+		t.Error("Expected user at the start of the range to match")
+	}
+	user.LastLoginAt = filter.LastLoginBefore
+	if filter.Matches(user) {
+		t.Error("Expected user at the end of the range not to match")
+	}
+
+	if filter, _ := (&GetUsersRequest{}).Filter(); !filter.IsZero() {
+		t.Errorf("Expected empty filter, got %+v", filter)
+	}
+	for _, invalid := range []GetUsersRequest{{IsActive: "yes"}, {RegisteredBefore: "tomorrow"}, {LastLoginAfter: "2024-02-01", LastLoginBefore: "2024-02-01"}} {
+		if _, err := invalid.Filter(); err == nil {
+			t.Errorf("Expected error for %+v", invalid)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package otp
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNewGenerator(t *testing.T) {
+	tests := []struct {
+		name     string
+		length   int
+		alphabet string
+		wantErr  bool
+	}{
+		{name: "default numeric", length: 6, alphabet: ""},
+		{name: "numeric", length: 8, alphabet: AlphabetNumeric},
+		{name: "alphanumeric", length: 6, alphabet: AlphabetAlphanumeric},
+		{name: "too short", length: 3, alphabet: AlphabetNumeric, wantErr: true},
+		{name: "too long", length: 13, alphabet: AlphabetNumeric, wantErr: true},
+		{name: "unknown alphabet", length: 6, alphabet: "emoji", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewGenerator(tt.length, tt.alphabet)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewGenerator() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGenerator_Generate(t *testing.T) {
+	for _, alphabet := range []string{AlphabetNumeric, AlphabetAlphanumeric} {
+		t.Run(alphabet, func(t *testing.T) {
+			g, err := NewGenerator(8, alphabet)
+			if err != nil {
+				t.Fatalf("Failed to create generator: %v", err)
+			}
+
+			seen := make(map[string]bool)
+			for i := 0; i < 100; i++ {
+				code, err := g.Generate()
+				if err != nil {
+					t.Fatalf("Expected no error, got %v", err)
+				}
+				if len(code) != 8 {
+					t.Fatalf("Expected length 8, got %d (%s)", len(code), code)
+				}
+				for _, c := range code {
+					if !strings.ContainsRune(g.Charset(), c) {
+						t.Fatalf("Code %s contains character %q outside the alphabet", code, c)
+					}
+				}
+				seen[code] = true
+			}
+
+			if len(seen) < 95 {
+				t.Errorf("Expected codes to be unique, got %d distinct out of 100", len(seen))
+			}
+		})
+	}
+}
+
+func TestAlphanumericCharset_NoAmbiguousCharacters(t *testing.T) {
+	charset, _ := Charset(AlphabetAlphanumeric)
+	for _, c := range "0O1IL" {
+		if strings.ContainsRune(charset, c) {
+			t.Errorf("Alphanumeric charset should not contain %q", c)
+		}
+	}
+}
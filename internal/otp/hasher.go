@@ -0,0 +1,49 @@
+package otp
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+)
+
+// Hasher computes keyed digests of OTP codes so that only the digest has
+// to be stored. The purpose and phone number are part of the MAC input, so
+// a digest cannot be copied to another phone number's or flow's record.
+type Hasher struct {
+	secret []byte
+}
+
+func NewHasher(secret string) *Hasher {
+	return &Hasher{
+		secret: []byte(secret),
+	}
+}
+
+// Hash returns the hex encoded HMAC-SHA256 of the code bound to the purpose
+// and phone number
+func (h *Hasher) Hash(purpose, phoneNumber, code string) string {
+	mac := hmac.New(sha256.New, h.secret)
+	mac.Write([]byte(purpose))
+	mac.Write([]byte{0})
+	mac.Write([]byte(phoneNumber))
+	mac.Write([]byte{0})
+	mac.Write([]byte(code))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// LegacyHash returns the digest of OTPs stored before purposes existed,
+// which was bound to the phone number only
+func (h *Hasher) LegacyHash(phoneNumber, code string) string {
+	mac := hmac.New(sha256.New, h.secret)
+	mac.Write([]byte(phoneNumber))
+	mac.Write([]byte{0})
+	mac.Write([]byte(code))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify reports whether code matches digest, in constant time
+func (h *Hasher) Verify(purpose, phoneNumber, code, digest string) bool {
+	expected := h.Hash(purpose, phoneNumber, code)
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(digest)) == 1
+}
@@ -0,0 +1,46 @@
+package otp
+
+import "testing"
+
+func TestHasher_HashAndVerify(t *testing.T) {
+	hasher := NewHasher("secret")
+
+	digest := hasher.Hash("login", "+1234567890", "123456")
+	if digest == "123456" || len(digest) != 64 {
+		t.Fatalf("Expected hex encoded HMAC-SHA256 digest, got %s", digest)
+	}
+
+	if !hasher.Verify("login", "+1234567890", "123456", digest) {
+		t.Error("Expected matching code to verify")
+	}
+	if hasher.Verify("login", "+1234567890", "654321", digest) {
+		t.Error("Expected wrong code to fail verification")
+	}
+	if hasher.Verify("login", "+1987654321", "123456", digest) {
+		t.Error("Expected digest to be bound to the phone number")
+	}
+	if hasher.Verify("account_deletion", "+1234567890", "123456", digest) {
+		t.Error("Expected digest to be bound to the purpose")
+	}
+}
+
+func TestHasher_LegacyHash(t *testing.T) {
+	hasher := NewHasher("secret")
+
+	legacy := hasher.LegacyHash("+1234567890", "123456")
+	if len(legacy) != 64 || legacy == hasher.Hash("login", "+1234567890", "123456") {
+		t.Errorf("Expected a distinct legacy digest, got %s", legacy)
+	}
+	if legacy != hasher.LegacyHash("+1234567890", "123456") || legacy == hasher.LegacyHash("+1987654321", "123456") {
+		t.Error("Expected legacy digest to be deterministic and bound to the phone number")
+	}
+}
+
+func TestHasher_DifferentSecrets(t *testing.T) {
+	a := NewHasher("secret-a").Hash("login", "+1234567890", "123456")
+	b := NewHasher("secret-b").Hash("login", "+1234567890", "123456")
+
+	if a == b {
+		t.Error("Expected different secrets to produce different digests")
+	}
+}
@@ -0,0 +1,69 @@
+package otp
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+)
+
+// Sealer encrypts codes with AES-256-GCM so an issued OTP can be re-sent
+// without keeping it readable in Redis. The phone number is bound as
+// additional data, so a sealed code only opens for its own record.
+type Sealer struct {
+	aead cipher.AEAD
+}
+
+// NewSealer derives the encryption key from the server secret
+func NewSealer(secret string) (*Sealer, error) {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("otp-seal-key"))
+
+	block, err := aes.NewCipher(mac.Sum(nil))
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Sealer{
+		aead: aead,
+	}, nil
+}
+
+// Seal returns the encrypted code as base64
+func (s *Sealer) Seal(phoneNumber, code string) (string, error) {
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	sealed := s.aead.Seal(nonce, nonce, []byte(code), []byte(phoneNumber))
+	return base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// Open decrypts a code produced by Seal for the same phone number
+func (s *Sealer) Open(phoneNumber, sealed string) (string, error) {
+	data, err := base64.RawStdEncoding.DecodeString(sealed)
+	if err != nil {
+		return "", err
+	}
+
+	if len(data) < s.aead.NonceSize() {
+		return "", fmt.Errorf("sealed code too short")
+	}
+
+	nonce, ciphertext := data[:s.aead.NonceSize()], data[s.aead.NonceSize():]
+	code, err := s.aead.Open(nil, nonce, ciphertext, []byte(phoneNumber))
+	if err != nil {
+		return "", err
+	}
+
+	return string(code), nil
+}
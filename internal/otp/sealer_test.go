@@ -0,0 +1,37 @@
+package otp
+
+import "testing"
+
+func TestSealer_SealAndOpen(t *testing.T) {
+	sealer, err := NewSealer("secret")
+	if err != nil {
+		t.Fatalf("Failed to create sealer: %v", err)
+	}
+
+	sealed, err := sealer.Seal("+1234567890", "123456")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if sealed == "123456" {
+		t.Fatal("Expected code to be encrypted")
+	}
+
+	code, err := sealer.Open("+1234567890", sealed)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if code != "123456" {
+		t.Errorf("Expected code 123456, got %s", code)
+	}
+
+	// Sealed codes are bound to their phone number
+	if _, err := sealer.Open("+1987654321", sealed); err == nil {
+		t.Error("Expected error when opening with another phone number")
+	}
+
+	// And to the server secret
+	other, _ := NewSealer("other-secret")
+	if _, err := other.Open("+1234567890", sealed); err == nil {
+		t.Error("Expected error when opening with another secret")
+	}
+}
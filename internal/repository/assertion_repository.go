@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"otp-auth-service/internal/errors"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const clientAssertionKeyPrefix = "client_assertion:"
+
+// AssertionRepository remembers the private_key_jwt client assertions that
+// were used, so a captured assertion cannot be replayed (RFC 7523 section 3)
+type AssertionRepository interface {
+	// MarkUsed records the jti of a client's assertion until it expires.
+	// It reports false if the assertion was used before.
+	MarkUsed(clientID, tokenID string, expiresAt time.Time) (bool, error)
+}
+
+type RedisAssertionRepository struct {
+	client *redis.Client
+}
+
+func NewAssertionRepository(client *redis.Client) AssertionRepository {
+	return &RedisAssertionRepository{
+		client: client,
+	}
+}
+
+func (r *RedisAssertionRepository) MarkUsed(clientID, tokenID string, expiresAt time.Time) (bool, error) {
+	ctx := context.Background()
+
+	ttl := time.Until(expiresAt)
+	if ttl <= 0 {
+		// Expired assertions are rejected before they get here
+		ttl = time.Second
+	}
+
+	ok, err := r.client.SetNX(ctx, clientAssertionKeyPrefix+clientID+":"+tokenID, 1, ttl).Result()
+	if err != nil {
+		return false, errors.ErrRedisError.WithDetails(err.Error())
+	}
+
+	return ok, nil
+}
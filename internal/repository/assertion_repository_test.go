@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func newTestAssertionRepository(t *testing.T) (*RedisAssertionRepository, *miniredis.Miniredis) {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	return NewAssertionRepository(client).(*RedisAssertionRepository), mr
+}
+
+func TestRedisAssertionRepository_MarkUsed(t *testing.T) {
+	repo, mr := newTestAssertionRepository(t)
+	expiresAt := time.Now().Add(time.Minute)
+
+	first, err := repo.MarkUsed("batch", "jti-1", expiresAt)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !first {
+		t.Error("Expected first use to be accepted")
+	}
+
+	again, _ := repo.MarkUsed("batch", "jti-1", expiresAt)
+	if again {
+		t.Error("Expected replay to be rejected")
+	}
+
+	// IDs are scoped to the client
+	other, _ := repo.MarkUsed("reports", "jti-1", expiresAt)
+	if !other {
+		t.Error("Expected the same jti of another client to be accepted")
+	}
+
+	// Entries only live as long as the assertion
+	mr.FastForward(2 * time.Minute)
+	if accepted, _ := repo.MarkUsed("batch", "jti-1", time.Now().Add(time.Minute)); !accepted {
+		t.Error("Expected entry to expire with the assertion")
+	}
+}
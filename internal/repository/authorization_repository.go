@@ -0,0 +1,150 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"otp-auth-service/internal/errors"
+	"otp-auth-service/internal/models"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	authorizationRequestKeyPrefix = "oauth_request:"
+	authorizationCodeKeyPrefix    = "oauth_code:"
+)
+
+// AuthorizationRepository stores pending OAuth authorization requests and
+// the codes issued for them. Both expire on their own.
+type AuthorizationRepository interface {
+	CreateRequest(request *models.AuthorizationRequest) error
+	GetRequest(id string) (*models.AuthorizationRequest, error)
+	// UpdateRequest overwrites a pending request without extending its lifetime
+	UpdateRequest(request *models.AuthorizationRequest) error
+	DeleteRequest(id string) error
+	CreateCode(code string, authorizationCode *models.AuthorizationCode) error
+	// ConsumeCode returns a code's record and deletes it, so every code can
+	// be exchanged once
+	ConsumeCode(code string) (*models.AuthorizationCode, error)
+}
+
+type RedisAuthorizationRepository struct {
+	client *redis.Client
+}
+
+func NewAuthorizationRepository(client *redis.Client) AuthorizationRepository {
+	return &RedisAuthorizationRepository{
+		client: client,
+	}
+}
+
+func (r *RedisAuthorizationRepository) CreateRequest(request *models.AuthorizationRequest) error {
+	ctx := context.Background()
+
+	requestJSON, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+
+	if err := r.client.Set(ctx, authorizationRequestKeyPrefix+request.ID, requestJSON, time.Until(request.ExpiresAt)).Err(); err != nil {
+		return errors.ErrRedisError.WithDetails(err.Error())
+	}
+
+	return nil
+}
+
+func (r *RedisAuthorizationRepository) GetRequest(id string) (*models.AuthorizationRequest, error) {
+	ctx := context.Background()
+
+	requestJSON, err := r.client.Get(ctx, authorizationRequestKeyPrefix+id).Result()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, errors.ErrAuthorizationRequestNotFound
+		}
+		return nil, errors.ErrRedisError.WithDetails(err.Error())
+	}
+
+	var request models.AuthorizationRequest
+	if err := json.Unmarshal([]byte(requestJSON), &request); err != nil {
+		return nil, err
+	}
+
+	return &request, nil
+}
+
+func (r *RedisAuthorizationRepository) UpdateRequest(request *models.AuthorizationRequest) error {
+	ctx := context.Background()
+
+	requestJSON, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+
+	updated, err := r.client.SetArgs(ctx, authorizationRequestKeyPrefix+request.ID, requestJSON, redis.SetArgs{
+		Mode:    "XX",
+		KeepTTL: true,
+	}).Result()
+	if err != nil {
+		if err == redis.Nil {
+			return errors.ErrAuthorizationRequestNotFound
+		}
+		return errors.ErrRedisError.WithDetails(err.Error())
+	}
+	if updated != "OK" {
+		return errors.ErrAuthorizationRequestNotFound
+	}
+
+	return nil
+}
+
+func (r *RedisAuthorizationRepository) DeleteRequest(id string) error {
+	ctx := context.Background()
+
+	if err := r.client.Del(ctx, authorizationRequestKeyPrefix+id).Err(); err != nil {
+		return errors.ErrRedisError.WithDetails(err.Error())
+	}
+
+	return nil
+}
+
+func (r *RedisAuthorizationRepository) CreateCode(code string, authorizationCode *models.AuthorizationCode) error {
+	ctx := context.Background()
+
+	codeJSON, err := json.Marshal(authorizationCode)
+	if err != nil {
+		return err
+	}
+
+	if err := r.client.Set(ctx, authorizationCodeKey(code), codeJSON, time.Until(authorizationCode.ExpiresAt)).Err(); err != nil {
+		return errors.ErrRedisError.WithDetails(err.Error())
+	}
+
+	return nil
+}
+
+func (r *RedisAuthorizationRepository) ConsumeCode(code string) (*models.AuthorizationCode, error) {
+	ctx := context.Background()
+
+	codeJSON, err := r.client.GetDel(ctx, authorizationCodeKey(code)).Result()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, errors.ErrInvalidAuthorizationCode
+		}
+		return nil, errors.ErrRedisError.WithDetails(err.Error())
+	}
+
+	var authorizationCode models.AuthorizationCode
+	if err := json.Unmarshal([]byte(codeJSON), &authorizationCode); err != nil {
+		return nil, err
+	}
+
+	return &authorizationCode, nil
+}
+
+// authorizationCodeKey stores codes by hash, like refresh tokens, so a
+// Redis dump does not hand out usable codes
+func authorizationCodeKey(code string) string {
+	return authorizationCodeKeyPrefix + hashToken(code)
+}
@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"otp-auth-service/internal/models"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func newTestAuthorizationRepository(t *testing.T) (*RedisAuthorizationRepository, *miniredis.Miniredis) {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	return NewAuthorizationRepository(client).(*RedisAuthorizationRepository), mr
+}
+
+func TestRedisAuthorizationRepository_Requests(t *testing.T) {
+	repo, mr := newTestAuthorizationRepository(t)
+
+	request := &models.AuthorizationRequest{
+		ID:          "request-1",
+		ClientID:    "spa",
+		RedirectURI: "https://app.example.com/callback",
+		Scopes:      []string{"profile"},
+		ExpiresAt:   time.Now().Add(10 * time.Minute),
+	}
+	if err := repo.CreateRequest(request); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// Updates keep the original lifetime
+	mr.FastForward(5 * time.Minute)
+	request.PhoneNumber = "+1234567890"
+	if err := repo.UpdateRequest(request); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if ttl := mr.TTL(authorizationRequestKeyPrefix + "request-1"); ttl > 5*time.Minute {
+		t.Errorf("Expected update to keep the TTL, got %v", ttl)
+	}
+
+	stored, err := repo.GetRequest("request-1")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if stored.PhoneNumber != "+1234567890" || stored.ClientID != "spa" {
+		t.Errorf("Unexpected request: %+v", stored)
+	}
+
+	// Expired requests cannot be updated back to life
+	mr.FastForward(6 * time.Minute)
+	_, err = repo.GetRequest("request-1")
+	assertErrorCode(t, err, "AUTHORIZATION_REQUEST_NOT_FOUND")
+	err = repo.UpdateRequest(request)
+	assertErrorCode(t, err, "AUTHORIZATION_REQUEST_NOT_FOUND")
+}
+
+func TestRedisAuthorizationRepository_ConsumeCode(t *testing.T) {
+	repo, mr := newTestAuthorizationRepository(t)
+
+	err := repo.CreateCode("code-1", &models.AuthorizationCode{
+		ClientID:  "spa",
+		UserID:    "user-1",
+		ExpiresAt: time.Now().Add(time.Minute),
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// Codes are stored by hash
+	if mr.Exists(authorizationCodeKeyPrefix + "code-1") {
+		t.Error("Expected code not to be stored in the clear")
+	}
+
+	code, err := repo.ConsumeCode("code-1")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if code.UserID != "user-1" {
+		t.Errorf("Expected user-1, got %s", code.UserID)
+	}
+
+	_, err = repo.ConsumeCode("code-1")
+	assertErrorCode(t, err, "INVALID_AUTHORIZATION_CODE")
+}
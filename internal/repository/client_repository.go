@@ -0,0 +1,36 @@
+package repository
+
+import (
+	"otp-auth-service/internal/errors"
+	"otp-auth-service/internal/models"
+)
+
+// ClientRepository looks up registered OAuth clients
+type ClientRepository interface {
+	GetByID(id string) (*models.OAuthClient, error)
+}
+
+// StaticClientRepository serves the clients registered in the config file
+type StaticClientRepository struct {
+	clients map[string]*models.OAuthClient
+}
+
+func NewClientRepository(clients []*models.OAuthClient) ClientRepository {
+	byID := make(map[string]*models.OAuthClient, len(clients))
+	for _, client := range clients {
+		byID[client.ID] = client
+	}
+
+	return &StaticClientRepository{
+		clients: byID,
+	}
+}
+
+func (r *StaticClientRepository) GetByID(id string) (*models.OAuthClient, error) {
+	client, exists := r.clients[id]
+	if !exists {
+		return nil, errors.ErrClientNotFound
+	}
+
+	return client, nil
+}
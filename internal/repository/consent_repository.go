@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+
+	"otp-auth-service/internal/errors"
+	"otp-auth-service/internal/models"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// consentKeyPrefix keys a hash per user of client ID -> consent
+const consentKeyPrefix = "consent:"
+
+// ConsentRepository stores the scopes users have granted to OAuth clients.
+// Consents do not expire; users revoke them.
+type ConsentRepository interface {
+	Get(userID, clientID string) (*models.Consent, error)
+	Save(consent *models.Consent) error
+	GetByUserID(userID string) ([]*models.Consent, error)
+	Delete(userID, clientID string) error
+}
+
+type RedisConsentRepository struct {
+	client *redis.Client
+}
+
+func NewConsentRepository(client *redis.Client) ConsentRepository {
+	return &RedisConsentRepository{
+		client: client,
+	}
+}
+
+func (r *RedisConsentRepository) Get(userID, clientID string) (*models.Consent, error) {
+	ctx := context.Background()
+
+	consentJSON, err := r.client.HGet(ctx, consentKeyPrefix+userID, clientID).Result()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, errors.ErrConsentNotFound
+		}
+		return nil, errors.ErrRedisError.WithDetails(err.Error())
+	}
+
+	var consent models.Consent
+	if err := json.Unmarshal([]byte(consentJSON), &consent); err != nil {
+		return nil, err
+	}
+
+	return &consent, nil
+}
+
+func (r *RedisConsentRepository) Save(consent *models.Consent) error {
+	ctx := context.Background()
+
+	consentJSON, err := json.Marshal(consent)
+	if err != nil {
+		return err
+	}
+
+	if err := r.client.HSet(ctx, consentKeyPrefix+consent.UserID, consent.ClientID, consentJSON).Err(); err != nil {
+		return errors.ErrRedisError.WithDetails(err.Error())
+	}
+
+	return nil
+}
+
+// GetByUserID returns the user's consents, most recently granted first
+func (r *RedisConsentRepository) GetByUserID(userID string) ([]*models.Consent, error) {
+	ctx := context.Background()
+
+	values, err := r.client.HGetAll(ctx, consentKeyPrefix+userID).Result()
+	if err != nil {
+		return nil, errors.ErrRedisError.WithDetails(err.Error())
+	}
+
+	consents := make([]*models.Consent, 0, len(values))
+	for _, consentJSON := range values {
+		var consent models.Consent
+		if err := json.Unmarshal([]byte(consentJSON), &consent); err != nil {
+			return nil, err
+		}
+		consents = append(consents, &consent)
+	}
+
+	sort.Slice(consents, func(i, j int) bool {
+		return consents[i].GrantedAt.After(consents[j].GrantedAt)
+	})
+
+	return consents, nil
+}
+
+func (r *RedisConsentRepository) Delete(userID, clientID string) error {
+	ctx := context.Background()
+
+	deleted, err := r.client.HDel(ctx, consentKeyPrefix+userID, clientID).Result()
+	if err != nil {
+		return errors.ErrRedisError.WithDetails(err.Error())
+	}
+	if deleted == 0 {
+		return errors.ErrConsentNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"otp-auth-service/internal/models"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func newTestConsentRepository(t *testing.T) *RedisConsentRepository {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	return NewConsentRepository(client).(*RedisConsentRepository)
+}
+
+func TestRedisConsentRepository(t *testing.T) {
+	repo := newTestConsentRepository(t)
+
+	_, err := repo.Get("user-1", "spa")
+	assertErrorCode(t, err, "CONSENT_NOT_FOUND")
+
+	now := time.Now()
+	consents := []*models.Consent{
+		{UserID: "user-1", ClientID: "spa", Scopes: []string{"profile"}, GrantedAt: now.Add(-time.Hour)},
+		{UserID: "user-1", ClientID: "partner", Scopes: []string{"orders"}, GrantedAt: now},
+		{UserID: "user-2", ClientID: "spa", Scopes: []string{"profile"}, GrantedAt: now},
+	}
+	for _, consent := range consents {
+		if err := repo.Save(consent); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+
+	consent, err := repo.Get("user-1", "spa")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(consent.Scopes) != 1 || consent.Scopes[0] != "profile" {
+		t.Errorf("Unexpected consent: %+v", consent)
+	}
+
+	list, err := repo.GetByUserID("user-1")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(list) != 2 || list[0].ClientID != "partner" {
+		t.Errorf("Expected 2 consents, most recent first, got %+v", list)
+	}
+
+	if err := repo.Delete("user-1", "spa"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	err = repo.Delete("user-1", "spa")
+	assertErrorCode(t, err, "CONSENT_NOT_FOUND")
+
+	// Other users' consents are untouched
+	if _, err := repo.Get("user-2", "spa"); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+}
@@ -0,0 +1,10 @@
+CREATE TABLE users (
+    id            TEXT PRIMARY KEY,
+    phone_number  TEXT NOT NULL,
+    registered_at TIMESTAMPTZ NOT NULL,
+    last_login_at TIMESTAMPTZ NOT NULL,
+    is_active     BOOLEAN NOT NULL DEFAULT TRUE
+);
+
+CREATE UNIQUE INDEX users_phone_number_key ON users (phone_number);
+CREATE INDEX users_registered_at_idx ON users (registered_at, id);
@@ -0,0 +1,2 @@
+-- Sorting the users list by last login
+CREATE INDEX users_last_login_at_idx ON users (last_login_at, id);
@@ -0,0 +1,18 @@
+-- Every suffix of the digits of each phone number. A substring of a number
+-- starts one of its suffixes, so substring search is a prefix search here.
+CREATE TABLE user_phone_suffixes (
+    user_id TEXT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    suffix  TEXT NOT NULL,
+    PRIMARY KEY (user_id, suffix)
+);
+
+INSERT INTO user_phone_suffixes (user_id, suffix)
+SELECT id, substr(ltrim(phone_number, '+'), n)
+FROM users, generate_series(1, length(ltrim(phone_number, '+'))) AS n;
+
+-- Prefix searches use LIKE, which needs pattern indexes under non-C collations
+CREATE INDEX user_phone_suffixes_suffix_idx ON user_phone_suffixes (suffix text_pattern_ops);
+CREATE INDEX users_phone_number_pattern_idx ON users (phone_number text_pattern_ops);
+
+-- Filtering the users list by status
+CREATE INDEX users_is_active_idx ON users (is_active, registered_at, id);
@@ -0,0 +1,3 @@
+-- Profile fields users edit through PATCH /users/me
+ALTER TABLE users ADD COLUMN name TEXT NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN locale TEXT NOT NULL DEFAULT '';
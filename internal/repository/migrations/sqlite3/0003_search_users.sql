@@ -0,0 +1,19 @@
+-- Every suffix of the digits of each phone number. A substring of a number
+-- starts one of its suffixes, so substring search is a prefix search here.
+CREATE TABLE user_phone_suffixes (
+    user_id TEXT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    suffix  TEXT NOT NULL,
+    PRIMARY KEY (user_id, suffix)
+);
+
+WITH RECURSIVE suffixes (user_id, suffix) AS (
+    SELECT id, ltrim(phone_number, '+') FROM users
+    UNION ALL
+    SELECT user_id, substr(suffix, 2) FROM suffixes WHERE length(suffix) > 1
+)
+INSERT INTO user_phone_suffixes (user_id, suffix) SELECT user_id, suffix FROM suffixes;
+
+CREATE INDEX user_phone_suffixes_suffix_idx ON user_phone_suffixes (suffix);
+
+-- Filtering the users list by status
+CREATE INDEX users_is_active_idx ON users (is_active, registered_at, id);
@@ -4,145 +4,226 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"math/rand"
 	"time"
 
+	"otp-auth-service/internal/config"
 	"otp-auth-service/internal/errors"
 	"otp-auth-service/internal/models"
+	"otp-auth-service/internal/otp"
 
 	"github.com/redis/go-redis/v9"
 )
 
+// OTPRepository stores OTPs per phone number and purpose. An OTP issued
+// for one purpose can never be verified for another. The rate limit and
+// resend cooldown are per phone number and shared by all purposes, so
+// switching purposes does not buy more messages.
 type OTPRepository interface {
-	GenerateOTP(phoneNumber string) (string, error)
-	VerifyOTP(phoneNumber, otp string) (bool, error)
-	IsRateLimited(phoneNumber string) (bool, error)
-	GetOTP(phoneNumber string) (*models.OTP, error)
+	GenerateOTP(phoneNumber string, purpose models.OTPPurpose) (*models.IssuedOTP, error)
+	ResendOTP(phoneNumber string, purpose models.OTPPurpose) (*models.IssuedOTP, error)
+	VerifyOTP(phoneNumber, otp string, purpose models.OTPPurpose) (bool, error)
+	IsRateLimited(phoneNumber string, purpose models.OTPPurpose) (bool, error)
+	GetOTP(phoneNumber string, purpose models.OTPPurpose) (*models.OTP, error)
 }
 
 type RedisOTPRepository struct {
-	client *redis.Client
+	client    *redis.Client
+	generator *otp.Generator
+	hasher    *otp.Hasher
+	sealer    *otp.Sealer
+	policies  map[models.OTPPurpose]config.OTPPolicy
 }
 
-func NewOTPRepository(client *redis.Client) OTPRepository {
+func NewOTPRepository(client *redis.Client, generator *otp.Generator, hasher *otp.Hasher, sealer *otp.Sealer, policies map[models.OTPPurpose]config.OTPPolicy) OTPRepository {
 	return &RedisOTPRepository{
-		client: client,
+		client:    client,
+		generator: generator,
+		hasher:    hasher,
+		sealer:    sealer,
+		policies:  policies,
 	}
 }
 
-func (r *RedisOTPRepository) GenerateOTP(phoneNumber string) (string, error) {
+func (r *RedisOTPRepository) GenerateOTP(phoneNumber string, purpose models.OTPPurpose) (*models.IssuedOTP, error) {
 	ctx := context.Background()
 
-	// Check rate limiting
-	isLimited, err := r.IsRateLimited(phoneNumber)
+	policy, err := r.policy(purpose)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	if isLimited {
-		return "", errors.ErrRateLimitExceeded.WithDetails(fmt.Sprintf("phone number: %s", phoneNumber))
+
+	// Generate OTP
+	code, err := r.generator.Generate()
+	if err != nil {
+		return nil, err
 	}
 
-	// Generate 6-digit OTP
-	otp := fmt.Sprintf("%06d", rand.Intn(1000000))
+	sealedCode, err := r.sealer.Seal(phoneNumber, code)
+	if err != nil {
+		return nil, err
+	}
 
 	// Create OTP object
 	otpData := &models.OTP{
 		PhoneNumber: phoneNumber,
-		Code:        otp,
-		ExpiresAt:   time.Now().Add(2 * time.Minute), // 2 minutes expiry
+		Purpose:     purpose,
+		CodeHash:    r.hasher.Hash(string(purpose), phoneNumber, code),
+		SealedCode:  sealedCode,
+		ExpiresAt:   time.Now().Add(policy.TTL),
 		Attempts:    0,
 	}
 
-	// Store OTP in Redis with 2 minutes expiry
-	otpKey := fmt.Sprintf("otp:%s", phoneNumber)
-	otpJSON, _ := json.Marshal(otpData)
-
-	err = r.client.Set(ctx, otpKey, otpJSON, 2*time.Minute).Err()
+	otpJSON, err := json.Marshal(otpData)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	// Update rate limiting counter
-	rateLimitKey := fmt.Sprintf("rate_limit:%s", phoneNumber)
-	pipe := r.client.Pipeline()
-	pipe.Incr(ctx, rateLimitKey)
-	pipe.Expire(ctx, rateLimitKey, 10*time.Minute) // 10 minutes window
-	_, err = pipe.Exec(ctx)
+	// Check rate limit and store OTP atomically
+	result, err := generateOTPScript.Run(ctx, r.client,
+		[]string{otpKey(policy, purpose, phoneNumber), r.rateLimitKey(phoneNumber), r.cooldownKey(phoneNumber)},
+		otpJSON, policy.TTL.Milliseconds(), policy.RateLimitMax, policy.RateLimitWindow.Milliseconds(),
+		policy.ResendCooldown.Milliseconds(),
+	).Int()
 	if err != nil {
-		return "", err
+		return nil, errors.ErrRedisError.WithDetails(err.Error())
+	}
+	if result != scriptOK {
+		return nil, errors.ErrRateLimitExceeded.WithDetails(fmt.Sprintf("phone number: %s", phoneNumber))
 	}
 
-	// Print OTP to console (for development)
-	fmt.Printf("OTP for %s: %s\n", phoneNumber, otp)
-
-	return otp, nil
+	return &models.IssuedOTP{
+		Code:       code,
+		ExpiresIn:  policy.TTL,
+		RetryAfter: policy.ResendCooldown,
+	}, nil
 }
 
-func (r *RedisOTPRepository) VerifyOTP(phoneNumber, otp string) (bool, error) {
+// ResendOTP returns the code that is still pending for the phone number so
+// it can be delivered again. It does not count against the rate limit but
+// enforces the resend cooldown, which only restarts once a code is re-sent.
+func (r *RedisOTPRepository) ResendOTP(phoneNumber string, purpose models.OTPPurpose) (*models.IssuedOTP, error) {
 	ctx := context.Background()
 
-	// Get OTP from Redis
-	otpKey := fmt.Sprintf("otp:%s", phoneNumber)
-	otpJSON, err := r.client.Get(ctx, otpKey).Result()
+	policy, err := r.policy(purpose)
 	if err != nil {
-		if err == redis.Nil {
-			return false, errors.ErrOTPNotFound
-		}
-		return false, err
+		return nil, err
+	}
+
+	values, err := resendOTPScript.Run(ctx, r.client,
+		[]string{otpKey(policy, purpose, phoneNumber), r.cooldownKey(phoneNumber)},
+		policy.ResendCooldown.Milliseconds(),
+	).Slice()
+	if err == nil && values[0] == int64(scriptNotFound) && values[1] == "" && purpose == models.PurposeLogin {
+		// Login OTPs sent before purposes existed, as in VerifyOTP
+		values, err = resendOTPScript.Run(ctx, r.client,
+			[]string{legacyOTPKey(policy, phoneNumber), r.cooldownKey(phoneNumber)},
+			policy.ResendCooldown.Milliseconds(),
+		).Slice()
+	}
+	if err != nil {
+		return nil, errors.ErrRedisError.WithDetails(err.Error())
+	}
+
+	result, _ := values[0].(int64)
+	otpJSON, _ := values[1].(string)
+	remaining, _ := values[2].(int64)
+
+	switch {
+	case result == scriptNotFound && otpJSON != "":
+		return nil, errors.ErrOTPNotFound.WithDetails("OTP cannot be re-sent, please request a new one")
+	case result == scriptNotFound:
+		return nil, errors.ErrOTPNotFound
+	case result == scriptRejected:
+		wait := time.Duration(remaining) * time.Millisecond
+		return nil, errors.ErrResendCooldown.WithRetryAfter(int((wait + time.Second - 1) / time.Second))
 	}
 
 	var otpData models.OTP
-	err = json.Unmarshal([]byte(otpJSON), &otpData)
+	if err := json.Unmarshal([]byte(otpJSON), &otpData); err != nil {
+		return nil, err
+	}
+
+	code := otpData.Code
+	if otpData.SealedCode != "" {
+		code, err = r.sealer.Open(phoneNumber, otpData.SealedCode)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &models.IssuedOTP{
+		Code:       code,
+		ExpiresIn:  time.Duration(remaining) * time.Millisecond,
+		RetryAfter: policy.ResendCooldown,
+	}, nil
+}
+
+func (r *RedisOTPRepository) VerifyOTP(phoneNumber, otp string, purpose models.OTPPurpose) (bool, error) {
+	ctx := context.Background()
+
+	policy, err := r.policy(purpose)
 	if err != nil {
 		return false, err
 	}
 
-	// Check if OTP is expired
-	if time.Now().After(otpData.ExpiresAt) {
-		// Delete expired OTP
-		r.client.Del(ctx, otpKey)
-		return false, errors.ErrOTPExpired
+	// Compare, count attempts and delete in a single script so parallel
+	// guesses cannot get past the attempt limit
+	result, err := verifyOTPScript.Run(ctx, r.client,
+		[]string{otpKey(policy, purpose, phoneNumber)},
+		r.hasher.Hash(string(purpose), phoneNumber, otp), otp, policy.MaxAttempts,
+	).Int()
+	if err == nil && result == scriptNotFound && purpose == models.PurposeLogin {
+		// OTPs sent before purposes existed are login OTPs, stored under the
+		// phone number alone and hashed without the purpose. The script
+		// deletes them once used up like any other record.
+		result, err = verifyOTPScript.Run(ctx, r.client,
+			[]string{legacyOTPKey(policy, phoneNumber)},
+			r.hasher.LegacyHash(phoneNumber, otp), otp, policy.MaxAttempts,
+		).Int()
+	}
+	if err != nil {
+		return false, errors.ErrRedisError.WithDetails(err.Error())
 	}
 
-	// Check if OTP matches
-	if otpData.Code != otp {
-				// Increment attempts
-		otpData.Attempts++
-		if otpData.Attempts >= 3 {
-			// Delete OTP after 3 failed attempts
-			r.client.Del(ctx, otpKey)
-			return false, errors.ErrTooManyAttempts
-		}
-		
-		// Update OTP data
-		otpJSON, _ := json.Marshal(otpData)
-		r.client.Set(ctx, otpKey, otpJSON, 2*time.Minute)
+	switch result {
+	case scriptOK:
+		return true, nil
+	case scriptNotFound:
+		return false, errors.ErrOTPNotFound
+	case scriptExpired:
+		return false, errors.ErrOTPExpired
+	case scriptTooManyAttempts:
+		return false, errors.ErrTooManyAttempts
+	default:
 		return false, errors.ErrInvalidOTP
 	}
-
-	// OTP is valid, delete it
-	r.client.Del(ctx, otpKey)
-	return true, nil
 }
 
-func (r *RedisOTPRepository) IsRateLimited(phoneNumber string) (bool, error) {
+func (r *RedisOTPRepository) IsRateLimited(phoneNumber string, purpose models.OTPPurpose) (bool, error) {
 	ctx := context.Background()
 
-	rateLimitKey := fmt.Sprintf("rate_limit:%s", phoneNumber)
-	count, err := r.client.Get(ctx, rateLimitKey).Int()
+	policy, err := r.policy(purpose)
+	if err != nil {
+		return false, err
+	}
+
+	count, err := r.client.Get(ctx, r.rateLimitKey(phoneNumber)).Int()
 	if err != nil && err != redis.Nil {
 		return false, err
 	}
 
-	// Allow max 3 requests per 10 minutes
-	return count >= 3, nil
+	return count >= policy.RateLimitMax, nil
 }
 
-func (r *RedisOTPRepository) GetOTP(phoneNumber string) (*models.OTP, error) {
+func (r *RedisOTPRepository) GetOTP(phoneNumber string, purpose models.OTPPurpose) (*models.OTP, error) {
 	ctx := context.Background()
 
-	otpKey := fmt.Sprintf("otp:%s", phoneNumber)
-	otpJSON, err := r.client.Get(ctx, otpKey).Result()
+	policy, err := r.policy(purpose)
+	if err != nil {
+		return nil, err
+	}
+
+	otpJSON, err := r.client.Get(ctx, otpKey(policy, purpose, phoneNumber)).Result()
 	if err != nil {
 		if err == redis.Nil {
 			return nil, fmt.Errorf("OTP not found")
@@ -158,3 +239,30 @@ func (r *RedisOTPRepository) GetOTP(phoneNumber string) (*models.OTP, error) {
 
 	return &otpData, nil
 }
+
+func (r *RedisOTPRepository) policy(purpose models.OTPPurpose) (config.OTPPolicy, error) {
+	policy, ok := r.policies[purpose]
+	if !ok {
+		return config.OTPPolicy{}, errors.ErrInvalidOTPPurpose.WithDetails(fmt.Sprintf("purpose: %s", purpose))
+	}
+	return policy, nil
+}
+
+func otpKey(policy config.OTPPolicy, purpose models.OTPPurpose, phoneNumber string) string {
+	return fmt.Sprintf("%s:%s:%s", policy.KeyPrefix, purpose, phoneNumber)
+}
+
+// legacyOTPKey is where login OTPs were stored before purposes existed
+func legacyOTPKey(policy config.OTPPolicy, phoneNumber string) string {
+	return fmt.Sprintf("%s:%s", policy.KeyPrefix, phoneNumber)
+}
+
+// rateLimitKey and cooldownKey are shared by all purposes. They use the
+// prefixes of the login policy, so per-purpose prefixes cannot split them.
+func (r *RedisOTPRepository) rateLimitKey(phoneNumber string) string {
+	return fmt.Sprintf("%s:%s", r.policies[models.PurposeLogin].RateLimitKeyPrefix, phoneNumber)
+}
+
+func (r *RedisOTPRepository) cooldownKey(phoneNumber string) string {
+	return fmt.Sprintf("%s:%s", r.policies[models.PurposeLogin].CooldownKeyPrefix, phoneNumber)
+}
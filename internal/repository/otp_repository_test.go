@@ -0,0 +1,444 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"otp-auth-service/internal/config"
+	"otp-auth-service/internal/errors"
+	"otp-auth-service/internal/models"
+	"otp-auth-service/internal/otp"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func newTestOTPRepository(t *testing.T) (*RedisOTPRepository, *miniredis.Miniredis) {
+	return newTestOTPRepositoryWithPolicy(t, config.DefaultOTPPolicy())
+}
+
+func newTestOTPRepositoryWithPolicy(t *testing.T, policy config.OTPPolicy) (*RedisOTPRepository, *miniredis.Miniredis) {
+	policies := make(map[models.OTPPurpose]config.OTPPolicy)
+	for _, purpose := range models.OTPPurposes {
+		policies[purpose] = policy
+	}
+	return newTestOTPRepositoryWithPolicies(t, policies)
+}
+
+func newTestOTPRepositoryWithPolicies(t *testing.T, policies map[models.OTPPurpose]config.OTPPolicy) (*RedisOTPRepository, *miniredis.Miniredis) {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	generator, err := otp.NewGenerator(6, otp.AlphabetNumeric)
+	if err != nil {
+		t.Fatalf("Failed to create generator: %v", err)
+	}
+
+	sealer, err := otp.NewSealer("test-secret")
+	if err != nil {
+		t.Fatalf("Failed to create sealer: %v", err)
+	}
+
+	repo := NewOTPRepository(client, generator, otp.NewHasher("test-secret"), sealer, policies).(*RedisOTPRepository)
+	return repo, mr
+}
+
+func assertErrorCode(t *testing.T, err error, code string) {
+	t.Helper()
+
+	domainErr, ok := err.(*errors.DomainError)
+	if !ok {
+		t.Fatalf("Expected DomainError %s, got %v", code, err)
+	}
+	if domainErr.Code != code {
+		t.Errorf("Expected error code %s, got %s", code, domainErr.Code)
+	}
+}
+
+func TestRedisOTPRepository_GenerateAndVerify(t *testing.T) {
+	repo, mr := newTestOTPRepository(t)
+
+	issued, err := repo.GenerateOTP("+1234567890", models.PurposeLogin)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	code := issued.Code
+
+	// Only the digest is stored
+	stored, err := mr.Get("otp:login:+1234567890")
+	if err != nil {
+		t.Fatalf("Expected OTP to be stored: %v", err)
+	}
+	var otpData models.OTP
+	if err := json.Unmarshal([]byte(stored), &otpData); err != nil {
+		t.Fatalf("Failed to decode stored OTP: %v", err)
+	}
+	if otpData.Code != "" || otpData.CodeHash == "" || strings.Contains(stored, code) {
+		t.Errorf("Expected only the code hash to be stored, got %s", stored)
+	}
+
+	valid, err := repo.VerifyOTP("+1234567890", code, models.PurposeLogin)
+	if err != nil || !valid {
+		t.Fatalf("Expected OTP to verify, got %v, %v", valid, err)
+	}
+
+	// OTP is single use
+	_, err = repo.VerifyOTP("+1234567890", code, models.PurposeLogin)
+	assertErrorCode(t, err, "OTP_NOT_FOUND")
+}
+
+func TestRedisOTPRepository_VerifyKeepsTTL(t *testing.T) {
+	repo, mr := newTestOTPRepository(t)
+
+	if _, err := repo.GenerateOTP("+1234567890", models.PurposeLogin); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	mr.FastForward(90 * time.Second)
+
+	_, err := repo.VerifyOTP("+1234567890", "000000x", models.PurposeLogin)
+	assertErrorCode(t, err, "INVALID_OTP")
+
+	if ttl := mr.TTL("otp:login:+1234567890"); ttl > 30*time.Second {
+		t.Errorf("Expected failed attempt to keep remaining TTL, got %v", ttl)
+	}
+}
+
+func TestRedisOTPRepository_TooManyAttempts(t *testing.T) {
+	repo, _ := newTestOTPRepository(t)
+
+	issued, err := repo.GenerateOTP("+1234567890", models.PurposeLogin)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	code := issued.Code
+
+	_, err = repo.VerifyOTP("+1234567890", "wrong1", models.PurposeLogin)
+	assertErrorCode(t, err, "INVALID_OTP")
+	_, err = repo.VerifyOTP("+1234567890", "wrong2", models.PurposeLogin)
+	assertErrorCode(t, err, "INVALID_OTP")
+	_, err = repo.VerifyOTP("+1234567890", "wrong3", models.PurposeLogin)
+	assertErrorCode(t, err, "TOO_MANY_ATTEMPTS")
+
+	// The correct code no longer works once the OTP is burned
+	_, err = repo.VerifyOTP("+1234567890", code, models.PurposeLogin)
+	assertErrorCode(t, err, "OTP_NOT_FOUND")
+}
+
+func TestRedisOTPRepository_ParallelGuesses(t *testing.T) {
+	repo, _ := newTestOTPRepository(t)
+
+	if _, err := repo.GenerateOTP("+1234567890", models.PurposeLogin); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	var wg sync.WaitGroup
+	var mutex sync.Mutex
+	counts := make(map[string]int)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := repo.VerifyOTP("+1234567890", "wrong", models.PurposeLogin)
+			mutex.Lock()
+			counts[errors.GetDomainError(err).Code]++
+			mutex.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	if counts["INVALID_OTP"] != 2 || counts["TOO_MANY_ATTEMPTS"] != 1 {
+		t.Errorf("Expected exactly 2 invalid and 1 too-many-attempts result, got %v", counts)
+	}
+}
+
+func TestRedisOTPRepository_RateLimit(t *testing.T) {
+	repo, mr := newTestOTPRepository(t)
+
+	for i := 0; i < 3; i++ {
+		if _, err := repo.GenerateOTP("+1234567890", models.PurposeLogin); err != nil {
+			t.Fatalf("Request %d: expected no error, got %v", i+1, err)
+		}
+	}
+
+	_, err := repo.GenerateOTP("+1234567890", models.PurposeLogin)
+	assertErrorCode(t, err, "RATE_LIMIT_EXCEEDED")
+
+	limited, err := repo.IsRateLimited("+1234567890", models.PurposeLogin)
+	if err != nil || !limited {
+		t.Errorf("Expected phone number to be rate limited, got %v, %v", limited, err)
+	}
+
+	// The window is not extended by further requests
+	if ttl := mr.TTL("rate_limit:+1234567890"); ttl <= 0 || ttl > 10*time.Minute {
+		t.Errorf("Expected rate limit window TTL, got %v", ttl)
+	}
+
+	mr.FastForward(10 * time.Minute)
+	if _, err := repo.GenerateOTP("+1234567890", models.PurposeLogin); err != nil {
+		t.Errorf("Expected new window to allow requests, got %v", err)
+	}
+}
+
+func TestRedisOTPRepository_LegacyPlaintextRecord(t *testing.T) {
+	repo, mr := newTestOTPRepository(t)
+
+	legacy, _ := json.Marshal(&models.OTP{
+		PhoneNumber: "+1234567890",
+		Code:        "123456",
+		ExpiresAt:   time.Now().Add(2 * time.Minute),
+	})
+	if err := repo.client.Set(context.Background(), "otp:+1234567890", legacy, 2*time.Minute).Err(); err != nil {
+		t.Fatalf("Failed to store legacy OTP: %v", err)
+	}
+
+	_, err := repo.VerifyOTP("+1234567890", "654321", models.PurposeLogin)
+	assertErrorCode(t, err, "INVALID_OTP")
+
+	// Legacy records are login OTPs only
+	_, err = repo.VerifyOTP("+1234567890", "123456", models.PurposeAccountDeletion)
+	assertErrorCode(t, err, "OTP_NOT_FOUND")
+
+	valid, err := repo.VerifyOTP("+1234567890", "123456", models.PurposeLogin)
+	if err != nil || !valid {
+		t.Errorf("Expected legacy OTP to verify, got %v, %v", valid, err)
+	}
+	if mr.Exists("otp:+1234567890") {
+		t.Error("Expected legacy OTP to be deleted once used")
+	}
+}
+
+func TestRedisOTPRepository_LegacyHashedRecord(t *testing.T) {
+	repo, mr := newTestOTPRepository(t)
+
+	legacy, _ := json.Marshal(&models.OTP{
+		PhoneNumber: "+1234567890",
+		CodeHash:    repo.hasher.LegacyHash("+1234567890", "123456"),
+		ExpiresAt:   time.Now().Add(2 * time.Minute),
+	})
+	if err := repo.client.Set(context.Background(), "otp:+1234567890", legacy, 2*time.Minute).Err(); err != nil {
+		t.Fatalf("Failed to store legacy OTP: %v", err)
+	}
+
+	valid, err := repo.VerifyOTP("+1234567890", "123456", models.PurposeLogin)
+	if err != nil || !valid {
+		t.Errorf("Expected legacy OTP to verify, got %v, %v", valid, err)
+	}
+	if mr.Exists("otp:+1234567890") {
+		t.Error("Expected legacy OTP to be deleted once used")
+	}
+
+	_, err = repo.VerifyOTP("+1234567890", "123456", models.PurposeLogin)
+	assertErrorCode(t, err, "OTP_NOT_FOUND")
+}
+
+func TestRedisOTPRepository_CustomPolicy(t *testing.T) {
+	policy := config.OTPPolicy{
+		TTL:                30 * time.Second,
+		MaxAttempts:        1,
+		RateLimitWindow:    time.Minute,
+		RateLimitMax:       1,
+		ResendCooldown:     10 * time.Second,
+		KeyPrefix:          "test_otp",
+		RateLimitKeyPrefix: "test_rl",
+		CooldownKeyPrefix:  "test_cd",
+	}
+	repo, mr := newTestOTPRepositoryWithPolicy(t, policy)
+
+	if _, err := repo.GenerateOTP("+1234567890", models.PurposeLogin); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if ttl := mr.TTL("test_otp:login:+1234567890"); ttl != 30*time.Second {
+		t.Errorf("Expected OTP TTL of 30s, got %v", ttl)
+	}
+	if ttl := mr.TTL("test_rl:+1234567890"); ttl != time.Minute {
+		t.Errorf("Expected rate limit window of 1m, got %v", ttl)
+	}
+
+	_, err := repo.GenerateOTP("+1234567890", models.PurposeLogin)
+	assertErrorCode(t, err, "RATE_LIMIT_EXCEEDED")
+
+	_, err = repo.VerifyOTP("+1234567890", "wrong", models.PurposeLogin)
+	assertErrorCode(t, err, "TOO_MANY_ATTEMPTS")
+}
+
+func TestRedisOTPRepository_ResendOTP(t *testing.T) {
+	repo, mr := newTestOTPRepository(t)
+
+	issued, err := repo.GenerateOTP("+1234567890", models.PurposeLogin)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// Cooldown starts with the first send
+	_, err = repo.ResendOTP("+1234567890", models.PurposeLogin)
+	assertErrorCode(t, err, "RESEND_COOLDOWN")
+	if domainErr := errors.GetDomainError(err); domainErr.RetryAfter != 30 {
+		t.Errorf("Expected retry after 30s, got %d", domainErr.RetryAfter)
+	}
+
+	mr.FastForward(31 * time.Second)
+
+	resent, err := repo.ResendOTP("+1234567890", models.PurposeLogin)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if resent.Code != issued.Code {
+		t.Errorf("Expected the same code to be re-sent, got %s and %s", issued.Code, resent.Code)
+	}
+	if resent.ExpiresIn <= 0 || resent.ExpiresIn > 89*time.Second {
+		t.Errorf("Expected remaining lifetime of the original OTP, got %v", resent.ExpiresIn)
+	}
+
+	// Resending does not use up the request quota
+	if limited, _ := repo.IsRateLimited("+1234567890", models.PurposeLogin); limited {
+		t.Error("Expected resend not to count against the rate limit")
+	}
+
+	// The re-sent code still verifies
+	valid, err := repo.VerifyOTP("+1234567890", resent.Code, models.PurposeLogin)
+	if err != nil || !valid {
+		t.Errorf("Expected re-sent OTP to verify, got %v, %v", valid, err)
+	}
+}
+
+func TestRedisOTPRepository_ResendOTP_NotFound(t *testing.T) {
+	repo, _ := newTestOTPRepository(t)
+
+	_, err := repo.ResendOTP("+1234567890", models.PurposeLogin)
+	assertErrorCode(t, err, "OTP_NOT_FOUND")
+}
+
+func TestRedisOTPRepository_ResendLegacyRecord(t *testing.T) {
+	repo, mr := newTestOTPRepository(t)
+	ctx := context.Background()
+
+	// Hashed records cannot be re-sent and must not start the cooldown
+	hashed, _ := json.Marshal(&models.OTP{
+		PhoneNumber: "+1234567890",
+		CodeHash:    repo.hasher.LegacyHash("+1234567890", "123456"),
+		ExpiresAt:   time.Now().Add(2 * time.Minute),
+	})
+	if err := repo.client.Set(ctx, "otp:+1234567890", hashed, 2*time.Minute).Err(); err != nil {
+		t.Fatalf("Failed to store legacy OTP: %v", err)
+	}
+	_, err := repo.ResendOTP("+1234567890", models.PurposeLogin)
+	assertErrorCode(t, err, "OTP_NOT_FOUND")
+	if details := errors.GetDomainError(err).Details; details == "" {
+		t.Error("Expected details asking for a new OTP")
+	}
+	if mr.Exists(repo.cooldownKey("+1234567890")) {
+		t.Error("Expected no cooldown for an OTP that was not re-sent")
+	}
+
+	plaintext, _ := json.Marshal(&models.OTP{
+		PhoneNumber: "+1234567890",
+		Code:        "123456",
+		ExpiresAt:   time.Now().Add(2 * time.Minute),
+	})
+	if err := repo.client.Set(ctx, "otp:+1234567890", plaintext, 2*time.Minute).Err(); err != nil {
+		t.Fatalf("Failed to store legacy OTP: %v", err)
+	}
+
+	// Legacy records are login OTPs only
+	_, err = repo.ResendOTP("+1234567890", models.PurposeAccountDeletion)
+	assertErrorCode(t, err, "OTP_NOT_FOUND")
+
+	resent, err := repo.ResendOTP("+1234567890", models.PurposeLogin)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if resent.Code != "123456" {
+		t.Errorf("Expected the legacy code to be re-sent, got %s", resent.Code)
+	}
+	_, err = repo.ResendOTP("+1234567890", models.PurposeLogin)
+	assertErrorCode(t, err, "RESEND_COOLDOWN")
+}
+
+func TestRedisOTPRepository_PurposeIsolation(t *testing.T) {
+	repo, _ := newTestOTPRepository(t)
+
+	issued, err := repo.GenerateOTP("+1234567890", models.PurposeAccountDeletion)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// A code issued for account deletion cannot be used to log in
+	_, err = repo.VerifyOTP("+1234567890", issued.Code, models.PurposeLogin)
+	assertErrorCode(t, err, "OTP_NOT_FOUND")
+
+	// Copying the record under the login key does not help either
+	stored, _ := repo.GetOTP("+1234567890", models.PurposeAccountDeletion)
+	storedJSON, _ := json.Marshal(stored)
+	repo.client.Set(context.Background(), "otp:login:+1234567890", storedJSON, time.Minute)
+	_, err = repo.VerifyOTP("+1234567890", issued.Code, models.PurposeLogin)
+	assertErrorCode(t, err, "INVALID_OTP")
+
+	valid, err := repo.VerifyOTP("+1234567890", issued.Code, models.PurposeAccountDeletion)
+	if err != nil || !valid {
+		t.Errorf("Expected OTP to verify for its own purpose, got %v, %v", valid, err)
+	}
+}
+
+func TestRedisOTPRepository_SharedRateLimit(t *testing.T) {
+	repo, mr := newTestOTPRepository(t)
+
+	// Cycling purposes does not get around the per-phone quota
+	for _, purpose := range models.OTPPurposes[:3] {
+		if _, err := repo.GenerateOTP("+1234567890", purpose); err != nil {
+			t.Fatalf("Expected no error for %s, got %v", purpose, err)
+		}
+	}
+	_, err := repo.GenerateOTP("+1234567890", models.PurposeTransactionConfirm)
+	assertErrorCode(t, err, "RATE_LIMIT_EXCEEDED")
+
+	// Nor the resend cooldown
+	_, err = repo.ResendOTP("+1234567890", models.PurposeLogin)
+	assertErrorCode(t, err, "RESEND_COOLDOWN")
+
+	if !mr.Exists("rate_limit:+1234567890") || !mr.Exists("otp_cooldown:+1234567890") {
+		t.Error("Expected per-phone rate limit and cooldown keys")
+	}
+}
+
+func TestRedisOTPRepository_PurposePolicies(t *testing.T) {
+	strict := config.DefaultOTPPolicy()
+	strict.MaxAttempts = 1
+	strict.RateLimitMax = 1
+
+	repo, _ := newTestOTPRepositoryWithPolicies(t, map[models.OTPPurpose]config.OTPPolicy{
+		models.PurposeLogin:           config.DefaultOTPPolicy(),
+		models.PurposeAccountDeletion: strict,
+	})
+
+	if _, err := repo.GenerateOTP("+1234567890", models.PurposeLogin); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// The quota is shared: the login OTP used up the single deletion slot
+	_, err := repo.GenerateOTP("+1234567890", models.PurposeAccountDeletion)
+	assertErrorCode(t, err, "RATE_LIMIT_EXCEEDED")
+
+	repo, _ = newTestOTPRepositoryWithPolicies(t, map[models.OTPPurpose]config.OTPPolicy{
+		models.PurposeLogin:           config.DefaultOTPPolicy(),
+		models.PurposeAccountDeletion: strict,
+	})
+	if _, err := repo.GenerateOTP("+1234567890", models.PurposeAccountDeletion); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	_, err = repo.VerifyOTP("+1234567890", "wrong", models.PurposeAccountDeletion)
+	assertErrorCode(t, err, "TOO_MANY_ATTEMPTS")
+
+	// Purposes without a policy are rejected
+	_, err = repo.GenerateOTP("+1234567890", models.PurposeTransactionConfirm)
+	assertErrorCode(t, err, "INVALID_OTP_PURPOSE")
+}
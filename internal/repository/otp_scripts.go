@@ -0,0 +1,114 @@
+package repository
+
+import "github.com/redis/go-redis/v9"
+
+// Result codes returned by the OTP scripts
+const (
+	scriptOK              = 1
+	scriptRejected        = 0
+	scriptNotFound        = -1
+	scriptTooManyAttempts = -2
+	scriptExpired         = -3
+)
+
+// generateOTPScript reserves a slot in the rate limit window and stores the
+// OTP in a single step, so concurrent requests cannot exceed the quota.
+// It also starts the resend cooldown.
+//
+// KEYS[1] OTP key, KEYS[2] rate limit key, KEYS[3] cooldown key
+// ARGV[1] OTP JSON, ARGV[2] OTP TTL (ms), ARGV[3] max requests, ARGV[4] window (ms),
+// ARGV[5] resend cooldown (ms)
+var generateOTPScript = redis.NewScript(`
+local count = tonumber(redis.call('GET', KEYS[2]) or '0')
+if count >= tonumber(ARGV[3]) then
+	return 0
+end
+
+redis.call('INCR', KEYS[2])
+if redis.call('PTTL', KEYS[2]) < 0 then
+	redis.call('PEXPIRE', KEYS[2], ARGV[4])
+end
+
+redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[2])
+redis.call('SET', KEYS[3], '1', 'PX', ARGV[5])
+return 1
+`)
+
+// verifyOTPScript checks a candidate code, counts failed attempts and
+// deletes the OTP once it is used up. The stored TTL is left untouched.
+// Digests are compared in constant time.
+//
+// KEYS[1] OTP key
+// ARGV[1] candidate digest, ARGV[2] candidate code (for plaintext records), ARGV[3] max attempts
+var verifyOTPScript = redis.NewScript(`
+local function equal(a, b)
+	if #a ~= #b then
+		return false
+	end
+	local diff = 0
+	for i = 1, #a do
+		diff = diff + math.abs(string.byte(a, i) - string.byte(b, i))
+	end
+	return diff == 0
+end
+
+local raw = redis.call('GET', KEYS[1])
+if not raw then
+	return -1
+end
+
+if redis.call('PTTL', KEYS[1]) <= 0 then
+	redis.call('DEL', KEYS[1])
+	return -3
+end
+
+local otp = cjson.decode(raw)
+local matched = false
+if type(otp.code_hash) == 'string' and otp.code_hash ~= '' then
+	matched = equal(otp.code_hash, ARGV[1])
+elseif type(otp.code) == 'string' and otp.code ~= '' then
+	matched = equal(otp.code, ARGV[2])
+end
+
+if matched then
+	redis.call('DEL', KEYS[1])
+	return 1
+end
+
+otp.attempts = (tonumber(otp.attempts) or 0) + 1
+if otp.attempts >= tonumber(ARGV[3]) then
+	redis.call('DEL', KEYS[1])
+	return -2
+end
+
+redis.call('SET', KEYS[1], cjson.encode(otp), 'KEEPTTL')
+return 0
+`)
+
+// resendOTPScript returns the stored OTP for re-delivery unless the resend
+// cooldown is still running, and restarts the cooldown. It returns
+// {result, OTP JSON, remaining OTP TTL or cooldown (ms)}. Records that hold
+// no code to re-send, like hashed ones written before codes were sealed,
+// are returned with scriptNotFound and leave the cooldown alone.
+//
+// KEYS[1] OTP key, KEYS[2] cooldown key
+// ARGV[1] resend cooldown (ms)
+var resendOTPScript = redis.NewScript(`
+local raw = redis.call('GET', KEYS[1])
+if not raw then
+	return {-1, '', 0}
+end
+
+local otp = cjson.decode(raw)
+if (otp.sealed_code or '') == '' and (otp.code or '') == '' then
+	return {-1, raw, 0}
+end
+
+local wait = redis.call('PTTL', KEYS[2])
+if wait > 0 then
+	return {0, '', wait}
+end
+
+redis.call('SET', KEYS[2], '1', 'PX', ARGV[1])
+return {1, raw, redis.call('PTTL', KEYS[1])}
+`)
@@ -0,0 +1,427 @@
+package repository
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"otp-auth-service/internal/errors"
+	"otp-auth-service/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	userKeyPrefix      = "user:"
+	userPhoneKeyPrefix = "user_phone:"
+	// usersByPhoneSuffix holds "<digit suffix>:<ID>" members for every
+	// suffix of each number's digits, for substring searches
+	usersByPhoneSuffix = "users:by_phone_suffix"
+	// usersQueryPrefix names the temporary sorted sets that filtered
+	// queries are paged from
+	usersQueryPrefix = "users:query:"
+)
+
+// RedisUserRepository keeps users in Redis hashes, so every replica sees
+// the same users. A key per phone number points to the user's ID and keeps
+// numbers unique.
+type RedisUserRepository struct {
+	client *redis.Client
+}
+
+func NewRedisUserRepository(client *redis.Client) UserRepository {
+	return &RedisUserRepository{
+		client: client,
+	}
+}
+
+func (r *RedisUserRepository) Create(user *models.User) error {
+	ctx := context.Background()
+
+	result, err := createUserScript.Run(ctx, r.client, r.userKeys(user), userArgs(user)...).Int()
+	if err != nil {
+		return errors.ErrRedisError.WithDetails(err.Error())
+	}
+	if result != scriptOK {
+		return errors.ErrUserAlreadyExists
+	}
+
+	return nil
+}
+
+func (r *RedisUserRepository) GetByPhoneNumber(phoneNumber string) (*models.User, error) {
+	ctx := context.Background()
+
+	id, err := r.client.Get(ctx, userPhoneKeyPrefix+phoneNumber).Result()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, errors.ErrUserNotFound
+		}
+		return nil, errors.ErrRedisError.WithDetails(err.Error())
+	}
+
+	return r.GetByID(id)
+}
+
+func (r *RedisUserRepository) GetByID(id string) (*models.User, error) {
+	ctx := context.Background()
+
+	fields, err := r.client.HGetAll(ctx, userKeyPrefix+id).Result()
+	if err != nil {
+		return nil, errors.ErrRedisError.WithDetails(err.Error())
+	}
+	if len(fields) == 0 {
+		return nil, errors.ErrUserNotFound
+	}
+
+	return parseUserHash(fields)
+}
+
+func (r *RedisUserRepository) Update(user *models.User) error {
+	ctx := context.Background()
+
+	result, err := updateUserScript.Run(ctx, r.client, r.userKeys(user), append(userArgs(user), userPhoneKeyPrefix)...).Int()
+	if err != nil {
+		return errors.ErrRedisError.WithDetails(err.Error())
+	}
+
+	switch result {
+	case scriptOK:
+		return nil
+	case scriptNotFound:
+		return errors.ErrUserNotFound
+	default:
+		return errors.ErrUserAlreadyExists
+	}
+}
+
+// phoneScoreDigits is the most digits a phone number has (E.164)
+const phoneScoreDigits = 15
+
+// phoneScore maps a phone number to a score in its lexicographic order.
+// Each digit is a base 11 digit one higher than its value, padded with
+// zeros to phoneScoreDigits, so numbers sort before the longer numbers
+// they are a prefix of. 11^15 < 2^53, so float64 scores hold it exactly.
+func phoneScore(phone string) int64 {
+	digits := strings.TrimPrefix(phone, "+")
+	var score int64
+	for i := 0; i < phoneScoreDigits; i++ {
+		score *= 11
+		if i < len(digits) {
+			score += int64(digits[i]-'0') + 1
+		}
+	}
+	return score
+}
+
+// phonePrefixBounds returns the scores of the numbers starting with
+// prefix. It reports false for prefixes longer than any number.
+func phonePrefixBounds(prefix string) (scoreBounds, bool) {
+	digits := strings.TrimPrefix(prefix, "+")
+	if len(digits) > phoneScoreDigits {
+		return scoreBounds{}, false
+	}
+
+	span := int64(1)
+	for i := len(digits); i < phoneScoreDigits; i++ {
+		span *= 11
+	}
+	min := phoneScore(prefix)
+	return scoreBounds{min: float64(min), max: float64(min + span)}, true
+}
+
+// scoreBounds selects the scores from min up to but excluding max
+type scoreBounds struct {
+	min, max float64
+}
+
+var unbounded = scoreBounds{min: math.Inf(-1), max: math.Inf(1)}
+
+// timeBounds selects the scores of times from after up to before, either
+// of which may be zero
+func timeBounds(after, before time.Time) scoreBounds {
+	bounds := unbounded
+	if !after.IsZero() {
+		bounds.min = float64(after.UnixMicro())
+	}
+	if !before.IsZero() {
+		bounds.max = float64(before.UnixMicro())
+	}
+	return bounds
+}
+
+// args returns the bounds as ZRANGEBYSCORE arguments
+func (b scoreBounds) args() (string, string) {
+	min, max := "-inf", "+inf"
+	if !math.IsInf(b.min, -1) {
+		min = strconv.FormatFloat(b.min, 'f', -1, 64)
+	}
+	if !math.IsInf(b.max, 1) {
+		max = "(" + strconv.FormatFloat(b.max, 'f', -1, 64)
+	}
+	return min, max
+}
+
+// indexRange restricts a query to the users whose score in index is
+// within bounds
+type indexRange struct {
+	index  string
+	bounds scoreBounds
+}
+
+// usersQueryTTL bounds how long the sorted set of a filtered query
+// outlives the query if deleting it fails
+const usersQueryTTL = time.Minute
+
+// GetAll reads each page from a sort index. The index of the queried
+// status is paged directly, within the bounds of a time range or phone
+// prefix on the sort field. Otherwise the users of the restriction that
+// matches the fewest are looked up in the other restrictions, and those
+// matching every one are stored in a temporary sorted set the page is read
+// from. The other restrictions are only counted, so a query costs as much
+// as the fewest users any one restriction matches.
+func (r *RedisUserRepository) GetAll(query models.UserQuery) (*models.UserPage, error) {
+	ctx := context.Background()
+	status := query.Filter.IsActive
+	index := sortIndexName(query.Sort, status)
+
+	var ranges []indexRange
+	filter := query.Filter
+	if !filter.RegisteredAfter.IsZero() || !filter.RegisteredBefore.IsZero() {
+		ranges = append(ranges, indexRange{
+			index:  sortIndexName(models.UserSortRegisteredAt, status),
+			bounds: timeBounds(filter.RegisteredAfter, filter.RegisteredBefore),
+		})
+	}
+	if !filter.LastLoginAfter.IsZero() || !filter.LastLoginBefore.IsZero() {
+		ranges = append(ranges, indexRange{
+			index:  sortIndexName(models.UserSortLastLoginAt, status),
+			bounds: timeBounds(filter.LastLoginAfter, filter.LastLoginBefore),
+		})
+	}
+
+	var substring *phoneSearch
+	if query.Search != "" {
+		search, ok := parsePhoneSearch(query.Search)
+		if !ok {
+			return &models.UserPage{Users: []*models.User{}}, nil
+		}
+		if search.substring {
+			substring = &search
+		} else {
+			bounds, ok := phonePrefixBounds(search.term)
+			if !ok {
+				return &models.UserPage{Users: []*models.User{}}, nil
+			}
+			ranges = append(ranges, indexRange{index: sortIndexName(models.UserSortPhoneNumber, status), bounds: bounds})
+		}
+	}
+
+	bounds := unbounded
+	if substring == nil && len(ranges) == 1 && ranges[0].index == index {
+		bounds = ranges[0].bounds
+	} else if substring != nil || len(ranges) > 0 {
+		matches, _, err := r.storeMatches(ctx, index, ranges, substring)
+		if err != nil {
+			return nil, errors.ErrRedisError.WithDetails(err.Error())
+		}
+		defer r.client.Del(ctx, matches)
+		index = matches
+	}
+
+	return r.page(ctx, index, bounds, query)
+}
+
+// storeMatches stores the users of sortIndex that match every range and
+// the substring search in a temporary sorted set, scored like sortIndex,
+// and returns its key and the number of candidates checked
+func (r *RedisUserRepository) storeMatches(ctx context.Context, sortIndex string, ranges []indexRange, substring *phoneSearch) (string, int, error) {
+	key := usersQueryPrefix + uuid.New().String()
+	keys := []string{key, sortIndex}
+	args := []interface{}{len(ranges), usersQueryTTL.Milliseconds(), userKeyPrefix}
+	for _, rg := range ranges {
+		min, max := rg.bounds.args()
+		keys = append(keys, rg.index)
+		args = append(args, min, max)
+	}
+	if substring != nil {
+		min, max := prefixRange(substring.term)
+		keys = append(keys, usersByPhoneSuffix)
+		args = append(args, "["+min, "("+max)
+	}
+
+	scanned, err := filterUsersScript.Run(ctx, r.client, keys, args...).Int()
+	if err != nil {
+		return "", 0, err
+	}
+	return key, scanned, nil
+}
+
+// page returns the page of query from the users of index within bounds
+func (r *RedisUserRepository) page(ctx context.Context, index string, bounds scoreBounds, query models.UserQuery) (*models.UserPage, error) {
+	min, max := bounds.args()
+	total, err := r.client.ZCount(ctx, index, min, max).Result()
+	if err != nil {
+		return nil, errors.ErrRedisError.WithDetails(err.Error())
+	}
+	page := &models.UserPage{Users: []*models.User{}, Total: int(total)}
+	if query.Limit < 1 || (query.Cursor == nil && query.Page < 1) {
+		return page, nil
+	}
+
+	// One member more than asked for tells whether another page follows
+	var ids []string
+	if query.Cursor == nil {
+		by := &redis.ZRangeBy{Min: min, Max: max, Offset: int64((query.Page - 1) * query.Limit), Count: int64(query.Limit + 1)}
+		if query.Desc {
+			ids, err = r.client.ZRevRangeByScore(ctx, index, by).Result()
+		} else {
+			ids, err = r.client.ZRangeByScore(ctx, index, by).Result()
+		}
+	} else {
+		ids, err = r.rangeAfterCursor(ctx, index, bounds, query)
+	}
+	if err != nil {
+		return nil, errors.ErrRedisError.WithDetails(err.Error())
+	}
+
+	if len(ids) > query.Limit {
+		ids = ids[:query.Limit]
+		page.HasMore = true
+	}
+	if page.Users, err = r.getUsers(ctx, ids); err != nil {
+		return nil, err
+	}
+
+	return page, nil
+}
+
+// rangeAfterCursor returns the members of index within bounds after the
+// cursor. Members sharing the cursor's score are ordered by ID; those up
+// to the cursor's ID are skipped.
+func (r *RedisUserRepository) rangeAfterCursor(ctx context.Context, index string, bounds scoreBounds, query models.UserQuery) ([]string, error) {
+	var score float64
+	if query.Cursor.Sort == models.UserSortPhoneNumber {
+		score = float64(phoneScore(query.Cursor.Value))
+	} else {
+		t, _ := query.Cursor.Time()
+		score = float64(t.UnixMicro())
+	}
+
+	// Cursors outside the bounds start the range at its beginning
+	if (!query.Desc && score < bounds.min) || (query.Desc && score >= bounds.max) {
+		min, max := bounds.args()
+		if query.Desc {
+			return r.client.ZRevRangeByScore(ctx, index, &redis.ZRangeBy{Max: max, Min: min, Count: int64(query.Limit + 1)}).Result()
+		}
+		return r.client.ZRangeByScore(ctx, index, &redis.ZRangeBy{Min: min, Max: max, Count: int64(query.Limit + 1)}).Result()
+	}
+
+	cursor := strconv.FormatFloat(score, 'f', -1, 64)
+	ties, err := r.client.ZRangeByScore(ctx, index, &redis.ZRangeBy{Min: cursor, Max: cursor}).Result()
+	if err != nil {
+		return nil, err
+	}
+	var skip int64
+	for _, id := range ties {
+		if (!query.Desc && id <= query.Cursor.ID) || (query.Desc && id >= query.Cursor.ID) {
+			skip++
+		}
+	}
+
+	min, max := bounds.args()
+	if query.Desc {
+		return r.client.ZRevRangeByScore(ctx, index, &redis.ZRangeBy{Max: cursor, Min: min, Offset: skip, Count: int64(query.Limit + 1)}).Result()
+	}
+	return r.client.ZRangeByScore(ctx, index, &redis.ZRangeBy{Min: cursor, Max: max, Offset: skip, Count: int64(query.Limit + 1)}).Result()
+}
+
+// getUsers loads users in the order of ids, skipping IDs whose user is gone
+func (r *RedisUserRepository) getUsers(ctx context.Context, ids []string) ([]*models.User, error) {
+	users := []*models.User{}
+	if len(ids) == 0 {
+		return users, nil
+	}
+
+	pipe := r.client.Pipeline()
+	cmds := make([]*redis.MapStringStringCmd, len(ids))
+	for i, id := range ids {
+		cmds[i] = pipe.HGetAll(ctx, userKeyPrefix+id)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, errors.ErrRedisError.WithDetails(err.Error())
+	}
+
+	for _, cmd := range cmds {
+		fields := cmd.Val()
+		if len(fields) == 0 {
+			continue
+		}
+		user, err := parseUserHash(fields)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+
+	return users, nil
+}
+
+// userKeys are the KEYS of the user scripts: the sort indexes of all,
+// active and inactive users follow the user's own keys
+func (r *RedisUserRepository) userKeys(user *models.User) []string {
+	keys := []string{userKeyPrefix + user.ID, userPhoneKeyPrefix + user.PhoneNumber, usersByPhoneSuffix}
+	active, inactive := true, false
+	for _, status := range []*bool{nil, &active, &inactive} {
+		for _, index := range sortIndexNames {
+			keys = append(keys, sortIndexName(index.field, status))
+		}
+	}
+	return keys
+}
+
+// userArgs are the ARGV of the user scripts. Times are scored in
+// microseconds, which float64 scores hold exactly, and phone numbers by
+// phoneScore.
+func userArgs(user *models.User) []interface{} {
+	active := "0"
+	if user.IsActive {
+		active = "1"
+	}
+	return []interface{}{
+		user.ID,
+		user.PhoneNumber,
+		user.RegisteredAt.UTC().Format(time.RFC3339Nano),
+		user.LastLoginAt.UTC().Format(time.RFC3339Nano),
+		active,
+		user.RegisteredAt.UnixMicro(),
+		user.LastLoginAt.UnixMicro(),
+		user.Name,
+		user.Locale,
+		phoneScore(user.PhoneNumber),
+	}
+}
+
+func parseUserHash(fields map[string]string) (*models.User, error) {
+	registeredAt, err := time.Parse(time.RFC3339Nano, fields["registered_at"])
+	if err != nil {
+		return nil, errors.ErrRedisError.WithDetails("malformed user " + fields["id"])
+	}
+	lastLoginAt, err := time.Parse(time.RFC3339Nano, fields["last_login_at"])
+	if err != nil {
+		return nil, errors.ErrRedisError.WithDetails("malformed user " + fields["id"])
+	}
+
+	return &models.User{
+		ID:           fields["id"],
+		PhoneNumber:  fields["phone_number"],
+		RegisteredAt: registeredAt,
+		LastLoginAt:  lastLoginAt,
+		IsActive:     fields["is_active"] == "1",
+		Name:         fields["name"],
+		Locale:       fields["locale"],
+	}, nil
+}
@@ -0,0 +1,279 @@
+package repository
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"otp-auth-service/internal/errors"
+	"otp-auth-service/internal/models"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func newTestRedisUserRepository(t *testing.T) (*RedisUserRepository, *miniredis.Miniredis) {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	return NewRedisUserRepository(client).(*RedisUserRepository), mr
+}
+
+func TestRedisUserRepository_CreateAndGet(t *testing.T) {
+	repo, _ := newTestRedisUserRepository(t)
+
+	user := models.NewUser("+1234567890")
+	if err := repo.Create(user); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := repo.Create(models.NewUser("+1234567890")); err != errors.ErrUserAlreadyExists {
+		t.Errorf("Expected USER_ALREADY_EXISTS, got %v", err)
+	}
+
+	stored, err := repo.GetByPhoneNumber("+1234567890")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if stored.ID != user.ID || !stored.IsActive || !stored.RegisteredAt.Equal(user.RegisteredAt) || !stored.LastLoginAt.Equal(user.LastLoginAt) {
+		t.Errorf("Expected %+v, got %+v", user, stored)
+	}
+
+	if _, err := repo.GetByID("non-existent-id"); err != errors.ErrUserNotFound {
+		t.Errorf("Expected USER_NOT_FOUND, got %v", err)
+	}
+	if _, err := repo.GetByPhoneNumber("+9999999999"); err != errors.ErrUserNotFound {
+		t.Errorf("Expected USER_NOT_FOUND, got %v", err)
+	}
+}
+
+func TestRedisUserRepository_ConcurrentCreate(t *testing.T) {
+	repo, _ := newTestRedisUserRepository(t)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	created := 0
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := repo.Create(models.NewUser("+1234567890")); err == nil {
+				mu.Lock()
+				created++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if created != 1 {
+		t.Errorf("Expected 1 user to be created, got %d", created)
+	}
+	if _, total, _ := getAll(repo, 1, 10, ""); total != 1 {
+		t.Errorf("Expected total 1, got %d", total)
+	}
+}
+
+func TestRedisUserRepository_Update(t *testing.T) {
+	repo, mr := newTestRedisUserRepository(t)
+
+	user := models.NewUser("+1234567890")
+	other := models.NewUser("+1987654321")
+	for _, u := range []*models.User{user, other} {
+		if err := repo.Create(u); err != nil {
+			t.Fatalf("Failed to create user: %v", err)
+		}
+	}
+
+	user.PhoneNumber = "+1555000111"
+	user.IsActive = false
+	if err := repo.Update(user); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	stored, err := repo.GetByPhoneNumber("+1555000111")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if stored.ID != user.ID || stored.IsActive {
+		t.Errorf("Expected updated user, got %+v", stored)
+	}
+
+	// The old number is released and no longer found
+	if mr.Exists(userPhoneKeyPrefix + "+1234567890") {
+		t.Error("Expected old phone key to be deleted")
+	}
+	if users, _, _ := getAll(repo, 1, 10, "+1234567890"); len(users) != 0 {
+		t.Errorf("Expected old number not to be found, got %d users", len(users))
+	}
+	if err := repo.Create(models.NewUser("+1234567890")); err != nil {
+		t.Errorf("Expected released number to be available, got %v", err)
+	}
+
+	other.PhoneNumber = "+1555000111"
+	if err := repo.Update(other); err != errors.ErrUserAlreadyExists {
+		t.Errorf("Expected USER_ALREADY_EXISTS, got %v", err)
+	}
+
+	missing := models.NewUser("+1111111111")
+	if err := repo.Update(missing); err != errors.ErrUserNotFound {
+		t.Errorf("Expected USER_NOT_FOUND, got %v", err)
+	}
+	if mr.Exists(userKeyPrefix + missing.ID) {
+		t.Error("Expected update of a missing user not to create it")
+	}
+}
+
+func TestRedisUserRepository_GetAll(t *testing.T) {
+	repo, _ := newTestRedisUserRepository(t)
+
+	registeredAt := time.Now().Add(-time.Hour)
+	phones := []string{"+5555555555", "+1111111111", "+4444444444", "+2222222222", "+3333333333"}
+	for _, phone := range phones {
+		user := models.NewUser(phone)
+		user.RegisteredAt = registeredAt
+		registeredAt = registeredAt.Add(time.Minute)
+		if err := repo.Create(user); err != nil {
+			t.Fatalf("Failed to create user: %v", err)
+		}
+	}
+
+	// Pages follow registration order
+	for page := 1; page <= 3; page++ {
+		users, total, err := getAll(repo, page, 2, "")
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if total != 5 {
+			t.Errorf("Expected total 5, got %d", total)
+		}
+		for i, user := range users {
+			if want := phones[(page-1)*2+i]; user.PhoneNumber != want {
+				t.Errorf("Expected %s on page %d, got %s", want, page, user.PhoneNumber)
+			}
+		}
+	}
+
+	users, total, err := getAll(repo, 1, 10, "+3333333333")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if total != 1 || len(users) != 1 || users[0].PhoneNumber != "+3333333333" {
+		t.Errorf("Expected 1 search result, got %d of %d", len(users), total)
+	}
+
+	// Search matches number prefixes
+	if _, total, _ := getAll(repo, 1, 10, "+333"); total != 1 {
+		t.Errorf("Expected 1 result for a number prefix, got %d", total)
+	}
+	if _, total, _ := getAll(repo, 1, 10, "+334"); total != 0 {
+		t.Errorf("Expected no results for an unknown prefix, got %d", total)
+	}
+
+	users, total, err = getAll(repo, 10, 10, "")
+	if err != nil || total != 5 || len(users) != 0 {
+		t.Errorf("Expected empty page of 5 users, got %d of %d (%v)", len(users), total, err)
+	}
+	if _, total, err = getAll(repo, 0, 0, ""); err != nil || total != 5 {
+		t.Errorf("Expected total 5 for invalid pagination, got %d (%v)", total, err)
+	}
+}
+
+func TestPhoneScore(t *testing.T) {
+	// Scores follow the lexicographic order of the numbers
+	phones := []string{"+1", "+10", "+100000000000000", "+12", "+120", "+1200", "+121", "+19", "+2", "+999999999999999"}
+	for i := 1; i < len(phones); i++ {
+		if phoneScore(phones[i-1]) >= phoneScore(phones[i]) {
+			t.Errorf("Expected %s to score below %s", phones[i-1], phones[i])
+		}
+	}
+
+	bounds, ok := phonePrefixBounds("+12")
+	if !ok {
+		t.Fatal("Expected bounds for +12")
+	}
+	for _, phone := range phones {
+		score := float64(phoneScore(phone))
+		inside := score >= bounds.min && score < bounds.max
+		if want := strings.HasPrefix(phone, "+12"); inside != want {
+			t.Errorf("%s: expected within +12 bounds %v, got %v", phone, want, inside)
+		}
+	}
+
+	if _, ok := phonePrefixBounds("+1234567890123456"); ok {
+		t.Error("Expected no bounds for a prefix longer than any number")
+	}
+}
+
+func TestRedisUserRepository_FilteredQueryCleanup(t *testing.T) {
+	repo, mr := newTestRedisUserRepository(t)
+	_, base := createSearchTestUsers(t, repo)
+
+	active := true
+	result, err := repo.GetAll(models.UserQuery{
+		Page: 1, Limit: 10, Search: "123",
+		Filter: models.UserFilter{IsActive: &active, RegisteredBefore: base.Add(3 * time.Hour)},
+	})
+	if err != nil || result.Total != 2 {
+		t.Fatalf("Expected 2 users, got %+v (%v)", result, err)
+	}
+
+	// The sorted set the page was read from is gone
+	for _, key := range mr.Keys() {
+		if strings.HasPrefix(key, usersQueryPrefix) {
+			t.Errorf("Expected no query keys, got %s", key)
+		}
+	}
+}
+
+func TestRedisUserRepository_FilterScansFewestMatches(t *testing.T) {
+	repo, _ := newTestRedisUserRepository(t)
+	users, base := createSearchTestUsers(t, repo)
+	ctx := context.Background()
+
+	everyone := indexRange{
+		index:  sortIndexName(models.UserSortRegisteredAt, nil),
+		bounds: timeBounds(base.Add(-time.Hour), time.Time{}),
+	}
+	firstLogin := indexRange{
+		index:  sortIndexName(models.UserSortLastLoginAt, nil),
+		bounds: timeBounds(base.Add(10*time.Hour), base.Add(11*time.Hour)),
+	}
+
+	tests := []struct {
+		name      string
+		ranges    []indexRange
+		substring *phoneSearch
+		want      []string
+	}{
+		{"narrow range", []indexRange{everyone, firstLogin}, nil, []string{users[0].ID}},
+		{"narrow search", []indexRange{everyone}, &phoneSearch{term: "9999", substring: true}, []string{users[4].ID}},
+		{"search checked per candidate", []indexRange{firstLogin}, &phoneSearch{term: "5", substring: true}, []string{users[0].ID}},
+		{"search rejects candidate", []indexRange{firstLogin}, &phoneSearch{term: "0", substring: true}, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, scanned, err := repo.storeMatches(ctx, sortIndexName(models.UserSortRegisteredAt, nil), tt.ranges, tt.substring)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			defer repo.client.Del(ctx, key)
+
+			// Only the one user of the narrowest restriction is checked
+			if scanned != 1 {
+				t.Errorf("Expected 1 candidate scanned, got %d", scanned)
+			}
+			ids, err := repo.client.ZRange(ctx, key, 0, -1).Result()
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if strings.Join(ids, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("Expected %v, got %v", tt.want, ids)
+			}
+		})
+	}
+}
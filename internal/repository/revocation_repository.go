@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"otp-auth-service/internal/errors"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	revokedTokenKeyPrefix  = "revoked_token:"
+	revokedBeforeKeyPrefix = "revoked_before:"
+)
+
+// RevocationRepository keeps track of access tokens that were revoked
+// before they expired. Entries only live as long as the tokens they cover.
+type RevocationRepository interface {
+	// RevokeToken denylists a single token by its jti
+	RevokeToken(tokenID string, expiresAt time.Time) error
+	// RevokeUserTokens revokes every token of the user issued at or before
+	// the given time. ttl must cover the lifetime of those tokens.
+	RevokeUserTokens(userID string, before time.Time, ttl time.Duration) error
+	IsRevoked(tokenID, userID string, issuedAt time.Time) (bool, error)
+}
+
+type RedisRevocationRepository struct {
+	client *redis.Client
+}
+
+func NewRevocationRepository(client *redis.Client) RevocationRepository {
+	return &RedisRevocationRepository{
+		client: client,
+	}
+}
+
+func (r *RedisRevocationRepository) RevokeToken(tokenID string, expiresAt time.Time) error {
+	ctx := context.Background()
+
+	ttl := time.Until(expiresAt)
+	if ttl <= 0 {
+		// Already expired, nothing to deny
+		return nil
+	}
+
+	if err := r.client.Set(ctx, revokedTokenKeyPrefix+tokenID, 1, ttl).Err(); err != nil {
+		return errors.ErrRedisError.WithDetails(err.Error())
+	}
+
+	return nil
+}
+
+func (r *RedisRevocationRepository) RevokeUserTokens(userID string, before time.Time, ttl time.Duration) error {
+	ctx := context.Background()
+
+	err := r.client.Set(ctx, revokedBeforeKeyPrefix+userID, before.Unix(), ttl).Err()
+	if err != nil {
+		return errors.ErrRedisError.WithDetails(err.Error())
+	}
+
+	return nil
+}
+
+func (r *RedisRevocationRepository) IsRevoked(tokenID, userID string, issuedAt time.Time) (bool, error) {
+	ctx := context.Background()
+
+	values, err := r.client.MGet(ctx, revokedTokenKeyPrefix+tokenID, revokedBeforeKeyPrefix+userID).Result()
+	if err != nil {
+		return false, errors.ErrRedisError.WithDetails(err.Error())
+	}
+
+	if tokenID != "" && values[0] != nil {
+		return true, nil
+	}
+
+	if cutoff, ok := values[1].(string); ok {
+		before, err := strconv.ParseInt(cutoff, 10, 64)
+		if err != nil {
+			return false, errors.ErrRedisError.WithDetails(err.Error())
+		}
+		// iat has second precision, so a token from the same second as the
+		// cutoff counts as revoked
+		if issuedAt.Unix() <= before {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
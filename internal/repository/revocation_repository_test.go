@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func newTestRevocationRepository(t *testing.T) (*RedisRevocationRepository, *miniredis.Miniredis) {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	return NewRevocationRepository(client).(*RedisRevocationRepository), mr
+}
+
+func TestRedisRevocationRepository_RevokeToken(t *testing.T) {
+	repo, mr := newTestRevocationRepository(t)
+	issuedAt := time.Now()
+
+	if err := repo.RevokeToken("jti-1", time.Now().Add(time.Minute)); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	revoked, err := repo.IsRevoked("jti-1", "user-1", issuedAt)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !revoked {
+		t.Error("Expected token to be revoked")
+	}
+
+	revoked, _ = repo.IsRevoked("jti-2", "user-1", issuedAt)
+	if revoked {
+		t.Error("Expected other token not to be revoked")
+	}
+
+	// The denylist entry lives as long as the token
+	if ttl := mr.TTL(revokedTokenKeyPrefix + "jti-1"); ttl <= 0 || ttl > time.Minute {
+		t.Errorf("Expected TTL within 1m, got %v", ttl)
+	}
+	mr.FastForward(time.Minute)
+	if mr.Exists(revokedTokenKeyPrefix + "jti-1") {
+		t.Error("Expected denylist entry to expire with the token")
+	}
+}
+
+func TestRedisRevocationRepository_RevokeExpiredToken(t *testing.T) {
+	repo, mr := newTestRevocationRepository(t)
+
+	if err := repo.RevokeToken("jti-1", time.Now().Add(-time.Minute)); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if mr.Exists(revokedTokenKeyPrefix + "jti-1") {
+		t.Error("Expected no entry for an expired token")
+	}
+}
+
+func TestRedisRevocationRepository_RevokeUserTokens(t *testing.T) {
+	repo, _ := newTestRevocationRepository(t)
+	now := time.Now()
+
+	if err := repo.RevokeUserTokens("user-1", now, time.Minute); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		userID   string
+		issuedAt time.Time
+		want     bool
+	}{
+		{name: "issued before", userID: "user-1", issuedAt: now.Add(-time.Minute), want: true},
+		{name: "issued same second", userID: "user-1", issuedAt: now, want: true},
+		{name: "issued after", userID: "user-1", issuedAt: now.Add(2 * time.Second), want: false},
+		{name: "other user", userID: "user-2", issuedAt: now.Add(-time.Minute), want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			revoked, err := repo.IsRevoked("jti", tt.userID, tt.issuedAt)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if revoked != tt.want {
+				t.Errorf("Expected revoked %v, got %v", tt.want, revoked)
+			}
+		})
+	}
+}
@@ -0,0 +1,197 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"otp-auth-service/internal/errors"
+	"otp-auth-service/internal/models"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	sessionKeyPrefix      = "session:"
+	userSessionsKeyPrefix = "user_sessions:"
+)
+
+// SessionRepository stores active sessions. A session lives until its
+// ExpiresAt, which is pushed back whenever its refresh token is rotated.
+type SessionRepository interface {
+	Create(session *models.Session) error
+	GetByID(id string) (*models.Session, error)
+	// Update overwrites an existing session; deleted sessions stay deleted
+	Update(session *models.Session) error
+	GetByUserID(userID string) ([]*models.Session, error)
+	Delete(session *models.Session) error
+	DeleteByUserID(userID string) error
+}
+
+type RedisSessionRepository struct {
+	client *redis.Client
+}
+
+func NewSessionRepository(client *redis.Client) SessionRepository {
+	return &RedisSessionRepository{
+		client: client,
+	}
+}
+
+func (r *RedisSessionRepository) Create(session *models.Session) error {
+	ctx := context.Background()
+
+	sessionJSON, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+
+	ttl := time.Until(session.ExpiresAt)
+	userKey := userSessionsKeyPrefix + session.UserID
+
+	pipe := r.client.TxPipeline()
+	pipe.Set(ctx, sessionKeyPrefix+session.ID, sessionJSON, ttl)
+	pipe.SAdd(ctx, userKey, session.ID)
+	// The user index must outlive the longest session
+	pipe.ExpireNX(ctx, userKey, ttl)
+	pipe.ExpireGT(ctx, userKey, ttl)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return errors.ErrRedisError.WithDetails(err.Error())
+	}
+
+	return nil
+}
+
+func (r *RedisSessionRepository) GetByID(id string) (*models.Session, error) {
+	ctx := context.Background()
+
+	sessionJSON, err := r.client.Get(ctx, sessionKeyPrefix+id).Result()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, errors.ErrSessionNotFound
+		}
+		return nil, errors.ErrRedisError.WithDetails(err.Error())
+	}
+
+	var session models.Session
+	if err := json.Unmarshal([]byte(sessionJSON), &session); err != nil {
+		return nil, err
+	}
+
+	return &session, nil
+}
+
+func (r *RedisSessionRepository) Update(session *models.Session) error {
+	ctx := context.Background()
+
+	sessionJSON, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+
+	ttl := time.Until(session.ExpiresAt)
+	userKey := userSessionsKeyPrefix + session.UserID
+
+	// SET XX so that a concurrent revocation is not undone
+	updated, err := r.client.SetXX(ctx, sessionKeyPrefix+session.ID, sessionJSON, ttl).Result()
+	if err != nil {
+		return errors.ErrRedisError.WithDetails(err.Error())
+	}
+	if !updated {
+		return errors.ErrSessionNotFound
+	}
+
+	if err := r.client.ExpireGT(ctx, userKey, ttl).Err(); err != nil {
+		return errors.ErrRedisError.WithDetails(err.Error())
+	}
+
+	return nil
+}
+
+// GetByUserID returns the user's sessions, most recently used first
+func (r *RedisSessionRepository) GetByUserID(userID string) ([]*models.Session, error) {
+	ctx := context.Background()
+
+	userKey := userSessionsKeyPrefix + userID
+	ids, err := r.client.SMembers(ctx, userKey).Result()
+	if err != nil {
+		return nil, errors.ErrRedisError.WithDetails(err.Error())
+	}
+	if len(ids) == 0 {
+		return []*models.Session{}, nil
+	}
+
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = sessionKeyPrefix + id
+	}
+
+	values, err := r.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, errors.ErrRedisError.WithDetails(err.Error())
+	}
+
+	sessions := make([]*models.Session, 0, len(values))
+	var expired []interface{}
+	for i, value := range values {
+		sessionJSON, ok := value.(string)
+		if !ok {
+			expired = append(expired, ids[i])
+			continue
+		}
+
+		var session models.Session
+		if err := json.Unmarshal([]byte(sessionJSON), &session); err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, &session)
+	}
+
+	// Drop index entries of sessions that expired on their own
+	if len(expired) > 0 {
+		if err := r.client.SRem(ctx, userKey, expired...).Err(); err != nil {
+			return nil, errors.ErrRedisError.WithDetails(err.Error())
+		}
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].LastSeenAt.After(sessions[j].LastSeenAt)
+	})
+
+	return sessions, nil
+}
+
+func (r *RedisSessionRepository) Delete(session *models.Session) error {
+	ctx := context.Background()
+
+	pipe := r.client.TxPipeline()
+	pipe.Del(ctx, sessionKeyPrefix+session.ID)
+	pipe.SRem(ctx, userSessionsKeyPrefix+session.UserID, session.ID)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return errors.ErrRedisError.WithDetails(err.Error())
+	}
+
+	return nil
+}
+
+func (r *RedisSessionRepository) DeleteByUserID(userID string) error {
+	ctx := context.Background()
+
+	userKey := userSessionsKeyPrefix + userID
+	ids, err := r.client.SMembers(ctx, userKey).Result()
+	if err != nil {
+		return errors.ErrRedisError.WithDetails(err.Error())
+	}
+
+	keys := []string{userKey}
+	for _, id := range ids {
+		keys = append(keys, sessionKeyPrefix+id)
+	}
+
+	if err := r.client.Del(ctx, keys...).Err(); err != nil {
+		return errors.ErrRedisError.WithDetails(err.Error())
+	}
+
+	return nil
+}
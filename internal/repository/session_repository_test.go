@@ -0,0 +1,155 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"otp-auth-service/internal/models"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func newTestSessionRepository(t *testing.T) (*RedisSessionRepository, *miniredis.Miniredis) {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	return NewSessionRepository(client).(*RedisSessionRepository), mr
+}
+
+func newTestSession(id, userID string, ttl time.Duration) *models.Session {
+	client := models.ClientInfo{DeviceName: "Pixel 8", IPAddress: "203.0.113.7", UserAgent: "app/1.0"}
+	return models.NewSession(id, userID, client, time.Now().Add(ttl))
+}
+
+func TestRedisSessionRepository_CreateAndGet(t *testing.T) {
+	repo, mr := newTestSessionRepository(t)
+
+	if err := repo.Create(newTestSession("session-1", "user-1", time.Hour)); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	session, err := repo.GetByID("session-1")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if session.UserID != "user-1" || session.DeviceName != "Pixel 8" || session.IPAddress != "203.0.113.7" {
+		t.Errorf("Unexpected session: %+v", session)
+	}
+	if ttl := mr.TTL(sessionKeyPrefix + "session-1"); ttl <= 0 || ttl > time.Hour {
+		t.Errorf("Expected TTL within 1h, got %v", ttl)
+	}
+
+	_, err = repo.GetByID("unknown")
+	assertErrorCode(t, err, "SESSION_NOT_FOUND")
+}
+
+func TestRedisSessionRepository_Update(t *testing.T) {
+	repo, mr := newTestSessionRepository(t)
+
+	session := newTestSession("session-1", "user-1", time.Minute)
+	if err := repo.Create(session); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	session.IPAddress = "198.51.100.1"
+	session.ExpiresAt = time.Now().Add(time.Hour)
+	if err := repo.Update(session); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	updated, _ := repo.GetByID("session-1")
+	if updated.IPAddress != "198.51.100.1" {
+		t.Errorf("Expected IP to be updated, got %s", updated.IPAddress)
+	}
+	if ttl := mr.TTL(sessionKeyPrefix + "session-1"); ttl <= time.Minute {
+		t.Errorf("Expected TTL to be extended, got %v", ttl)
+	}
+	if ttl := mr.TTL(userSessionsKeyPrefix + "user-1"); ttl <= time.Minute {
+		t.Errorf("Expected user index TTL to be extended, got %v", ttl)
+	}
+
+	// A deleted session is not brought back
+	if err := repo.Delete(session); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	assertErrorCode(t, repo.Update(session), "SESSION_NOT_FOUND")
+	if mr.Exists(sessionKeyPrefix + "session-1") {
+		t.Error("Expected deleted session to stay deleted")
+	}
+}
+
+func TestRedisSessionRepository_GetByUserID(t *testing.T) {
+	repo, mr := newTestSessionRepository(t)
+
+	older := newTestSession("session-1", "user-1", time.Hour)
+	older.LastSeenAt = time.Now().Add(-time.Hour)
+	sessions := []*models.Session{
+		older,
+		newTestSession("session-2", "user-1", time.Hour),
+		newTestSession("session-3", "user-1", time.Minute),
+		newTestSession("session-4", "user-2", time.Hour),
+	}
+	for _, session := range sessions {
+		if err := repo.Create(session); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+
+	// session-3 expires on its own
+	mr.FastForward(2 * time.Minute)
+
+	list, err := repo.GetByUserID("user-1")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(list) != 2 {
+		t.Fatalf("Expected 2 sessions, got %d", len(list))
+	}
+	if list[0].ID != "session-2" || list[1].ID != "session-1" {
+		t.Errorf("Expected most recently seen first, got %s, %s", list[0].ID, list[1].ID)
+	}
+
+	// The expired session is dropped from the index
+	members, _ := mr.Members(userSessionsKeyPrefix + "user-1")
+	if len(members) != 2 {
+		t.Errorf("Expected expired session to be removed from index, got %v", members)
+	}
+
+	empty, err := repo.GetByUserID("user-3")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(empty) != 0 {
+		t.Errorf("Expected no sessions, got %d", len(empty))
+	}
+}
+
+func TestRedisSessionRepository_DeleteByUserID(t *testing.T) {
+	repo, _ := newTestSessionRepository(t)
+
+	for _, session := range []*models.Session{
+		newTestSession("session-1", "user-1", time.Hour),
+		newTestSession("session-2", "user-1", time.Hour),
+		newTestSession("session-3", "user-2", time.Hour),
+	} {
+		if err := repo.Create(session); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+
+	if err := repo.DeleteByUserID("user-1"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	for _, id := range []string{"session-1", "session-2"} {
+		_, err := repo.GetByID(id)
+		assertErrorCode(t, err, "SESSION_NOT_FOUND")
+	}
+	if _, err := repo.GetByID("session-3"); err != nil {
+		t.Errorf("Expected other user's session to survive, got %v", err)
+	}
+}
@@ -0,0 +1,302 @@
+package repository
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+
+	"otp-auth-service/internal/errors"
+	"otp-auth-service/internal/models"
+)
+
+const userColumns = "id, phone_number, registered_at, last_login_at, is_active, name, locale"
+
+// SQLUserRepository stores users in PostgreSQL or SQLite. Phone numbers
+// are kept unique by an index, so concurrent registrations of the same
+// number cannot both succeed.
+type SQLUserRepository struct {
+	db      *sql.DB
+	dialect string
+}
+
+// NewSQLUserRepository migrates the schema of db and returns a repository
+// using it. dialect is DialectPostgres or DialectSQLite.
+func NewSQLUserRepository(db *sql.DB, dialect string) (UserRepository, error) {
+	if err := Migrate(db, dialect); err != nil {
+		return nil, err
+	}
+
+	return &SQLUserRepository{
+		db:      db,
+		dialect: dialect,
+	}, nil
+}
+
+func (r *SQLUserRepository) Create(user *models.User) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return errors.ErrDatabaseError.WithDetails(err.Error())
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(r.rebind(`INSERT INTO users (`+userColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (phone_number) DO NOTHING`),
+		user.ID, user.PhoneNumber, user.RegisteredAt.UTC(), user.LastLoginAt.UTC(), user.IsActive, user.Name, user.Locale)
+	if err != nil {
+		return errors.ErrDatabaseError.WithDetails(err.Error())
+	}
+
+	inserted, err := result.RowsAffected()
+	if err != nil {
+		return errors.ErrDatabaseError.WithDetails(err.Error())
+	}
+	if inserted == 0 {
+		return errors.ErrUserAlreadyExists
+	}
+
+	if err := r.insertPhoneSuffixes(tx, user); err != nil {
+		return errors.ErrDatabaseError.WithDetails(err.Error())
+	}
+	if err := tx.Commit(); err != nil {
+		return errors.ErrDatabaseError.WithDetails(err.Error())
+	}
+
+	return nil
+}
+
+func (r *SQLUserRepository) GetByPhoneNumber(phoneNumber string) (*models.User, error) {
+	return r.getUser("phone_number", phoneNumber)
+}
+
+func (r *SQLUserRepository) GetByID(id string) (*models.User, error) {
+	return r.getUser("id", id)
+}
+
+func (r *SQLUserRepository) getUser(column, value string) (*models.User, error) {
+	row := r.db.QueryRow(r.rebind("SELECT "+userColumns+" FROM users WHERE "+column+" = ?"), value)
+
+	user, err := scanUser(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.ErrUserNotFound
+		}
+		return nil, errors.ErrDatabaseError.WithDetails(err.Error())
+	}
+
+	return user, nil
+}
+
+func (r *SQLUserRepository) Update(user *models.User) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return errors.ErrDatabaseError.WithDetails(err.Error())
+	}
+	defer tx.Rollback()
+
+	var oldPhone string
+	if err := tx.QueryRow(r.rebind("SELECT phone_number FROM users WHERE id = ?"), user.ID).Scan(&oldPhone); err != nil {
+		if err == sql.ErrNoRows {
+			return errors.ErrUserNotFound
+		}
+		return errors.ErrDatabaseError.WithDetails(err.Error())
+	}
+
+	if _, err := tx.Exec(r.rebind(`UPDATE users
+		SET phone_number = ?, registered_at = ?, last_login_at = ?, is_active = ?, name = ?, locale = ?
+		WHERE id = ?`),
+		user.PhoneNumber, user.RegisteredAt.UTC(), user.LastLoginAt.UTC(), user.IsActive, user.Name, user.Locale, user.ID); err != nil {
+		// Constraint errors differ between drivers; a changed phone number
+		// that belongs to someone else is the one we expect. The failed
+		// transaction is ended first, as SQLite runs on a single connection.
+		tx.Rollback()
+		if existing, getErr := r.GetByPhoneNumber(user.PhoneNumber); getErr == nil && existing.ID != user.ID {
+			return errors.ErrUserAlreadyExists
+		}
+		return errors.ErrDatabaseError.WithDetails(err.Error())
+	}
+
+	if oldPhone != user.PhoneNumber {
+		if _, err := tx.Exec(r.rebind("DELETE FROM user_phone_suffixes WHERE user_id = ?"), user.ID); err != nil {
+			return errors.ErrDatabaseError.WithDetails(err.Error())
+		}
+		if err := r.insertPhoneSuffixes(tx, user); err != nil {
+			return errors.ErrDatabaseError.WithDetails(err.Error())
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return errors.ErrDatabaseError.WithDetails(err.Error())
+	}
+
+	return nil
+}
+
+// insertPhoneSuffixes indexes the phone number of user for substring search
+func (r *SQLUserRepository) insertPhoneSuffixes(tx *sql.Tx, user *models.User) error {
+	for _, suffix := range phoneSuffixes(user.PhoneNumber) {
+		if _, err := tx.Exec(r.rebind("INSERT INTO user_phone_suffixes (user_id, suffix) VALUES (?, ?)"), user.ID, suffix); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sqlSortColumns are the columns of the sort fields. Each is indexed
+// together with id.
+var sqlSortColumns = map[string]string{
+	models.UserSortRegisteredAt: "registered_at",
+	models.UserSortLastLoginAt:  "last_login_at",
+	models.UserSortPhoneNumber:  "phone_number",
+}
+
+func (r *SQLUserRepository) GetAll(query models.UserQuery) (*models.UserPage, error) {
+	page := &models.UserPage{Users: []*models.User{}}
+
+	conditions, args, ok := r.filterConditions(query)
+	if !ok {
+		return page, nil
+	}
+	if err := r.db.QueryRow(r.rebind("SELECT COUNT(*) FROM users"+where(conditions)), args...).Scan(&page.Total); err != nil {
+		return nil, errors.ErrDatabaseError.WithDetails(err.Error())
+	}
+	if query.Limit < 1 || (query.Cursor == nil && query.Page < 1) {
+		return page, nil
+	}
+
+	column, ok := sqlSortColumns[query.Sort]
+	if !ok {
+		column = sqlSortColumns[models.UserSortRegisteredAt]
+	}
+	direction, comparison := "ASC", ">"
+	if query.Desc {
+		direction, comparison = "DESC", "<"
+	}
+
+	offset := 0
+	if query.Cursor != nil {
+		// Row values compare column by column, like the ORDER BY
+		conditions = append(conditions, "("+column+", id) "+comparison+" (?, ?)")
+		args = append(args, r.cursorValue(query.Cursor), query.Cursor.ID)
+	} else {
+		offset = (query.Page - 1) * query.Limit
+	}
+
+	// One user more than asked for tells whether another page follows
+	rows, err := r.db.Query(r.rebind("SELECT "+userColumns+" FROM users"+where(conditions)+
+		" ORDER BY "+column+" "+direction+", id "+direction+" LIMIT ? OFFSET ?"),
+		append(args, query.Limit+1, offset)...)
+	if err != nil {
+		return nil, errors.ErrDatabaseError.WithDetails(err.Error())
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, errors.ErrDatabaseError.WithDetails(err.Error())
+		}
+		page.Users = append(page.Users, user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.ErrDatabaseError.WithDetails(err.Error())
+	}
+
+	if len(page.Users) > query.Limit {
+		page.Users = page.Users[:query.Limit]
+		page.HasMore = true
+	}
+
+	return page, nil
+}
+
+// filterConditions returns the conditions selecting the users that match
+// the search and filter of query. It reports false if no user can match.
+func (r *SQLUserRepository) filterConditions(query models.UserQuery) ([]string, []interface{}, bool) {
+	var conditions []string
+	var args []interface{}
+
+	if query.Search != "" {
+		search, ok := parsePhoneSearch(query.Search)
+		if !ok {
+			return nil, nil, false
+		}
+		if search.substring {
+			condition, conditionArgs := r.prefixCondition("suffix", search.term)
+			conditions = append(conditions, "id IN (SELECT user_id FROM user_phone_suffixes WHERE "+condition+")")
+			args = append(args, conditionArgs...)
+		} else {
+			condition, conditionArgs := r.prefixCondition("phone_number", search.term)
+			conditions = append(conditions, condition)
+			args = append(args, conditionArgs...)
+		}
+	}
+
+	filter := query.Filter
+	if filter.IsActive != nil {
+		conditions = append(conditions, "is_active = ?")
+		args = append(args, *filter.IsActive)
+	}
+	for _, bound := range []struct {
+		condition string
+		value     time.Time
+	}{
+		{"registered_at >= ?", filter.RegisteredAfter},
+		{"registered_at < ?", filter.RegisteredBefore},
+		{"last_login_at >= ?", filter.LastLoginAfter},
+		{"last_login_at < ?", filter.LastLoginBefore},
+	} {
+		if !bound.value.IsZero() {
+			conditions = append(conditions, bound.condition)
+			args = append(args, bound.value.UTC())
+		}
+	}
+
+	return conditions, args, true
+}
+
+// prefixCondition matches values of column starting with prefix in a way
+// the dialect answers from an index
+func (r *SQLUserRepository) prefixCondition(column, prefix string) (string, []interface{}) {
+	if r.dialect == DialectPostgres {
+		// Served by the text_pattern_ops indexes; prefixes hold no wildcards
+		return column + " LIKE ?", []interface{}{prefix + "%"}
+	}
+
+	// SQLite only uses indexes for case-sensitive LIKE, while a range on
+	// the BINARY collation does the same
+	min, max := prefixRange(prefix)
+	return column + " >= ? AND " + column + " < ?", []interface{}{min, max}
+}
+
+// cursorValue returns the sort value of cursor as the column stores it
+func (r *SQLUserRepository) cursorValue(cursor *models.UserCursor) interface{} {
+	if cursor.Sort == models.UserSortPhoneNumber {
+		return cursor.Value
+	}
+	t, _ := cursor.Time()
+	return t.UTC()
+}
+
+func (r *SQLUserRepository) rebind(query string) string {
+	return rebind(r.dialect, query)
+}
+
+func where(conditions []string) string {
+	if len(conditions) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(conditions, " AND ")
+}
+
+// rowScanner is a *sql.Row or *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanUser reads a row of userColumns
+func scanUser(row rowScanner) (*models.User, error) {
+	var user models.User
+	if err := row.Scan(&user.ID, &user.PhoneNumber, &user.RegisteredAt, &user.LastLoginAt, &user.IsActive, &user.Name, &user.Locale); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
@@ -0,0 +1,214 @@
+package repository
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"otp-auth-service/internal/errors"
+	"otp-auth-service/internal/models"
+
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// forEachSQLDialect runs test against SQLite and, when TEST_DATABASE_URL
+// names a scratch PostgreSQL database, against PostgreSQL
+func forEachSQLDialect(t *testing.T, test func(t *testing.T, db *sql.DB, dialect string)) {
+	t.Run("sqlite", func(t *testing.T) {
+		test(t, openTestSQLite(t, filepath.Join(t.TempDir(), "users.db")), DialectSQLite)
+	})
+
+	t.Run("postgres", func(t *testing.T) {
+		url := os.Getenv("TEST_DATABASE_URL")
+		if url == "" {
+			t.Skip("TEST_DATABASE_URL is not set")
+		}
+		db, err := sql.Open(DialectPostgres, url)
+		if err != nil {
+			t.Fatalf("Failed to open database: %v", err)
+		}
+		t.Cleanup(func() { db.Close() })
+		if _, err := db.Exec("DROP TABLE IF EXISTS user_phone_suffixes, users, schema_migrations"); err != nil {
+			t.Fatalf("Failed to reset database: %v", err)
+		}
+		test(t, db, DialectPostgres)
+	})
+}
+
+func openTestSQLite(t *testing.T, path string) *sql.DB {
+	t.Helper()
+
+	db, err := sql.Open(DialectSQLite, path)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func newTestSQLUserRepository(t *testing.T, db *sql.DB, dialect string) *SQLUserRepository {
+	t.Helper()
+
+	repo, err := NewSQLUserRepository(db, dialect)
+	if err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+	return repo.(*SQLUserRepository)
+}
+
+func TestSQLUserRepository_CRUD(t *testing.T) {
+	forEachSQLDialect(t, func(t *testing.T, db *sql.DB, dialect string) {
+		repo := newTestSQLUserRepository(t, db, dialect)
+
+		user := models.NewUser("+1234567890")
+		if err := repo.Create(user); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if err := repo.Create(models.NewUser("+1234567890")); err != errors.ErrUserAlreadyExists {
+			t.Errorf("Expected USER_ALREADY_EXISTS, got %v", err)
+		}
+
+		stored, err := repo.GetByPhoneNumber("+1234567890")
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if stored.ID != user.ID || !stored.IsActive || !stored.RegisteredAt.Equal(user.RegisteredAt) {
+			t.Errorf("Expected %+v, got %+v", user, stored)
+		}
+		if _, err := repo.GetByID("non-existent-id"); err != errors.ErrUserNotFound {
+			t.Errorf("Expected USER_NOT_FOUND, got %v", err)
+		}
+
+		user.LastLoginAt = user.LastLoginAt.Add(time.Hour)
+		user.IsActive = false
+		if err := repo.Update(user); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		stored, _ = repo.GetByID(user.ID)
+		if stored.IsActive || !stored.LastLoginAt.Equal(user.LastLoginAt) {
+			t.Errorf("Expected updated user, got %+v", stored)
+		}
+
+		// Phone numbers stay unique on update
+		other := models.NewUser("+1987654321")
+		if err := repo.Create(other); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		other.PhoneNumber = "+1234567890"
+		if err := repo.Update(other); err != errors.ErrUserAlreadyExists {
+			t.Errorf("Expected USER_ALREADY_EXISTS, got %v", err)
+		}
+
+		missing := models.NewUser("+1111111111")
+		if err := repo.Update(missing); err != errors.ErrUserNotFound {
+			t.Errorf("Expected USER_NOT_FOUND, got %v", err)
+		}
+	})
+}
+
+func TestSQLUserRepository_GetAll(t *testing.T) {
+	forEachSQLDialect(t, func(t *testing.T, db *sql.DB, dialect string) {
+		repo := newTestSQLUserRepository(t, db, dialect)
+
+		registeredAt := time.Now().Add(-time.Hour)
+		var phones []string
+		for _, phone := range []string{"+1111111111", "+2222222222", "+3333333333", "+4444444444", "+5555555555"} {
+			user := models.NewUser(phone)
+			user.RegisteredAt = registeredAt
+			registeredAt = registeredAt.Add(time.Minute)
+			if err := repo.Create(user); err != nil {
+				t.Fatalf("Failed to create user: %v", err)
+			}
+			phones = append(phones, phone)
+		}
+
+		// Pages follow registration order
+		for page := 1; page <= 3; page++ {
+			users, total, err := getAll(repo, page, 2, "")
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if total != 5 {
+				t.Errorf("Expected total 5, got %d", total)
+			}
+			for i, user := range users {
+				if want := phones[(page-1)*2+i]; user.PhoneNumber != want {
+					t.Errorf("Expected %s on page %d, got %s", want, page, user.PhoneNumber)
+				}
+			}
+		}
+
+		users, total, err := getAll(repo, 1, 10, "+3333333333")
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if total != 1 || len(users) != 1 || users[0].PhoneNumber != "+3333333333" {
+			t.Errorf("Expected 1 search result, got %d of %d", len(users), total)
+		}
+
+		users, total, err = getAll(repo, 10, 10, "")
+		if err != nil || total != 5 || len(users) != 0 {
+			t.Errorf("Expected empty page of 5 users, got %d of %d (%v)", len(users), total, err)
+		}
+		if _, total, err = getAll(repo, 0, 0, ""); err != nil || total != 5 {
+			t.Errorf("Expected total 5 for invalid pagination, got %d (%v)", total, err)
+		}
+	})
+}
+
+func TestSQLUserRepository_Persists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "users.db")
+
+	repo := newTestSQLUserRepository(t, openTestSQLite(t, path), DialectSQLite)
+	user := models.NewUser("+1234567890")
+	if err := repo.Create(user); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	repo.db.Close()
+
+	// Migrating again on restart is a no-op and keeps the users
+	repo = newTestSQLUserRepository(t, openTestSQLite(t, path), DialectSQLite)
+	if _, err := repo.GetByID(user.ID); err != nil {
+		t.Errorf("Expected user to survive a restart, got %v", err)
+	}
+
+	var versions int
+	if err := repo.db.QueryRow("SELECT COUNT(*) FROM schema_migrations").Scan(&versions); err != nil {
+		t.Fatalf("Failed to read schema_migrations: %v", err)
+	}
+	if migrations, _ := loadMigrations(DialectSQLite); versions != len(migrations) {
+		t.Errorf("Expected %d applied migrations, got %d", len(migrations), versions)
+	}
+}
+
+func TestLoadMigrations(t *testing.T) {
+	for _, dialect := range []string{DialectPostgres, DialectSQLite} {
+		migrations, err := loadMigrations(dialect)
+		if err != nil {
+			t.Fatalf("Expected no error for %s, got %v", dialect, err)
+		}
+		for i, m := range migrations {
+			if m.version != i+1 {
+				t.Errorf("Expected %s migration %d to have version %d, got %d", dialect, i, i+1, m.version)
+			}
+		}
+	}
+
+	if _, err := loadMigrations("mysql"); err == nil {
+		t.Error("Expected error for unsupported dialect")
+	}
+}
+
+func TestRebind(t *testing.T) {
+	query := "SELECT id FROM users WHERE phone_number = ? LIMIT ? OFFSET ?"
+	if got := rebind(DialectPostgres, query); got != "SELECT id FROM users WHERE phone_number = $1 LIMIT $2 OFFSET $3" {
+		t.Errorf("Unexpected PostgreSQL query: %s", got)
+	}
+	if got := rebind(DialectSQLite, query); got != query {
+		t.Errorf("Expected SQLite query to be unchanged, got %s", got)
+	}
+}
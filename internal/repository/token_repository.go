@@ -0,0 +1,204 @@
+package repository
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"otp-auth-service/internal/errors"
+	"otp-auth-service/internal/models"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	refreshTokenKeyPrefix  = "refresh_token:"
+	refreshFamilyKeyPrefix = "refresh_family:"
+	refreshUserKeyPrefix   = "refresh_user:"
+)
+
+// Result codes returned by rotateRefreshTokenScript
+const (
+	rotateOK     = 1
+	rotateReused = -2
+)
+
+// TokenRepository stores refresh tokens. Tokens are only kept as SHA-256
+// hashes; the raw value is known to the client alone.
+type TokenRepository interface {
+	CreateRefreshToken(token string, data *models.RefreshToken) error
+	GetRefreshToken(token string) (*models.RefreshToken, error)
+	RotateRefreshToken(oldToken, newToken string, data *models.RefreshToken) error
+	RevokeFamily(familyID string) error
+	RevokeUserFamilies(userID string) error
+}
+
+type RedisTokenRepository struct {
+	client *redis.Client
+}
+
+func NewTokenRepository(client *redis.Client) TokenRepository {
+	return &RedisTokenRepository{
+		client: client,
+	}
+}
+
+// rotateRefreshTokenScript marks the old token as rotated and stores its
+// successor in one step. Presenting a token that was already rotated means
+// it leaked, so the whole family is revoked.
+//
+// KEYS[1] old token key, KEYS[2] new token key, KEYS[3] family key, KEYS[4] user key
+// ARGV[1] new token JSON, ARGV[2] TTL (ms), ARGV[3] token key prefix, ARGV[4] new token hash
+var rotateRefreshTokenScript = redis.NewScript(`
+local raw = redis.call('GET', KEYS[1])
+if not raw then
+	return -1
+end
+
+local token = cjson.decode(raw)
+if token.rotated then
+	for _, hash in ipairs(redis.call('SMEMBERS', KEYS[3])) do
+		redis.call('DEL', ARGV[3] .. hash)
+	end
+	redis.call('DEL', KEYS[3])
+	return -2
+end
+
+token.rotated = true
+redis.call('SET', KEYS[1], cjson.encode(token), 'KEEPTTL')
+redis.call('SET', KEYS[2], ARGV[1], 'PX', ARGV[2])
+redis.call('SADD', KEYS[3], ARGV[4])
+redis.call('PEXPIRE', KEYS[3], ARGV[2])
+redis.call('PEXPIRE', KEYS[4], ARGV[2])
+return 1
+`)
+
+func (r *RedisTokenRepository) CreateRefreshToken(token string, data *models.RefreshToken) error {
+	ctx := context.Background()
+
+	tokenJSON, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	ttl := time.Until(data.ExpiresAt)
+	hash := hashToken(token)
+	familyKey := refreshFamilyKeyPrefix + data.FamilyID
+	userKey := refreshUserKeyPrefix + data.UserID
+
+	pipe := r.client.TxPipeline()
+	pipe.Set(ctx, refreshTokenKeyPrefix+hash, tokenJSON, ttl)
+	pipe.SAdd(ctx, familyKey, hash)
+	pipe.Expire(ctx, familyKey, ttl)
+	pipe.SAdd(ctx, userKey, data.FamilyID)
+	pipe.Expire(ctx, userKey, ttl)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return errors.ErrRedisError.WithDetails(err.Error())
+	}
+
+	return nil
+}
+
+func (r *RedisTokenRepository) GetRefreshToken(token string) (*models.RefreshToken, error) {
+	ctx := context.Background()
+
+	tokenJSON, err := r.client.Get(ctx, refreshTokenKeyPrefix+hashToken(token)).Result()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, errors.ErrInvalidRefreshToken
+		}
+		return nil, errors.ErrRedisError.WithDetails(err.Error())
+	}
+
+	var data models.RefreshToken
+	if err := json.Unmarshal([]byte(tokenJSON), &data); err != nil {
+		return nil, err
+	}
+
+	return &data, nil
+}
+
+// RotateRefreshToken replaces oldToken with newToken within data's family
+func (r *RedisTokenRepository) RotateRefreshToken(oldToken, newToken string, data *models.RefreshToken) error {
+	ctx := context.Background()
+
+	tokenJSON, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	newHash := hashToken(newToken)
+	result, err := rotateRefreshTokenScript.Run(ctx, r.client,
+		[]string{
+			refreshTokenKeyPrefix + hashToken(oldToken),
+			refreshTokenKeyPrefix + newHash,
+			refreshFamilyKeyPrefix + data.FamilyID,
+			refreshUserKeyPrefix + data.UserID,
+		},
+		tokenJSON, time.Until(data.ExpiresAt).Milliseconds(), refreshTokenKeyPrefix, newHash,
+	).Int()
+	if err != nil {
+		return errors.ErrRedisError.WithDetails(err.Error())
+	}
+
+	switch result {
+	case rotateOK:
+		return nil
+	case rotateReused:
+		return errors.ErrRefreshTokenReused
+	default:
+		return errors.ErrInvalidRefreshToken
+	}
+}
+
+// RevokeFamily deletes every refresh token issued from the same login
+func (r *RedisTokenRepository) RevokeFamily(familyID string) error {
+	ctx := context.Background()
+
+	familyKey := refreshFamilyKeyPrefix + familyID
+	hashes, err := r.client.SMembers(ctx, familyKey).Result()
+	if err != nil {
+		return errors.ErrRedisError.WithDetails(err.Error())
+	}
+
+	keys := []string{familyKey}
+	for _, hash := range hashes {
+		keys = append(keys, refreshTokenKeyPrefix+hash)
+	}
+
+	if err := r.client.Del(ctx, keys...).Err(); err != nil {
+		return errors.ErrRedisError.WithDetails(err.Error())
+	}
+
+	return nil
+}
+
+// RevokeUserFamilies deletes every refresh token of the user
+func (r *RedisTokenRepository) RevokeUserFamilies(userID string) error {
+	ctx := context.Background()
+
+	userKey := refreshUserKeyPrefix + userID
+	familyIDs, err := r.client.SMembers(ctx, userKey).Result()
+	if err != nil {
+		return errors.ErrRedisError.WithDetails(err.Error())
+	}
+
+	for _, familyID := range familyIDs {
+		if err := r.RevokeFamily(familyID); err != nil {
+			return err
+		}
+	}
+
+	if err := r.client.Del(ctx, userKey).Err(); err != nil {
+		return errors.ErrRedisError.WithDetails(err.Error())
+	}
+
+	return nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,173 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"otp-auth-service/internal/models"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func newTestTokenRepository(t *testing.T) (*RedisTokenRepository, *miniredis.Miniredis) {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	return NewTokenRepository(client).(*RedisTokenRepository), mr
+}
+
+func newTestRefreshToken(familyID string) *models.RefreshToken {
+	now := time.Now()
+	return &models.RefreshToken{
+		UserID:      "user-1",
+		PhoneNumber: "+1234567890",
+		FamilyID:    familyID,
+		IssuedAt:    now,
+		ExpiresAt:   now.Add(time.Hour),
+	}
+}
+
+func TestRedisTokenRepository_CreateAndGet(t *testing.T) {
+	repo, mr := newTestTokenRepository(t)
+
+	if err := repo.CreateRefreshToken("token-1", newTestRefreshToken("family-1")); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// Only the hash of the token is used as key
+	if mr.Exists(refreshTokenKeyPrefix + "token-1") {
+		t.Error("Expected raw token not to be stored")
+	}
+	if ttl := mr.TTL(refreshTokenKeyPrefix + hashToken("token-1")); ttl <= 0 || ttl > time.Hour {
+		t.Errorf("Expected TTL within 1h, got %v", ttl)
+	}
+
+	data, err := repo.GetRefreshToken("token-1")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if data.UserID != "user-1" || data.FamilyID != "family-1" {
+		t.Errorf("Unexpected token data: %+v", data)
+	}
+	if data.Rotated {
+		t.Error("Expected new token not to be rotated")
+	}
+
+	_, err = repo.GetRefreshToken("unknown")
+	assertErrorCode(t, err, "INVALID_REFRESH_TOKEN")
+}
+
+func TestRedisTokenRepository_Rotate(t *testing.T) {
+	repo, _ := newTestTokenRepository(t)
+
+	if err := repo.CreateRefreshToken("token-1", newTestRefreshToken("family-1")); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := repo.RotateRefreshToken("token-1", "token-2", newTestRefreshToken("family-1")); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	old, err := repo.GetRefreshToken("token-1")
+	if err != nil {
+		t.Fatalf("Expected rotated token to be kept, got %v", err)
+	}
+	if !old.Rotated {
+		t.Error("Expected old token to be marked as rotated")
+	}
+
+	current, err := repo.GetRefreshToken("token-2")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if current.Rotated {
+		t.Error("Expected new token not to be rotated")
+	}
+
+	err = repo.RotateRefreshToken("unknown", "token-3", newTestRefreshToken("family-1"))
+	assertErrorCode(t, err, "INVALID_REFRESH_TOKEN")
+}
+
+func TestRedisTokenRepository_ReuseRevokesFamily(t *testing.T) {
+	repo, mr := newTestTokenRepository(t)
+
+	if err := repo.CreateRefreshToken("token-1", newTestRefreshToken("family-1")); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := repo.CreateRefreshToken("other", newTestRefreshToken("family-2")); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := repo.RotateRefreshToken("token-1", "token-2", newTestRefreshToken("family-1")); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// Rotating the same token twice is a reuse
+	err := repo.RotateRefreshToken("token-1", "token-3", newTestRefreshToken("family-1"))
+	assertErrorCode(t, err, "REFRESH_TOKEN_REUSED")
+
+	for _, token := range []string{"token-1", "token-2", "token-3"} {
+		_, err := repo.GetRefreshToken(token)
+		assertErrorCode(t, err, "INVALID_REFRESH_TOKEN")
+	}
+	if mr.Exists(refreshFamilyKeyPrefix + "family-1") {
+		t.Error("Expected family to be deleted")
+	}
+
+	// Other families are untouched
+	if _, err := repo.GetRefreshToken("other"); err != nil {
+		t.Errorf("Expected other family to survive, got %v", err)
+	}
+}
+
+func TestRedisTokenRepository_RevokeFamily(t *testing.T) {
+	repo, _ := newTestTokenRepository(t)
+
+	if err := repo.CreateRefreshToken("token-1", newTestRefreshToken("family-1")); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := repo.RotateRefreshToken("token-1", "token-2", newTestRefreshToken("family-1")); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if err := repo.RevokeFamily("family-1"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	for _, token := range []string{"token-1", "token-2"} {
+		_, err := repo.GetRefreshToken(token)
+		assertErrorCode(t, err, "INVALID_REFRESH_TOKEN")
+	}
+}
+
+func TestRedisTokenRepository_RevokeUserFamilies(t *testing.T) {
+	repo, mr := newTestTokenRepository(t)
+
+	for token, family := range map[string]string{"token-1": "family-1", "token-2": "family-2"} {
+		if err := repo.CreateRefreshToken(token, newTestRefreshToken(family)); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+	other := newTestRefreshToken("family-3")
+	other.UserID = "user-2"
+	if err := repo.CreateRefreshToken("other", other); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if err := repo.RevokeUserFamilies("user-1"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	for _, token := range []string{"token-1", "token-2"} {
+		_, err := repo.GetRefreshToken(token)
+		assertErrorCode(t, err, "INVALID_REFRESH_TOKEN")
+	}
+	if mr.Exists(refreshUserKeyPrefix + "user-1") {
+		t.Error("Expected user index to be deleted")
+	}
+	if _, err := repo.GetRefreshToken("other"); err != nil {
+		t.Errorf("Expected other user's token to survive, got %v", err)
+	}
+}
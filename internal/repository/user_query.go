@@ -0,0 +1,160 @@
+package repository
+
+import (
+	"sort"
+	"strings"
+
+	"otp-auth-service/internal/models"
+)
+
+// compareUsers orders users by a sort field and then by ID, the order every
+// repository lists users in
+func compareUsers(a, b *models.User, field string) int {
+	var c int
+	switch field {
+	case models.UserSortPhoneNumber:
+		c = strings.Compare(a.PhoneNumber, b.PhoneNumber)
+	case models.UserSortLastLoginAt:
+		c = a.LastLoginAt.Compare(b.LastLoginAt)
+	default:
+		c = a.RegisteredAt.Compare(b.RegisteredAt)
+	}
+	if c != 0 {
+		return c
+	}
+	return strings.Compare(a.ID, b.ID)
+}
+
+// sortIndexNames name the sort indexes of each sort field. The Redis user
+// scripts get the indexes in this order.
+var sortIndexNames = []struct{ field, name string }{
+	{models.UserSortRegisteredAt, "by_registered_at"},
+	{models.UserSortLastLoginAt, "by_last_login_at"},
+	{models.UserSortPhoneNumber, "by_phone_number"},
+}
+
+// sortIndexName returns the key of the sort index of field that holds
+// the users of a status, or every user if isActive is nil
+func sortIndexName(field string, isActive *bool) string {
+	name := sortIndexNames[0].name
+	for _, index := range sortIndexNames {
+		if index.field == field {
+			name = index.name
+		}
+	}
+
+	switch {
+	case isActive == nil:
+		return "users:" + name
+	case *isActive:
+		return "users:active:" + name
+	default:
+		return "users:inactive:" + name
+	}
+}
+
+// cursorUser is a stand-in user at the position of cursor
+func cursorUser(cursor *models.UserCursor) *models.User {
+	user := &models.User{ID: cursor.ID, PhoneNumber: cursor.Value}
+	if t, err := cursor.Time(); err == nil {
+		user.RegisteredAt, user.LastLoginAt = t, t
+	}
+	return user
+}
+
+// pageUsers sorts users for query and cuts out its page, for repositories
+// that have every matching user at hand
+func pageUsers(users []*models.User, query models.UserQuery) *models.UserPage {
+	direction := 1
+	if query.Desc {
+		direction = -1
+	}
+	sort.Slice(users, func(i, j int) bool {
+		return direction*compareUsers(users[i], users[j], query.Sort) < 0
+	})
+
+	page := &models.UserPage{Users: []*models.User{}, Total: len(users)}
+	if query.Limit < 1 {
+		return page
+	}
+
+	var start int
+	if query.Cursor != nil {
+		after := cursorUser(query.Cursor)
+		start = sort.Search(len(users), func(i int) bool {
+			return direction*compareUsers(users[i], after, query.Sort) > 0
+		})
+	} else {
+		if query.Page < 1 {
+			return page
+		}
+		start = (query.Page - 1) * query.Limit
+	}
+	if start >= len(users) {
+		return page
+	}
+
+	end := start + query.Limit
+	if end < len(users) {
+		page.HasMore = true
+	} else {
+		end = len(users)
+	}
+	page.Users = users[start:end]
+
+	return page
+}
+
+// phoneSearch is a search term for phone numbers. A term starting with +
+// is a prefix of the number. Digits alone may appear anywhere in it; they
+// are found as the prefix of one of the number's digit suffixes, so both
+// kinds of search are prefix lookups in a sorted index.
+type phoneSearch struct {
+	term      string
+	substring bool
+}
+
+// matches reports whether phone matches the search
+func (s phoneSearch) matches(phone string) bool {
+	if s.term == "" {
+		return true
+	}
+	if s.substring {
+		return strings.Contains(strings.TrimPrefix(phone, "+"), s.term)
+	}
+	return strings.HasPrefix(phone, s.term)
+}
+
+// parsePhoneSearch normalizes search, dropping the separators people type
+// into numbers. It reports false for terms no phone number contains.
+func parsePhoneSearch(search string) (phoneSearch, bool) {
+	term := strings.NewReplacer(" ", "", "-", "", "_", "").Replace(search)
+	digits := strings.TrimPrefix(term, "+")
+	if digits == "" {
+		return phoneSearch{}, false
+	}
+	for _, c := range digits {
+		if c < '0' || c > '9' {
+			return phoneSearch{}, false
+		}
+	}
+	return phoneSearch{term: term, substring: term == digits}, true
+}
+
+// phoneSuffixes returns every suffix of the digits of phone
+func phoneSuffixes(phone string) []string {
+	digits := strings.TrimPrefix(phone, "+")
+	suffixes := make([]string, len(digits))
+	for i := range digits {
+		suffixes[i] = digits[i:]
+	}
+	return suffixes
+}
+
+// prefixRange returns the bounds [min, max) of the strings starting with
+// prefix. Phone numbers hold + and digits only, so the last byte of a
+// prefix never overflows.
+func prefixRange(prefix string) (string, string) {
+	last := len(prefix) - 1
+	return prefix, prefix[:last] + string(prefix[last]+1)
+}
@@ -0,0 +1,347 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+
+	"otp-auth-service/internal/models"
+)
+
+// forEachUserRepository runs test against an empty repository of every kind
+func forEachUserRepository(t *testing.T, test func(t *testing.T, repo UserRepository)) {
+	t.Run("memory", func(t *testing.T) {
+		test(t, NewUserRepository())
+	})
+	t.Run("redis", func(t *testing.T) {
+		repo, _ := newTestRedisUserRepository(t)
+		test(t, repo)
+	})
+	forEachSQLDialect(t, func(t *testing.T, db *sql.DB, dialect string) {
+		test(t, newTestSQLUserRepository(t, db, dialect))
+	})
+}
+
+// createSortTestUsers creates users whose registration and last login
+// times tie in places, so the ID decides their order. IDs ascend in the
+// order users are listed here.
+func createSortTestUsers(t *testing.T, repo UserRepository) []*models.User {
+	t.Helper()
+
+	base := time.Now().Add(-time.Hour).Truncate(time.Second)
+	var users []*models.User
+	for i, u := range []struct {
+		phone             string
+		registered, login time.Duration
+	}{
+		{"+1000000004", 0, 30 * time.Minute},
+		{"+1000000002", time.Minute, 10 * time.Minute},
+		{"+1000000005", 2 * time.Minute, 10 * time.Minute},
+		{"+1000000001", 2 * time.Minute, 20 * time.Minute},
+		{"+1000000003", 4 * time.Minute, 40 * time.Minute},
+	} {
+		user := models.NewUser(u.phone)
+		user.ID = fmt.Sprintf("00000000-0000-4000-8000-%012d", i+1)
+		user.RegisteredAt = base.Add(u.registered)
+		user.LastLoginAt = base.Add(u.login)
+		if err := repo.Create(user); err != nil {
+			t.Fatalf("Failed to create user: %v", err)
+		}
+		users = append(users, user)
+	}
+	return users
+}
+
+func phoneNumbers(users []*models.User) []string {
+	phones := make([]string, len(users))
+	for i, user := range users {
+		phones[i] = user.PhoneNumber
+	}
+	return phones
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestUserRepository_Sorting(t *testing.T) {
+	forEachUserRepository(t, func(t *testing.T, repo UserRepository) {
+		users := createSortTestUsers(t, repo)
+
+		for _, field := range models.UserSortFields {
+			for _, desc := range []bool{false, true} {
+				expected := append([]*models.User(nil), users...)
+				sort.Slice(expected, func(i, j int) bool {
+					c := compareUsers(expected[i], expected[j], field)
+					return (c < 0) != desc
+				})
+				want := phoneNumbers(expected)
+
+				// Page numbers
+				var paged []*models.User
+				for page := 1; page <= 3; page++ {
+					result, err := repo.GetAll(models.UserQuery{Page: page, Limit: 2, Sort: field, Desc: desc})
+					if err != nil {
+						t.Fatalf("Expected no error, got %v", err)
+					}
+					if result.Total != 5 || result.HasMore != (page < 3) {
+						t.Errorf("%s desc=%v page %d: unexpected total %d, has more %v", field, desc, page, result.Total, result.HasMore)
+					}
+					paged = append(paged, result.Users...)
+				}
+				if got := phoneNumbers(paged); !equalStrings(got, want) {
+					t.Errorf("%s desc=%v: expected pages %v, got %v", field, desc, want, got)
+				}
+
+				// Cursors
+				var followed []*models.User
+				query := models.UserQuery{Page: 1, Limit: 2, Sort: field, Desc: desc}
+				for i := 0; i < 5; i++ {
+					result, err := repo.GetAll(query)
+					if err != nil {
+						t.Fatalf("Expected no error, got %v", err)
+					}
+					followed = append(followed, result.Users...)
+					if !result.HasMore {
+						break
+					}
+					query.Cursor = models.NewUserCursor(result.Users[len(result.Users)-1], field, desc)
+				}
+				if got := phoneNumbers(followed); !equalStrings(got, want) {
+					t.Errorf("%s desc=%v: expected cursor pages %v, got %v", field, desc, want, got)
+				}
+			}
+		}
+	})
+}
+
+func TestUserRepository_SortByPhoneNumber(t *testing.T) {
+	forEachUserRepository(t, func(t *testing.T, repo UserRepository) {
+		createSortTestUsers(t, repo)
+
+		result, err := repo.GetAll(models.UserQuery{Page: 1, Limit: 10, Sort: models.UserSortPhoneNumber, Desc: true})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		want := []string{"+1000000005", "+1000000004", "+1000000003", "+1000000002", "+1000000001"}
+		if got := phoneNumbers(result.Users); !equalStrings(got, want) {
+			t.Errorf("Expected %v, got %v", want, got)
+		}
+	})
+}
+
+func TestUserRepository_CursorAfterChangedUser(t *testing.T) {
+	forEachUserRepository(t, func(t *testing.T, repo UserRepository) {
+		users := createSortTestUsers(t, repo)
+
+		// The cursor keeps its position when its user logs in meanwhile
+		cursor := models.NewUserCursor(users[1], models.UserSortLastLoginAt, false)
+		users[1].LastLoginAt = users[1].LastLoginAt.Add(time.Hour)
+		if err := repo.Update(users[1]); err != nil {
+			t.Fatalf("Failed to update user: %v", err)
+		}
+
+		result, err := repo.GetAll(models.UserQuery{Limit: 10, Sort: models.UserSortLastLoginAt, Cursor: cursor})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		want := []string{"+1000000005", "+1000000001", "+1000000004", "+1000000003", "+1000000002"}
+		if got := phoneNumbers(result.Users); !equalStrings(got, want) {
+			t.Errorf("Expected users after the cursor, got %v", got)
+		}
+	})
+}
+
+// createSearchTestUsers creates users registered and last logged in an
+// hour apart; the one with +447700900123 is inactive
+func createSearchTestUsers(t *testing.T, repo UserRepository) ([]*models.User, time.Time) {
+	t.Helper()
+
+	base := time.Now().Add(-24 * time.Hour).Truncate(time.Second)
+	var users []*models.User
+	for i, phone := range []string{"+4915112345678", "+4917098765432", "+14155550123", "+447700900123", "+4915199990000"} {
+		user := models.NewUser(phone)
+		user.RegisteredAt = base.Add(time.Duration(i) * time.Hour)
+		user.LastLoginAt = base.Add(time.Duration(10+i) * time.Hour)
+		user.IsActive = phone != "+447700900123"
+		if err := repo.Create(user); err != nil {
+			t.Fatalf("Failed to create user: %v", err)
+		}
+		users = append(users, user)
+	}
+	return users, base
+}
+
+func searchPhones(t *testing.T, repo UserRepository, search string, filter models.UserFilter) []string {
+	t.Helper()
+
+	result, err := repo.GetAll(models.UserQuery{Page: 1, Limit: 10, Search: search, Filter: filter, Sort: models.UserSortPhoneNumber})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if result.Total != len(result.Users) {
+		t.Errorf("Expected total %d, got %d", len(result.Users), result.Total)
+	}
+	return phoneNumbers(result.Users)
+}
+
+func TestUserRepository_Search(t *testing.T) {
+	forEachUserRepository(t, func(t *testing.T, repo UserRepository) {
+		users, _ := createSearchTestUsers(t, repo)
+
+		tests := []struct {
+			search string
+			want   []string
+		}{
+			{"+49151", []string{"+4915112345678", "+4915199990000"}},
+			{"+49 151", []string{"+4915112345678", "+4915199990000"}},
+			{"+1", []string{"+14155550123"}},
+			{"+415", nil},
+			{"555", []string{"+14155550123"}},
+			{"123", []string{"+14155550123", "+447700900123", "+4915112345678"}},
+			{"0123", []string{"+14155550123", "+447700900123"}},
+			{"4915", []string{"+4915112345678", "+4915199990000"}},
+			{"9999", []string{"+4915199990000"}},
+			{"john", nil},
+		}
+		for _, tt := range tests {
+			if got := searchPhones(t, repo, tt.search, models.UserFilter{}); !equalStrings(got, tt.want) {
+				t.Errorf("Search %q: expected %v, got %v", tt.search, tt.want, got)
+			}
+		}
+
+		// A changed number is found by its new digits only
+		users[1].PhoneNumber = "+4930123456"
+		if err := repo.Update(users[1]); err != nil {
+			t.Fatalf("Failed to update user: %v", err)
+		}
+		if got := searchPhones(t, repo, "98765", models.UserFilter{}); len(got) != 0 {
+			t.Errorf("Expected old number not to be found, got %v", got)
+		}
+		if got := searchPhones(t, repo, "3012", models.UserFilter{}); !equalStrings(got, []string{"+4930123456"}) {
+			t.Errorf("Expected new number to be found, got %v", got)
+		}
+	})
+}
+
+func TestUserRepository_Filter(t *testing.T) {
+	forEachUserRepository(t, func(t *testing.T, repo UserRepository) {
+		_, base := createSearchTestUsers(t, repo)
+		active, inactive := true, false
+
+		tests := []struct {
+			name   string
+			search string
+			filter models.UserFilter
+			want   []string
+		}{
+			{name: "inactive", filter: models.UserFilter{IsActive: &inactive}, want: []string{"+447700900123"}},
+			{name: "active", filter: models.UserFilter{IsActive: &active}, want: []string{"+14155550123", "+4915112345678", "+4915199990000", "+4917098765432"}},
+			{
+				name:   "registered range",
+				filter: models.UserFilter{RegisteredAfter: base.Add(time.Hour), RegisteredBefore: base.Add(3 * time.Hour)},
+				want:   []string{"+14155550123", "+4917098765432"},
+			},
+			{
+				name:   "last login range",
+				filter: models.UserFilter{LastLoginAfter: base.Add(13 * time.Hour)},
+				want:   []string{"+447700900123", "+4915199990000"},
+			},
+			{
+				name:   "combined",
+				search: "+49",
+				filter: models.UserFilter{IsActive: &active, RegisteredAfter: base.Add(time.Hour), LastLoginBefore: base.Add(20 * time.Hour)},
+				want:   []string{"+4915199990000", "+4917098765432"},
+			},
+			{
+				name:   "combined without matches",
+				search: "0123",
+				filter: models.UserFilter{IsActive: &inactive, RegisteredBefore: base.Add(time.Hour)},
+			},
+		}
+		for _, tt := range tests {
+			if got := searchPhones(t, repo, tt.search, tt.filter); !equalStrings(got, tt.want) {
+				t.Errorf("%s: expected %v, got %v", tt.name, tt.want, got)
+			}
+		}
+
+		// Cursors page through filtered users
+		query := models.UserQuery{Limit: 1, Page: 1, Filter: models.UserFilter{IsActive: &active}, Sort: models.UserSortLastLoginAt, Desc: true}
+		var followed []*models.User
+		for i := 0; i < 5; i++ {
+			result, err := repo.GetAll(query)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if result.Total != 4 {
+				t.Errorf("Expected total 4, got %d", result.Total)
+			}
+			followed = append(followed, result.Users...)
+			if !result.HasMore {
+				break
+			}
+			query.Cursor = models.NewUserCursor(result.Users[0], query.Sort, query.Desc)
+		}
+		want := []string{"+4915199990000", "+14155550123", "+4917098765432", "+4915112345678"}
+		if got := phoneNumbers(followed); !equalStrings(got, want) {
+			t.Errorf("Expected cursor pages %v, got %v", want, got)
+		}
+	})
+}
+
+func TestUserRepository_FilterCursor(t *testing.T) {
+	forEachUserRepository(t, func(t *testing.T, repo UserRepository) {
+		_, base := createSearchTestUsers(t, repo)
+		active := true
+
+		// Ranges on the sort field, on another field and with a search
+		// are paged alike
+		for _, query := range []models.UserQuery{
+			{Sort: models.UserSortRegisteredAt, Filter: models.UserFilter{RegisteredAfter: base.Add(time.Hour)}},
+			{Sort: models.UserSortRegisteredAt, Desc: true, Filter: models.UserFilter{IsActive: &active, RegisteredBefore: base.Add(4 * time.Hour)}},
+			{Sort: models.UserSortPhoneNumber, Filter: models.UserFilter{LastLoginBefore: base.Add(14 * time.Hour)}},
+			{Sort: models.UserSortPhoneNumber, Desc: true, Search: "+49"},
+			{Sort: models.UserSortLastLoginAt, Search: "1", Filter: models.UserFilter{IsActive: &active}},
+		} {
+			query.Page, query.Limit = 1, 10
+			all, err := repo.GetAll(query)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			want := phoneNumbers(all.Users)
+			if len(want) < 2 {
+				t.Fatalf("Expected several users for %+v, got %v", query, want)
+			}
+
+			query.Limit = 1
+			var followed []*models.User
+			for i := 0; i < len(want)+1; i++ {
+				result, err := repo.GetAll(query)
+				if err != nil {
+					t.Fatalf("Expected no error, got %v", err)
+				}
+				if result.Total != len(want) {
+					t.Errorf("Expected total %d, got %d", len(want), result.Total)
+				}
+				followed = append(followed, result.Users...)
+				if !result.HasMore {
+					break
+				}
+				query.Cursor = models.NewUserCursor(result.Users[0], query.Sort, query.Desc)
+			}
+			if got := phoneNumbers(followed); !equalStrings(got, want) {
+				t.Errorf("Sort %s desc=%v: expected cursor pages %v, got %v", query.Sort, query.Desc, want, got)
+			}
+		}
+	})
+}
@@ -1,7 +1,10 @@
 package repository
 
 import (
+	"sort"
+	"strings"
 	"sync"
+	"time"
 
 	"otp-auth-service/internal/errors"
 	"otp-auth-service/internal/models"
@@ -12,19 +15,32 @@ type UserRepository interface {
 	GetByPhoneNumber(phoneNumber string) (*models.User, error)
 	GetByID(id string) (*models.User, error)
 	Update(user *models.User) error
-	GetAll(page, limit int, search string) ([]*models.User, int, error)
+	// GetAll returns a page of the users matching query, in the order it
+	// asks for
+	GetAll(query models.UserQuery) (*models.UserPage, error)
 }
 
 type InMemoryUserRepository struct {
 	users      map[string]*models.User
 	phoneIndex map[string]string // phone number -> user ID
-	mutex      sync.RWMutex
+	// indexed holds a copy of each user as it is in the indexes, since
+	// callers may change stored users in place
+	indexed map[string]models.User
+	// sorted holds the sort indexes by name (see sortIndexName), each
+	// holding sortEntry entries in order
+	sorted map[string][]string
+	// byPhoneSuffix holds sorted "<digit suffix>:<ID>" entries for
+	// substring searches
+	byPhoneSuffix []string
+	mutex         sync.RWMutex
 }
 
 func NewUserRepository() UserRepository {
 	return &InMemoryUserRepository{
 		users:      make(map[string]*models.User),
 		phoneIndex: make(map[string]string),
+		indexed:    make(map[string]models.User),
+		sorted:     make(map[string][]string),
 	}
 }
 
@@ -38,7 +54,7 @@ func (r *InMemoryUserRepository) Create(user *models.User) error {
 	}
 
 	r.users[user.ID] = user
-	r.phoneIndex[user.PhoneNumber] = user.ID
+	r.index(user)
 	return nil
 }
 
@@ -79,36 +95,230 @@ func (r *InMemoryUserRepository) Update(user *models.User) error {
 		return errors.ErrUserNotFound
 	}
 
+	// Callers may have changed the stored user in place, so the indexes
+	// are updated from the copy in indexed
+	if owner, exists := r.phoneIndex[user.PhoneNumber]; exists && owner != user.ID {
+		return errors.ErrUserAlreadyExists
+	}
+	r.unindex(user.ID)
+	r.index(user)
+
 	r.users[user.ID] = user
 	return nil
 }
 
-func (r *InMemoryUserRepository) GetAll(page, limit int, search string) ([]*models.User, int, error) {
+// index adds user to the phone number index and the sort indexes of all
+// users and of its status
+func (r *InMemoryUserRepository) index(user *models.User) {
+	r.phoneIndex[user.PhoneNumber] = user.ID
+	r.indexed[user.ID] = *user
+	for _, index := range sortIndexNames {
+		entry := sortEntry(user, index.field)
+		for _, status := range []*bool{nil, &user.IsActive} {
+			name := sortIndexName(index.field, status)
+			r.sorted[name] = insertSorted(r.sorted[name], entry)
+		}
+	}
+	for _, suffix := range phoneSuffixes(user.PhoneNumber) {
+		r.byPhoneSuffix = insertSorted(r.byPhoneSuffix, suffix+":"+user.ID)
+	}
+}
+
+// unindex removes the user with id from the indexes
+func (r *InMemoryUserRepository) unindex(id string) {
+	user, ok := r.indexed[id]
+	if !ok {
+		return
+	}
+
+	delete(r.phoneIndex, user.PhoneNumber)
+	delete(r.indexed, id)
+	for _, index := range sortIndexNames {
+		entry := sortEntry(&user, index.field)
+		for _, status := range []*bool{nil, &user.IsActive} {
+			name := sortIndexName(index.field, status)
+			r.sorted[name] = removeSorted(r.sorted[name], entry)
+		}
+	}
+	for _, suffix := range phoneSuffixes(user.PhoneNumber) {
+		r.byPhoneSuffix = removeSorted(r.byPhoneSuffix, suffix+":"+id)
+	}
+}
+
+// sortEntryTime formats times so that their strings sort in time order
+const sortEntryTime = "2006-01-02T15:04:05.000000000"
+
+// sortEntry returns the entry of user in the sort indexes of field:
+// "<sort value>\x00<ID>". The separator sorts before every character of a
+// value, so entries are in the order of compareUsers.
+func sortEntry(user *models.User, field string) string {
+	switch field {
+	case models.UserSortPhoneNumber:
+		return user.PhoneNumber + "\x00" + user.ID
+	case models.UserSortLastLoginAt:
+		return user.LastLoginAt.UTC().Format(sortEntryTime) + "\x00" + user.ID
+	default:
+		return user.RegisteredAt.UTC().Format(sortEntryTime) + "\x00" + user.ID
+	}
+}
+
+// entryRange restricts a query to the users whose entries in index are
+// from min up to but excluding max
+type entryRange struct {
+	index    string
+	min, max string
+}
+
+// timeEntryRange returns the range of sort entries of times from after up
+// to before, either of which may be zero
+func timeEntryRange(index string, after, before time.Time) entryRange {
+	rg := entryRange{index: index}
+	if !after.IsZero() {
+		rg.min = after.UTC().Format(sortEntryTime)
+	}
+	if !before.IsZero() {
+		rg.max = before.UTC().Format(sortEntryTime)
+	}
+	return rg
+}
+
+// entries returns the entries of the range
+func (r *InMemoryUserRepository) entries(rg entryRange) []string {
+	entries := r.sorted[rg.index]
+	end := len(entries)
+	if rg.max != "" {
+		end = sort.SearchStrings(entries, rg.max)
+	}
+	return entries[sort.SearchStrings(entries[:end], rg.min):end]
+}
+
+// GetAll pages through the sort index of the queried status, within a time
+// range or phone prefix on the sort field. Other restrictions are answered
+// from the index entries of the one matching the fewest users, whose users
+// are then checked against the whole query and sorted.
+func (r *InMemoryUserRepository) GetAll(query models.UserQuery) (*models.UserPage, error) {
 	r.mutex.RLock()
 	defer r.mutex.RUnlock()
 
-	var filteredUsers []*models.User
+	status := query.Filter.IsActive
+	index := sortIndexName(query.Sort, status)
 
-	// Filter users based on search criteria
-	for _, user := range r.users {
-		if search == "" || user.PhoneNumber == search {
-			filteredUsers = append(filteredUsers, user)
+	var ranges []entryRange
+	filter := query.Filter
+	if !filter.RegisteredAfter.IsZero() || !filter.RegisteredBefore.IsZero() {
+		ranges = append(ranges, timeEntryRange(sortIndexName(models.UserSortRegisteredAt, status), filter.RegisteredAfter, filter.RegisteredBefore))
+	}
+	if !filter.LastLoginAfter.IsZero() || !filter.LastLoginBefore.IsZero() {
+		ranges = append(ranges, timeEntryRange(sortIndexName(models.UserSortLastLoginAt, status), filter.LastLoginAfter, filter.LastLoginBefore))
+	}
+
+	var search phoneSearch
+	if query.Search != "" {
+		var ok bool
+		if search, ok = parsePhoneSearch(query.Search); !ok {
+			return &models.UserPage{Users: []*models.User{}}, nil
+		}
+		if !search.substring {
+			min, max := prefixRange(search.term)
+			ranges = append(ranges, entryRange{index: sortIndexName(models.UserSortPhoneNumber, status), min: min, max: max})
 		}
 	}
 
-	total := len(filteredUsers)
+	if !search.substring && len(ranges) <= 1 {
+		rg := entryRange{index: index}
+		if len(ranges) == 1 {
+			rg = ranges[0]
+		}
+		if rg.index == index {
+			return r.pageEntries(r.entries(rg), query), nil
+		}
+	}
 
-	// Calculate pagination
-	start := (page - 1) * limit
-	end := start + limit
+	// Candidates come from the narrowest restriction
+	var ids []string
+	if search.substring {
+		min, max := prefixRange(search.term)
+		entries := r.byPhoneSuffix[sort.SearchStrings(r.byPhoneSuffix, min):sort.SearchStrings(r.byPhoneSuffix, max)]
+		ids = entryIDs(entries, ":")
+	}
+	for _, rg := range ranges {
+		if entries := r.entries(rg); ids == nil || len(entries) < len(ids) {
+			ids = entryIDs(entries, "\x00")
+		}
+	}
 
-	if start >= total {
-		return []*models.User{}, total, nil
+	// A number may hold a substring more than once
+	var users []*models.User
+	seen := make(map[string]bool)
+	for _, id := range ids {
+		user := r.indexed[id]
+		if !seen[id] && query.Filter.Matches(&user) && search.matches(user.PhoneNumber) {
+			seen[id] = true
+			users = append(users, r.users[id])
+		}
 	}
 
-	if end > total {
-		end = total
+	return pageUsers(users, query), nil
+}
+
+// pageEntries cuts the page of query out of the entries of its sort index
+func (r *InMemoryUserRepository) pageEntries(entries []string, query models.UserQuery) *models.UserPage {
+	page := &models.UserPage{Users: []*models.User{}, Total: len(entries)}
+	if query.Limit < 1 || (query.Cursor == nil && query.Page < 1) {
+		return page
 	}
 
-	return filteredUsers[start:end], total, nil
+	// Positions count from the end of the index when sorting descending
+	n := len(entries)
+	at := func(i int) string {
+		if query.Desc {
+			return entries[n-1-i]
+		}
+		return entries[i]
+	}
+
+	var start int
+	if query.Cursor != nil {
+		after := sortEntry(cursorUser(query.Cursor), query.Sort)
+		if query.Desc {
+			start = n - sort.SearchStrings(entries, after)
+		} else {
+			start = sort.Search(n, func(i int) bool { return entries[i] > after })
+		}
+	} else {
+		start = (query.Page - 1) * query.Limit
+	}
+
+	for i := start; i < n && i < start+query.Limit; i++ {
+		page.Users = append(page.Users, r.users[entryIDs([]string{at(i)}, "\x00")[0]])
+	}
+	page.HasMore = start+query.Limit < n
+
+	return page
+}
+
+// entryIDs returns the user IDs of index entries, which end in the ID
+// after sep
+func entryIDs(entries []string, sep string) []string {
+	ids := make([]string, len(entries))
+	for i, entry := range entries {
+		ids[i] = entry[strings.LastIndex(entry, sep)+len(sep):]
+	}
+	return ids
+}
+
+func insertSorted(entries []string, entry string) []string {
+	i := sort.SearchStrings(entries, entry)
+	entries = append(entries, "")
+	copy(entries[i+1:], entries[i:])
+	entries[i] = entry
+	return entries
+}
+
+func removeSorted(entries []string, entry string) []string {
+	i := sort.SearchStrings(entries, entry)
+	if i < len(entries) && entries[i] == entry {
+		entries = append(entries[:i], entries[i+1:]...)
+	}
+	return entries
 }
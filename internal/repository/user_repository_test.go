@@ -173,7 +173,7 @@ func TestInMemoryUserRepository_GetAll(t *testing.T) {
 	}
 
 	// Test get all users
-	allUsers, total, err := repo.GetAll(1, 10, "")
+	allUsers, total, err := getAll(repo, 1, 10, "")
 	if err != nil {
 		t.Errorf("Expected no error, got %v", err)
 	}
@@ -187,7 +187,7 @@ func TestInMemoryUserRepository_GetAll(t *testing.T) {
 	}
 
 	// Test pagination
-	paginatedUsers, total, err := repo.GetAll(1, 2, "")
+	paginatedUsers, total, err := getAll(repo, 1, 2, "")
 	if err != nil {
 		t.Errorf("Expected no error, got %v", err)
 	}
@@ -201,7 +201,7 @@ func TestInMemoryUserRepository_GetAll(t *testing.T) {
 	}
 
 	// Test second page
-	secondPageUsers, total, err := repo.GetAll(2, 2, "")
+	secondPageUsers, total, err := getAll(repo, 2, 2, "")
 	if err != nil {
 		t.Errorf("Expected no error, got %v", err)
 	}
@@ -211,7 +211,7 @@ func TestInMemoryUserRepository_GetAll(t *testing.T) {
 	}
 
 	// Test search
-	searchResults, total, err := repo.GetAll(1, 10, "+1111111111")
+	searchResults, total, err := getAll(repo, 1, 10, "+1111111111")
 	if err != nil {
 		t.Errorf("Expected no error, got %v", err)
 	}
@@ -229,7 +229,7 @@ func TestInMemoryUserRepository_GetAll(t *testing.T) {
 	}
 
 	// Test empty page
-	emptyPageUsers, total, err := repo.GetAll(10, 10, "")
+	emptyPageUsers, total, err := getAll(repo, 10, 10, "")
 	if err != nil {
 		t.Errorf("Expected no error, got %v", err)
 	}
@@ -250,7 +250,7 @@ func TestInMemoryUserRepository_ConcurrentAccess(t *testing.T) {
 	// Test concurrent reads
 	for i := 0; i < 5; i++ {
 		go func() {
-			_, _, _ = repo.GetAll(1, 10, "")
+			_, _, _ = getAll(repo, 1, 10, "")
 			done <- true
 		}()
 	}
@@ -271,7 +271,7 @@ func TestInMemoryUserRepository_ConcurrentAccess(t *testing.T) {
 	}
 
 	// Verify no panic occurred
-	users, total, err := repo.GetAll(1, 100, "")
+	users, total, err := getAll(repo, 1, 100, "")
 	if err != nil {
 		t.Errorf("Expected no error after concurrent access, got %v", err)
 	}
@@ -304,7 +304,7 @@ func TestInMemoryUserRepository_EdgeCases(t *testing.T) {
 	}
 
 	// Test pagination edge cases - repository uses defaults for invalid values
-	_, total, err := repo.GetAll(0, 0, "")
+	_, total, err := getAll(repo, 0, 0, "")
 	if err != nil {
 		t.Errorf("Expected no error for invalid pagination (should use defaults), got %v", err)
 	}
@@ -312,7 +312,7 @@ func TestInMemoryUserRepository_EdgeCases(t *testing.T) {
 		t.Errorf("Expected total 2, got %d", total)
 	}
 
-	_, total, err = repo.GetAll(-1, -1, "")
+	_, total, err = getAll(repo, -1, -1, "")
 	if err != nil {
 		t.Errorf("Expected no error for negative pagination (should use defaults), got %v", err)
 	}
@@ -320,3 +320,36 @@ func TestInMemoryUserRepository_EdgeCases(t *testing.T) {
 		t.Errorf("Expected total 2, got %d", total)
 	}
 }
+
+// getAll lists a page of users in the default order
+func getAll(repo UserRepository, page, limit int, search string) ([]*models.User, int, error) {
+	result, err := repo.GetAll(models.UserQuery{Page: page, Limit: limit, Search: search})
+	if err != nil {
+		return nil, 0, err
+	}
+	return result.Users, result.Total, nil
+}
+
+func TestUserRepository_Profile(t *testing.T) {
+	forEachUserRepository(t, func(t *testing.T, repo UserRepository) {
+		user := models.NewUser("+1234567890")
+		user.Name = "Jane Doe"
+		if err := repo.Create(user); err != nil {
+			t.Fatalf("Failed to create user: %v", err)
+		}
+
+		updated := *user
+		updated.Locale = "de-DE"
+		if err := repo.Update(&updated); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		stored, err := repo.GetByID(user.ID)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if stored.Name != "Jane Doe" || stored.Locale != "de-DE" {
+			t.Errorf("Expected profile to be stored, got %+v", stored)
+		}
+	})
+}
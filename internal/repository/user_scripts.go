@@ -0,0 +1,184 @@
+package repository
+
+import "github.com/redis/go-redis/v9"
+
+// phoneSuffixesLua defines phone_suffixes, which returns every suffix of
+// the digits of a phone number like phoneSuffixes
+const phoneSuffixesLua = `
+local function phone_suffixes(phone)
+	local digits = (string.gsub(phone, '^%+', ''))
+	local suffixes = {}
+	for i = 1, #digits do
+		suffixes[#suffixes + 1] = string.sub(digits, i)
+	end
+	return suffixes
+end
+`
+
+// indexUserLua defines index_user, which adds a user to the sort indexes
+// of all users and of its status and removes it from those of the other
+// status
+const indexUserLua = `
+local function index_user(id, active, scores)
+	local status, other = 7, 10
+	if active == '0' then
+		status, other = 10, 7
+	end
+	for i = 0, 2 do
+		redis.call('ZADD', KEYS[4 + i], scores[i + 1], id)
+		redis.call('ZADD', KEYS[status + i], scores[i + 1], id)
+		redis.call('ZREM', KEYS[other + i], id)
+	end
+end
+`
+
+// createUserScript claims the phone number and stores the user in a single
+// step, so concurrent registrations of a number cannot both succeed. It
+// returns scriptOK, or scriptRejected if the number is taken.
+//
+// KEYS[1] user key, KEYS[2] phone key, KEYS[3] phone suffix index,
+// KEYS[4-6] registration, last login and phone number indexes of all users,
+// KEYS[7-9] the same of active users, KEYS[10-12] of inactive users
+// ARGV[1] ID, ARGV[2] phone number, ARGV[3] registered_at, ARGV[4] last_login_at,
+// ARGV[5] is_active, ARGV[6] registration score, ARGV[7] last login score,
+// ARGV[8] name, ARGV[9] locale, ARGV[10] phone number score
+var createUserScript = redis.NewScript(phoneSuffixesLua + indexUserLua + `
+if redis.call('EXISTS', KEYS[2]) == 1 then
+	return 0
+end
+
+redis.call('SET', KEYS[2], ARGV[1])
+redis.call('HSET', KEYS[1], 'id', ARGV[1], 'phone_number', ARGV[2], 'registered_at', ARGV[3],
+	'last_login_at', ARGV[4], 'is_active', ARGV[5], 'name', ARGV[8], 'locale', ARGV[9])
+index_user(ARGV[1], ARGV[5], {ARGV[6], ARGV[7], ARGV[10]})
+for _, suffix in ipairs(phone_suffixes(ARGV[2])) do
+	redis.call('ZADD', KEYS[3], 0, suffix .. ':' .. ARGV[1])
+end
+return 1
+`)
+
+// updateUserScript overwrites a user and moves the phone number indexes
+// along if the number changed. It returns scriptOK, scriptNotFound, or
+// scriptRejected if the new number belongs to another user. The key of
+// the old number is built from ARGV[11], since it is only known once the
+// user is read.
+//
+// KEYS and ARGV[1-10] are those of createUserScript, ARGV[11] the phone key prefix
+var updateUserScript = redis.NewScript(phoneSuffixesLua + indexUserLua + `
+local old = redis.call('HGET', KEYS[1], 'phone_number')
+if not old then
+	return -1
+end
+
+if old ~= ARGV[2] then
+	local owner = redis.call('GET', KEYS[2])
+	if owner and owner ~= ARGV[1] then
+		return 0
+	end
+	redis.call('DEL', ARGV[11] .. old)
+	redis.call('SET', KEYS[2], ARGV[1])
+	for _, suffix in ipairs(phone_suffixes(old)) do
+		redis.call('ZREM', KEYS[3], suffix .. ':' .. ARGV[1])
+	end
+	for _, suffix in ipairs(phone_suffixes(ARGV[2])) do
+		redis.call('ZADD', KEYS[3], 0, suffix .. ':' .. ARGV[1])
+	end
+end
+
+redis.call('HSET', KEYS[1], 'phone_number', ARGV[2], 'registered_at', ARGV[3],
+	'last_login_at', ARGV[4], 'is_active', ARGV[5], 'name', ARGV[8], 'locale', ARGV[9])
+index_user(ARGV[1], ARGV[5], {ARGV[6], ARGV[7], ARGV[10]})
+return 1
+`)
+
+// filterUsersScript stores the members of a sort index that are within
+// every score range and match a phone substring search in KEYS[1], with
+// their sort scores, and returns the number of candidates it checked. The
+// candidates are the members of the restriction matching the fewest users;
+// each is looked up in the sort index and the other restrictions, so no
+// broader restriction is read in full.
+//
+// KEYS[1] result, KEYS[2] sort index, then the index of each score range,
+// then optionally the phone suffix index
+// ARGV[1] number of score ranges, ARGV[2] TTL of the result in
+// milliseconds, ARGV[3] user key prefix, then min and max per score range,
+// then the lex range of the substring search
+var filterUsersScript = redis.NewScript(`
+local ranges = tonumber(ARGV[1])
+local search = #KEYS > 2 + ranges
+local lex_min, lex_max = ARGV[4 + 2 * ranges], ARGV[5 + 2 * ranges]
+
+local function bound(arg)
+	if string.sub(arg, 1, 1) == '(' then
+		return tonumber(string.sub(arg, 2)), true
+	end
+	return tonumber(arg), false
+end
+
+local function within(score, min, max)
+	if min ~= '-inf' then
+		local value, exclusive = bound(min)
+		if score < value or (exclusive and score == value) then
+			return false
+		end
+	end
+	if max ~= '+inf' then
+		local value, exclusive = bound(max)
+		if score > value or (exclusive and score == value) then
+			return false
+		end
+	end
+	return true
+end
+
+local smallest, fewest
+for i = 1, ranges do
+	local count = redis.call('ZCOUNT', KEYS[2 + i], ARGV[2 + 2 * i], ARGV[3 + 2 * i])
+	if not fewest or count < fewest then
+		smallest, fewest = i, count
+	end
+end
+if search then
+	local count = redis.call('ZLEXCOUNT', KEYS[3 + ranges], lex_min, lex_max)
+	if not fewest or count < fewest then
+		smallest, fewest = 0, count
+	end
+end
+
+local candidates
+if smallest == 0 then
+	candidates = {}
+	local seen = {}
+	for _, member in ipairs(redis.call('ZRANGEBYLEX', KEYS[3 + ranges], lex_min, lex_max)) do
+		local id = string.match(member, '[^:]+$')
+		if not seen[id] then
+			seen[id] = true
+			candidates[#candidates + 1] = id
+		end
+	end
+else
+	candidates = redis.call('ZRANGEBYSCORE', KEYS[2 + smallest], ARGV[2 + 2 * smallest], ARGV[3 + 2 * smallest])
+end
+
+local term = search and string.sub(lex_min, 2)
+for _, id in ipairs(candidates) do
+	local score = redis.call('ZSCORE', KEYS[2], id)
+	local match = score ~= false
+	for i = 1, ranges do
+		if match and i ~= smallest then
+			local value = redis.call('ZSCORE', KEYS[2 + i], id)
+			match = value ~= false and within(tonumber(value), ARGV[2 + 2 * i], ARGV[3 + 2 * i])
+		end
+	end
+	if match and search and smallest ~= 0 then
+		local phone = redis.call('HGET', ARGV[3] .. id, 'phone_number')
+		match = phone ~= false and string.find((string.gsub(phone, '^%+', '')), term, 1, true) ~= nil
+	end
+	if match then
+		redis.call('ZADD', KEYS[1], score, id)
+	end
+end
+
+redis.call('PEXPIRE', KEYS[1], ARGV[2])
+return #candidates
+`)
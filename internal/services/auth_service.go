@@ -1,108 +1,613 @@
 package services
 
 import (
+	"crypto/rand"
+	"encoding/base64"
 	"fmt"
+	"log"
 	"time"
 
+	"otp-auth-service/internal/delivery"
 	"otp-auth-service/internal/errors"
 	"otp-auth-service/internal/models"
 	"otp-auth-service/internal/repository"
+	"otp-auth-service/internal/signing"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 )
 
+// AuthConfig holds the token settings of AuthService
+type AuthConfig struct {
+	Keyring *signing.Keyring
+	// Issuer is the iss claim of issued tokens; tokens from other issuers
+	// are rejected
+	Issuer string
+	// Audiences make up the aud claim of issued tokens; tokens must name
+	// at least one of them
+	Audiences       []string
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+}
+
 type AuthService struct {
-	userRepo  repository.UserRepository
-	otpRepo   repository.OTPRepository
-	jwtSecret string
+	userRepo       repository.UserRepository
+	otpRepo        repository.OTPRepository
+	tokenRepo      repository.TokenRepository
+	revocationRepo repository.RevocationRepository
+	sessionRepo    repository.SessionRepository
+	sender         delivery.Sender
+	config         AuthConfig
 }
 
-func NewAuthService(userRepo repository.UserRepository, otpRepo repository.OTPRepository, jwtSecret string) *AuthService {
+func NewAuthService(userRepo repository.UserRepository, otpRepo repository.OTPRepository, tokenRepo repository.TokenRepository, revocationRepo repository.RevocationRepository, sessionRepo repository.SessionRepository, sender delivery.Sender, config AuthConfig) *AuthService {
 	return &AuthService{
-		userRepo:  userRepo,
-		otpRepo:   otpRepo,
-		jwtSecret: jwtSecret,
+		userRepo:       userRepo,
+		otpRepo:        otpRepo,
+		tokenRepo:      tokenRepo,
+		revocationRepo: revocationRepo,
+		sessionRepo:    sessionRepo,
+		sender:         sender,
+		config:         config,
 	}
 }
 
-func (s *AuthService) RequestOTP(phoneNumber string) (*models.RequestOTPResponse, error) {
+func (s *AuthService) RequestOTP(phoneNumber string, purpose models.OTPPurpose) (*models.RequestOTPResponse, error) {
+	purpose = defaultPurpose(purpose)
+
 	// Generate OTP
-	_, err := s.otpRepo.GenerateOTP(phoneNumber)
+	issued, err := s.otpRepo.GenerateOTP(phoneNumber, purpose)
 	if err != nil {
 		return nil, err
 	}
 
+	// Deliver OTP to the user
+	if err := s.deliverOTP(phoneNumber, delivery.ChannelSMS, purpose, issued.Code); err != nil {
+		return nil, err
+	}
+
 	return &models.RequestOTPResponse{
 		Message:     "OTP sent successfully",
 		PhoneNumber: phoneNumber,
+		Purpose:     purpose,
+		ExpiresIn:   seconds(issued.ExpiresIn),
+		RetryAfter:  seconds(issued.RetryAfter),
 	}, nil
 }
 
-func (s *AuthService) VerifyOTP(phoneNumber, otp string) (*models.VerifyOTPResponse, error) {
-	// Verify OTP
-	isValid, err := s.otpRepo.VerifyOTP(phoneNumber, otp)
+// RequestUserOTP sends an OTP for purpose to the user's own phone number
+func (s *AuthService) RequestUserOTP(userID string, purpose models.OTPPurpose) (*models.RequestOTPResponse, error) {
+	user, err := s.userRepo.GetByID(userID)
 	if err != nil {
 		return nil, err
 	}
 
-	if !isValid {
-		return nil, errors.ErrInvalidOTP
+	return s.RequestOTP(user.PhoneNumber, purpose)
+}
+
+// ResendOTP re-delivers the pending OTP, optionally on another channel,
+// without issuing a new code
+func (s *AuthService) ResendOTP(phoneNumber, channel string, purpose models.OTPPurpose) (*models.ResendOTPResponse, error) {
+	purpose = defaultPurpose(purpose)
+	if channel == "" {
+		channel = string(delivery.ChannelSMS)
 	}
 
-	// Check if user exists
-	user, err := s.userRepo.GetByPhoneNumber(phoneNumber)
-	isNewUser := false
+	issued, err := s.otpRepo.ResendOTP(phoneNumber, purpose)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.deliverOTP(phoneNumber, delivery.Channel(channel), purpose, issued.Code); err != nil {
+		return nil, err
+	}
+
+	return &models.ResendOTPResponse{
+		Message:     "OTP resent successfully",
+		PhoneNumber: phoneNumber,
+		Purpose:     purpose,
+		Channel:     channel,
+		ExpiresIn:   seconds(issued.ExpiresIn),
+		RetryAfter:  seconds(issued.RetryAfter),
+	}, nil
+}
+
+func (s *AuthService) deliverOTP(phoneNumber string, channel delivery.Channel, purpose models.OTPPurpose, code string) error {
+	err := s.sender.Send(&delivery.Message{
+		Channel:   channel,
+		Recipient: phoneNumber,
+		Body:      fmt.Sprintf("Your verification code is %s", code),
+		Metadata: map[string]string{
+			"type":    "otp",
+			"purpose": string(purpose),
+		},
+	})
+	if err != nil {
+		// Gateway errors may hold URLs and credentials, so callers only
+		// learn that delivery failed
+		log.Printf("Failed to deliver %s OTP over %s: %v", purpose, channel, err)
+		return errors.ErrOTPDeliveryFailed
+	}
+	return nil
+}
+
+// defaultPurpose treats a missing purpose as login
+func defaultPurpose(purpose models.OTPPurpose) models.OTPPurpose {
+	if purpose == "" {
+		return models.PurposeLogin
+	}
+	return purpose
+}
+
+// seconds rounds a duration up to whole seconds for API responses
+func seconds(d time.Duration) int {
+	return int((d + time.Second - 1) / time.Second)
+}
+
+// VerifyOTP signs the user in with a login OTP and starts a new session for
+// the client. OTPs issued for other purposes are rejected without being
+// consumed.
+func (s *AuthService) VerifyOTP(phoneNumber, otp string, purpose models.OTPPurpose, client models.ClientInfo) (*models.VerifyOTPResponse, error) {
+	purpose = defaultPurpose(purpose)
+	if purpose != models.PurposeLogin {
+		return nil, errors.ErrInvalidOTPPurpose.WithDetails(
+			fmt.Sprintf("%s OTPs cannot be used to log in", purpose),
+		)
+	}
+
+	user, isNewUser, err := s.authenticate(phoneNumber, otp)
+	if err != nil {
+		return nil, err
+	}
 
+	tokens, err := s.startSession(user, nil, nil, client, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.VerifyOTPResponse{
+		Message:      "Authentication successful",
+		Token:        tokens.Token,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresIn:    tokens.ExpiresIn,
+		User:         user.ToResponse(),
+		IsNewUser:    isNewUser,
+	}, nil
+}
+
+// authenticate consumes a login OTP and returns the user it belongs to,
+// signing up new phone numbers
+func (s *AuthService) authenticate(phoneNumber, otp string) (*models.User, bool, error) {
+	if err := s.VerifyPurposeOTP(phoneNumber, otp, models.PurposeLogin); err != nil {
+		return nil, false, err
+	}
+
+	user, err := s.userRepo.GetByPhoneNumber(phoneNumber)
 	if err != nil {
 		// User doesn't exist, create new user
 		user = models.NewUser(phoneNumber)
-		err = s.userRepo.Create(user)
-		if err != nil {
+		if err := s.userRepo.Create(user); err != nil {
+			return nil, false, err
+		}
+		return user, true, nil
+	}
+	if !user.IsActive {
+		return nil, false, errors.ErrUserDeactivated
+	}
+
+	// Update last login time
+	user.LastLoginAt = time.Now()
+	if err := s.userRepo.Update(user); err != nil {
+		return nil, false, err
+	}
+
+	return user, false, nil
+}
+
+// startSession starts a session for a signed in user and issues its first
+// tokens. oauthClient and scopes are set for sign-ins through an OAuth client.
+// authTime is when the user verified the OTP.
+func (s *AuthService) startSession(user *models.User, oauthClient *models.OAuthClient, scopes []string, client models.ClientInfo, authTime time.Time) (*models.TokenResponse, error) {
+	if oauthClient != nil {
+		client.ClientID = oauthClient.ID
+		if client.DeviceName == "" {
+			client.DeviceName = oauthClient.Name
+		}
+	}
+
+	// The session ID doubles as the refresh token family
+	session := models.NewSession(uuid.New().String(), user.ID, client, time.Now().Add(s.config.RefreshTokenTTL))
+	session.AuthTime = authTime
+	session.ACR = models.ACRPhoneOTP
+	if err := s.sessionRepo.Create(session); err != nil {
+		return nil, err
+	}
+
+	return s.issueTokens(&models.RefreshToken{
+		UserID:      user.ID,
+		PhoneNumber: user.PhoneNumber,
+		FamilyID:    session.ID,
+		ClientID:    client.ClientID,
+		Scopes:      scopes,
+	}, session)
+}
+
+// RefreshToken exchanges a refresh token for a new access token and a new
+// refresh token. Every refresh token can be used once; presenting one that
+// was already rotated revokes all tokens of its family and ends the session.
+func (s *AuthService) RefreshToken(refreshToken string, client models.ClientInfo) (*models.TokenResponse, error) {
+	return s.refresh(refreshToken, "", client)
+}
+
+// refresh rotates a refresh token issued to the given OAuth client, or to
+// first-party apps if clientID is empty
+func (s *AuthService) refresh(refreshToken, clientID string, client models.ClientInfo) (*models.TokenResponse, error) {
+	current, err := s.tokenRepo.GetRefreshToken(refreshToken)
+	if err != nil {
+		return nil, err
+	}
+
+	// Confidential clients must not be able to skip client authentication
+	// by refreshing through /auth/refresh
+	if current.ClientID != clientID {
+		return nil, errors.ErrInvalidRefreshToken.WithDetails("token was issued to another client")
+	}
+
+	if current.Rotated {
+		if err := s.endSession(current.UserID, current.FamilyID); err != nil {
 			return nil, err
 		}
-		isNewUser = true
-	} else {
-		// Update last login time
-		user.LastLoginAt = time.Now()
-		err = s.userRepo.Update(user)
-		if err != nil {
+		return nil, errors.ErrRefreshTokenReused
+	}
+
+	user, err := s.userRepo.GetByID(current.UserID)
+	if err != nil {
+		return nil, errors.ErrInvalidRefreshToken.WithDetails("user no longer exists")
+	}
+	if !user.IsActive {
+		return nil, errors.ErrUserDeactivated
+	}
+
+	newRefreshToken, err := generateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	next := &models.RefreshToken{
+		UserID:      user.ID,
+		PhoneNumber: user.PhoneNumber,
+		FamilyID:    current.FamilyID,
+		IssuedAt:    now,
+		ExpiresAt:   now.Add(s.config.RefreshTokenTTL),
+		ClientID:    current.ClientID,
+		Scopes:      current.Scopes,
+	}
+	if err := s.tokenRepo.RotateRefreshToken(refreshToken, newRefreshToken, next); err != nil {
+		// Another request rotated the token since it was read: a reuse too
+		if errors.GetDomainError(err).Code == errors.ErrRefreshTokenReused.Code {
+			if err := s.endSession(current.UserID, current.FamilyID); err != nil {
+				return nil, err
+			}
+		}
+		return nil, err
+	}
+
+	session, err := s.extendSession(user.ID, current.FamilyID, client, next.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	accessToken, err := s.generateJWT(next, session)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.TokenResponse{
+		Token:        accessToken,
+		RefreshToken: newRefreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    seconds(s.config.AccessTokenTTL),
+		Scope:        models.FormatScope(next.Scopes),
+	}, nil
+}
+
+// extendSession records client activity on a refresh and keeps the session
+// alive as long as its refresh token. Families created before sessions
+// existed get a session on their first refresh.
+func (s *AuthService) extendSession(userID, sessionID string, client models.ClientInfo, expiresAt time.Time) (*models.Session, error) {
+	session, err := s.sessionRepo.GetByID(sessionID)
+	if err != nil {
+		if errors.GetDomainError(err).Code != errors.ErrSessionNotFound.Code {
 			return nil, err
 		}
+		session = models.NewSession(sessionID, userID, client, expiresAt)
+		return session, s.sessionRepo.Create(session)
 	}
 
-	// Generate JWT token
-	token, err := s.generateJWT(user.ID, user.PhoneNumber)
+	session.IPAddress = client.IPAddress
+	session.UserAgent = client.UserAgent
+	session.LastSeenAt = time.Now()
+	session.ExpiresAt = expiresAt
+
+	return session, s.sessionRepo.Update(session)
+}
+
+// ListSessions returns the user's active sessions, flagging the one the
+// request was made with
+func (s *AuthService) ListSessions(userID, currentSessionID string) ([]*models.SessionResponse, error) {
+	sessions, err := s.sessionRepo.GetByUserID(userID)
 	if err != nil {
 		return nil, err
 	}
 
-	return &models.VerifyOTPResponse{
-		Message:   "Authentication successful",
-		Token:     token,
-		User:      user.ToResponse(),
-		IsNewUser: isNewUser,
+	responses := make([]*models.SessionResponse, len(sessions))
+	for i, session := range sessions {
+		responses[i] = session.ToResponse(currentSessionID)
+	}
+
+	return responses, nil
+}
+
+// RevokeSession ends one of the user's sessions. Its refresh tokens are
+// deleted and its access tokens are rejected from now on.
+func (s *AuthService) RevokeSession(userID, sessionID string) error {
+	session, err := s.sessionRepo.GetByID(sessionID)
+	if err != nil {
+		return err
+	}
+
+	// Do not reveal whether other users' sessions exist
+	if session.UserID != userID {
+		return errors.ErrSessionNotFound
+	}
+
+	return s.endSession(userID, sessionID)
+}
+
+func (s *AuthService) endSession(userID, sessionID string) error {
+	if err := s.tokenRepo.RevokeFamily(sessionID); err != nil {
+		return err
+	}
+
+	return s.sessionRepo.Delete(&models.Session{ID: sessionID, UserID: userID})
+}
+
+// issueTokens creates an access token and the first refresh token of a
+// family. grant describes what the tokens are issued for.
+func (s *AuthService) issueTokens(grant *models.RefreshToken, session *models.Session) (*models.TokenResponse, error) {
+	accessToken, err := s.generateJWT(grant, session)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := generateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	grant.IssuedAt = now
+	grant.ExpiresAt = now.Add(s.config.RefreshTokenTTL)
+	if err := s.tokenRepo.CreateRefreshToken(refreshToken, grant); err != nil {
+		return nil, err
+	}
+
+	return &models.TokenResponse{
+		Token:        accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    seconds(s.config.AccessTokenTTL),
+		Scope:        models.FormatScope(grant.Scopes),
+	}, nil
+}
+
+// generateOpaqueToken returns 256 random bits, base64url encoded
+func generateOpaqueToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// StepUp re-verifies the user of the session claims belong to with a login
+// OTP and issues a new access token with a fresh auth_time, for operations
+// that require a recent verification. The session and its refresh tokens
+// stay as they are, but later refreshes carry the new auth_time too.
+func (s *AuthService) StepUp(claims *models.Claims, otp string) (*models.TokenResponse, error) {
+	if claims.SessionID == "" {
+		return nil, errors.ErrInvalidToken.WithDetails("token has no session to step up")
+	}
+
+	session, err := s.sessionRepo.GetByID(claims.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	user, err := s.userRepo.GetByID(claims.UserID())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.VerifyPurposeOTP(user.PhoneNumber, otp, models.PurposeLogin); err != nil {
+		return nil, err
+	}
+
+	session.AuthTime = time.Now()
+	session.ACR = models.ACRPhoneOTP
+	if err := s.sessionRepo.Update(session); err != nil {
+		return nil, err
+	}
+
+	accessToken, err := s.generateJWT(&models.RefreshToken{
+		UserID:      user.ID,
+		PhoneNumber: user.PhoneNumber,
+		FamilyID:    session.ID,
+		ClientID:    claims.ClientID,
+		Scopes:      models.ParseScope(claims.Scope),
+	}, session)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.TokenResponse{
+		Token:     accessToken,
+		TokenType: "Bearer",
+		ExpiresIn: seconds(s.config.AccessTokenTTL),
+		Scope:     claims.Scope,
 	}, nil
 }
 
-func (s *AuthService) generateJWT(userID, phoneNumber string) (string, error) {
-	claims := jwt.MapClaims{
-		"user_id":      userID,
-		"phone_number": phoneNumber,
-		"exp":          time.Now().Add(24 * time.Hour).Unix(), // 24 hours expiry
-		"iat":          time.Now().Unix(),
+// VerifyPurposeOTP consumes an OTP issued for the given purpose. It is the
+// building block for flows other than login that need OTP confirmation.
+func (s *AuthService) VerifyPurposeOTP(phoneNumber, otp string, purpose models.OTPPurpose) error {
+	isValid, err := s.otpRepo.VerifyOTP(phoneNumber, otp, purpose)
+	if err != nil {
+		return err
+	}
+
+	if !isValid {
+		return errors.ErrInvalidOTP
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(s.jwtSecret))
+	return nil
 }
 
-func (s *AuthService) ValidateToken(tokenString string) (*jwt.Token, error) {
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+// Logout revokes the access token it is called with and, when the token
+// belongs to a session, ends that session
+func (s *AuthService) Logout(claims *models.Claims) error {
+	if err := s.revocationRepo.RevokeToken(claims.ID, claims.ExpiresAt.Time); err != nil {
+		return err
+	}
+
+	if claims.SessionID != "" {
+		if err := s.endSession(claims.UserID(), claims.SessionID); err != nil {
+			return err
 		}
-		return []byte(s.jwtSecret), nil
-	})
+	}
+
+	return nil
+}
+
+// LogoutAll revokes every access and refresh token issued to the user so far
+func (s *AuthService) LogoutAll(userID string) error {
+	// Access tokens issued now or earlier expire within AccessTokenTTL
+	if err := s.revocationRepo.RevokeUserTokens(userID, time.Now(), s.config.AccessTokenTTL); err != nil {
+		return err
+	}
+
+	if err := s.tokenRepo.RevokeUserFamilies(userID); err != nil {
+		return err
+	}
+
+	return s.sessionRepo.DeleteByUserID(userID)
+}
+
+// DeactivateAccount deactivates the user's account and signs the user out
+// everywhere. Deactivated users cannot sign in again.
+func (s *AuthService) DeactivateAccount(userID string) error {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return err
+	}
+
+	user.IsActive = false
+	if err := s.userRepo.Update(user); err != nil {
+		return err
+	}
+
+	return s.LogoutAll(userID)
+}
+
+// generateJWT signs an access token for the refresh token family (the
+// session) of grant. The session supplies auth_time and acr.
+func (s *AuthService) generateJWT(grant *models.RefreshToken, session *models.Session) (string, error) {
+	claims := &models.Claims{
+		PhoneNumber:      grant.PhoneNumber,
+		SessionID:        grant.FamilyID,
+		ClientID:         grant.ClientID,
+		Scope:            models.FormatScope(grant.Scopes),
+		RegisteredClaims: s.registeredClaims(grant.UserID),
+	}
+	if !session.AuthTime.IsZero() {
+		claims.AuthTime = jwt.NewNumericDate(session.AuthTime)
+		claims.ACR = session.ACR
+	}
+
+	return s.config.Keyring.Sign(claims)
+}
+
+// issueServiceToken issues an access token to a machine client acting on
+// its own behalf. It has no session and no refresh token; the client
+// authenticates again once it expires.
+func (s *AuthService) issueServiceToken(client *models.OAuthClient, scopes []string) (*models.TokenResponse, error) {
+	claims := &models.Claims{
+		PrincipalType:    models.PrincipalService,
+		ClientID:         client.ID,
+		Scope:            models.FormatScope(scopes),
+		RegisteredClaims: s.registeredClaims(client.ID),
+	}
+
+	accessToken, err := s.config.Keyring.Sign(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.TokenResponse{
+		Token:     accessToken,
+		TokenType: "Bearer",
+		ExpiresIn: seconds(s.config.AccessTokenTTL),
+		Scope:     claims.Scope,
+	}, nil
+}
+
+// registeredClaims returns the standard claims of a new access token
+func (s *AuthService) registeredClaims(subject string) jwt.RegisteredClaims {
+	now := time.Now()
+	return jwt.RegisteredClaims{
+		ID:        uuid.New().String(),
+		Issuer:    s.config.Issuer,
+		Subject:   subject,
+		Audience:  s.config.Audiences,
+		IssuedAt:  jwt.NewNumericDate(now),
+		NotBefore: jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(s.config.AccessTokenTTL)),
+	}
+}
+
+// JWKS returns the public keys tokens can be verified with. It is empty
+// when tokens are signed with a shared secret.
+func (s *AuthService) JWKS() *signing.JWKS {
+	return s.config.Keyring.JWKS()
+}
+
+// ValidateToken verifies an access token's signature, issuer, audience and
+// lifetime, and that it was neither revoked nor issued for an ended session
+func (s *AuthService) ValidateToken(tokenString string) (*models.Claims, error) {
+	claims, err := s.parseClaims(tokenString)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.checkRevoked(claims); err != nil {
+		return nil, err
+	}
+
+	if err := s.checkSession(claims); err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
+
+// parseClaims checks everything about a token that its content decides
+func (s *AuthService) parseClaims(tokenString string) (*models.Claims, error) {
+	claims := &models.Claims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, s.config.Keyring.Keyfunc,
+		jwt.WithIssuer(s.config.Issuer),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+	)
 
 	if err != nil {
 		return nil, errors.ErrInvalidToken.WithDetails(err.Error())
@@ -112,5 +617,64 @@ func (s *AuthService) ValidateToken(tokenString string) (*jwt.Token, error) {
 		return nil, errors.ErrInvalidToken
 	}
 
-	return token, nil
+	if !s.acceptsAudience(claims.Audience) {
+		return nil, errors.ErrInvalidToken.WithDetails("token is not intended for this service")
+	}
+
+	return claims, nil
+}
+
+// acceptsAudience reports whether the audience names one of ours
+func (s *AuthService) acceptsAudience(audience jwt.ClaimStrings) bool {
+	for _, aud := range audience {
+		for _, accepted := range s.config.Audiences {
+			if aud == accepted {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// sessionTouchInterval limits how often requests update a session's last
+// seen time
+const sessionTouchInterval = time.Minute
+
+// checkSession rejects tokens whose session was revoked and records the
+// session as seen
+func (s *AuthService) checkSession(claims *models.Claims) error {
+	// Tokens issued before sessions existed carry no sid
+	if claims.SessionID == "" {
+		return nil
+	}
+
+	session, err := s.sessionRepo.GetByID(claims.SessionID)
+	if err != nil {
+		if errors.GetDomainError(err).Code == errors.ErrSessionNotFound.Code {
+			return errors.ErrTokenRevoked.WithDetails("session has been revoked")
+		}
+		return err
+	}
+
+	if time.Since(session.LastSeenAt) >= sessionTouchInterval {
+		session.LastSeenAt = time.Now()
+		if err := s.sessionRepo.Update(session); err != nil && errors.GetDomainError(err).Code != errors.ErrSessionNotFound.Code {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkRevoked rejects tokens that were logged out before they expired
+func (s *AuthService) checkRevoked(claims *models.Claims) error {
+	revoked, err := s.revocationRepo.IsRevoked(claims.ID, claims.UserID(), claims.IssuedAt.Time)
+	if err != nil {
+		return err
+	}
+	if revoked {
+		return errors.ErrTokenRevoked
+	}
+
+	return nil
 }
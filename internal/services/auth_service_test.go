@@ -0,0 +1,414 @@
+package services
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"otp-auth-service/internal/config"
+	"otp-auth-service/internal/delivery"
+	"otp-auth-service/internal/errors"
+	"otp-auth-service/internal/models"
+	"otp-auth-service/internal/otp"
+	"otp-auth-service/internal/repository"
+	"otp-auth-service/internal/signing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+const testPhoneNumber = "+1234567890"
+
+type testAuthService struct {
+	*AuthService
+	redis  *redis.Client
+	outbox string
+}
+
+func newTestAuthConfig(t *testing.T) AuthConfig {
+	t.Helper()
+
+	key, err := signing.NewHMACKey("test-secret")
+	if err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	keyring, err := signing.NewStaticKeyring(key)
+	if err != nil {
+		t.Fatalf("Failed to create keyring: %v", err)
+	}
+
+	return AuthConfig{
+		Keyring:         keyring,
+		Issuer:          "https://auth.example.com",
+		Audiences:       []string{"api"},
+		AccessTokenTTL:  15 * time.Minute,
+		RefreshTokenTTL: time.Hour,
+	}
+}
+
+func newTestAuthService(t *testing.T, authConfig AuthConfig) *testAuthService {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	generator, err := otp.NewGenerator(6, otp.AlphabetNumeric)
+	if err != nil {
+		t.Fatalf("Failed to create generator: %v", err)
+	}
+	sealer, err := otp.NewSealer("test-secret")
+	if err != nil {
+		t.Fatalf("Failed to create sealer: %v", err)
+	}
+
+	policies := make(map[models.OTPPurpose]config.OTPPolicy)
+	for _, purpose := range models.OTPPurposes {
+		policies[purpose] = config.DefaultOTPPolicy()
+	}
+
+	outbox := filepath.Join(t.TempDir(), "outbox.log")
+	service := NewAuthService(
+		repository.NewUserRepository(),
+		repository.NewOTPRepository(client, generator, otp.NewHasher("test-secret"), sealer, policies),
+		repository.NewTokenRepository(client),
+		repository.NewRevocationRepository(client),
+		repository.NewSessionRepository(client),
+		delivery.NewFileSender(outbox),
+		authConfig,
+	)
+
+	return &testAuthService{AuthService: service, redis: client, outbox: outbox}
+}
+
+// lastOTP returns the code of the most recently delivered OTP
+func (s *testAuthService) lastOTP(t *testing.T) string {
+	t.Helper()
+
+	records, err := delivery.ReadFile(s.outbox)
+	if err != nil || len(records) == 0 {
+		t.Fatalf("Expected OTP to be delivered, got %v", err)
+	}
+	body := records[len(records)-1].Body
+	return body[len(body)-6:]
+}
+
+// login signs the test user in through the OTP flow
+func (s *testAuthService) login(t *testing.T) *models.VerifyOTPResponse {
+	t.Helper()
+
+	if _, err := s.RequestOTP(testPhoneNumber, models.PurposeLogin); err != nil {
+		t.Fatalf("Failed to request OTP: %v", err)
+	}
+
+	response, err := s.VerifyOTP(testPhoneNumber, s.lastOTP(t), models.PurposeLogin, models.ClientInfo{
+		DeviceName: "Pixel 8",
+		IPAddress:  "203.0.113.7",
+		UserAgent:  "app/1.0",
+	})
+	if err != nil {
+		t.Fatalf("Failed to verify OTP: %v", err)
+	}
+
+	return response
+}
+
+func assertErrorCode(t *testing.T, err error, code string) {
+	t.Helper()
+
+	if err == nil {
+		t.Fatalf("Expected %s, got no error", code)
+	}
+	if got := errors.GetDomainError(err).Code; got != code {
+		t.Errorf("Expected error code %s, got %s (%v)", code, got, err)
+	}
+}
+
+func TestAuthService_ValidateToken(t *testing.T) {
+	service := newTestAuthService(t, newTestAuthConfig(t))
+	response := service.login(t)
+
+	claims, err := service.ValidateToken(response.Token)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if claims.UserID() != response.User.ID {
+		t.Errorf("Expected subject %s, got %s", response.User.ID, claims.UserID())
+	}
+	if claims.PhoneNumber != testPhoneNumber {
+		t.Errorf("Expected phone number %s, got %s", testPhoneNumber, claims.PhoneNumber)
+	}
+	if claims.Issuer != "https://auth.example.com" {
+		t.Errorf("Expected issuer to be set, got %s", claims.Issuer)
+	}
+	if claims.ID == "" || claims.SessionID == "" || claims.NotBefore == nil {
+		t.Errorf("Expected jti, sid and nbf to be set, got %+v", claims)
+	}
+}
+
+func TestAuthService_ValidateToken_IssuerAndAudience(t *testing.T) {
+	authConfig := newTestAuthConfig(t)
+	service := newTestAuthService(t, authConfig)
+	token := service.login(t).Token
+
+	tests := []struct {
+		name      string
+		issuer    string
+		audiences []string
+		wantErr   bool
+	}{
+		{name: "same issuer and audience", issuer: authConfig.Issuer, audiences: []string{"api"}},
+		{name: "one of several audiences", issuer: authConfig.Issuer, audiences: []string{"billing", "api"}},
+		{name: "other issuer", issuer: "https://evil.example.com", audiences: []string{"api"}, wantErr: true},
+		{name: "other audience", issuer: authConfig.Issuer, audiences: []string{"billing"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verifierConfig := authConfig
+			verifierConfig.Issuer = tt.issuer
+			verifierConfig.Audiences = tt.audiences
+
+			verifier := &AuthService{config: verifierConfig}
+			_, err := verifier.parseClaims(token)
+			if tt.wantErr {
+				assertErrorCode(t, err, "INVALID_TOKEN")
+			} else if err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestAuthService_RefreshToken(t *testing.T) {
+	service := newTestAuthService(t, newTestAuthConfig(t))
+	response := service.login(t)
+
+	refreshed, err := service.RefreshToken(response.RefreshToken, models.ClientInfo{IPAddress: "198.51.100.1"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if refreshed.RefreshToken == response.RefreshToken {
+		t.Error("Expected a new refresh token")
+	}
+	if _, err := service.ValidateToken(refreshed.Token); err != nil {
+		t.Errorf("Expected refreshed access token to be valid, got %v", err)
+	}
+
+	// Reusing the old refresh token ends the session
+	_, err = service.RefreshToken(response.RefreshToken, models.ClientInfo{})
+	assertErrorCode(t, err, "REFRESH_TOKEN_REUSED")
+
+	_, err = service.RefreshToken(refreshed.RefreshToken, models.ClientInfo{})
+	assertErrorCode(t, err, "INVALID_REFRESH_TOKEN")
+
+	_, err = service.ValidateToken(refreshed.Token)
+	assertErrorCode(t, err, "TOKEN_REVOKED")
+}
+
+// staleTokenRepository reads refresh tokens as they were before a
+// concurrent rotation
+type staleTokenRepository struct {
+	repository.TokenRepository
+	token *models.RefreshToken
+}
+
+func (r staleTokenRepository) GetRefreshToken(string) (*models.RefreshToken, error) {
+	return r.token, nil
+}
+
+func TestAuthService_RefreshTokenRace(t *testing.T) {
+	service := newTestAuthService(t, newTestAuthConfig(t))
+	response := service.login(t)
+
+	// Both requests read the token before either rotates it
+	current, err := service.tokenRepo.GetRefreshToken(response.RefreshToken)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	refreshed, err := service.RefreshToken(response.RefreshToken, models.ClientInfo{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	service.tokenRepo = staleTokenRepository{TokenRepository: service.tokenRepo, token: current}
+
+	// The second rotation fails and ends the session like any reuse
+	_, err = service.RefreshToken(response.RefreshToken, models.ClientInfo{})
+	assertErrorCode(t, err, "REFRESH_TOKEN_REUSED")
+
+	service.tokenRepo = service.tokenRepo.(staleTokenRepository).TokenRepository
+	_, err = service.RefreshToken(refreshed.RefreshToken, models.ClientInfo{})
+	assertErrorCode(t, err, "INVALID_REFRESH_TOKEN")
+	_, err = service.ValidateToken(refreshed.Token)
+	assertErrorCode(t, err, "TOKEN_REVOKED")
+}
+
+func TestAuthService_Logout(t *testing.T) {
+	service := newTestAuthService(t, newTestAuthConfig(t))
+	first := service.login(t)
+	second := service.login(t)
+
+	claims, err := service.ValidateToken(first.Token)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := service.Logout(claims); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	_, err = service.ValidateToken(first.Token)
+	assertErrorCode(t, err, "TOKEN_REVOKED")
+	_, err = service.RefreshToken(first.RefreshToken, models.ClientInfo{})
+	assertErrorCode(t, err, "INVALID_REFRESH_TOKEN")
+
+	// Other sessions are unaffected
+	if _, err := service.ValidateToken(second.Token); err != nil {
+		t.Errorf("Expected other session to stay valid, got %v", err)
+	}
+}
+
+func TestAuthService_LogoutAll(t *testing.T) {
+	service := newTestAuthService(t, newTestAuthConfig(t))
+	first := service.login(t)
+	second := service.login(t)
+
+	if err := service.LogoutAll(first.User.ID); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	for _, response := range []*models.VerifyOTPResponse{first, second} {
+		_, err := service.ValidateToken(response.Token)
+		assertErrorCode(t, err, "TOKEN_REVOKED")
+		_, err = service.RefreshToken(response.RefreshToken, models.ClientInfo{})
+		assertErrorCode(t, err, "INVALID_REFRESH_TOKEN")
+	}
+}
+
+func TestAuthService_Sessions(t *testing.T) {
+	service := newTestAuthService(t, newTestAuthConfig(t))
+	first := service.login(t)
+	second := service.login(t)
+
+	claims, _ := service.ValidateToken(second.Token)
+	sessions, err := service.ListSessions(claims.UserID(), claims.SessionID)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(sessions) != 2 {
+		t.Fatalf("Expected 2 sessions, got %d", len(sessions))
+	}
+	current := 0
+	for _, session := range sessions {
+		if session.Current {
+			current++
+		}
+		if session.DeviceName != "Pixel 8" || session.IPAddress != "203.0.113.7" {
+			t.Errorf("Unexpected session: %+v", session)
+		}
+	}
+	if current != 1 {
+		t.Errorf("Expected exactly one current session, got %d", current)
+	}
+
+	firstClaims, _ := service.ValidateToken(first.Token)
+
+	// Users cannot revoke other users' sessions
+	err = service.RevokeSession("someone-else", firstClaims.SessionID)
+	assertErrorCode(t, err, "SESSION_NOT_FOUND")
+
+	if err := service.RevokeSession(claims.UserID(), firstClaims.SessionID); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	_, err = service.ValidateToken(first.Token)
+	assertErrorCode(t, err, "TOKEN_REVOKED")
+	if _, err := service.ValidateToken(second.Token); err != nil {
+		t.Errorf("Expected other session to stay valid, got %v", err)
+	}
+}
+
+func TestAuthService_StepUp(t *testing.T) {
+	service := newTestAuthService(t, newTestAuthConfig(t))
+	response := service.login(t)
+
+	claims, err := service.ValidateToken(response.Token)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !claims.AuthenticatedWithin(time.Minute) || claims.ACR != models.ACRPhoneOTP {
+		t.Fatalf("Expected fresh auth_time and acr, got %v %q", claims.AuthTime, claims.ACR)
+	}
+
+	// Refreshing does not renew auth_time
+	session, err := service.sessionRepo.GetByID(claims.SessionID)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	session.AuthTime = time.Now().Add(-time.Hour)
+	if err := service.sessionRepo.Update(session); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	refreshed, err := service.RefreshToken(response.RefreshToken, models.ClientInfo{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	stale, err := service.ValidateToken(refreshed.Token)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if stale.AuthenticatedWithin(5*time.Minute) || !stale.AuthenticatedWithin(2*time.Hour) {
+		t.Errorf("Expected auth_time of the login, got %v", stale.AuthTime)
+	}
+
+	if _, err := service.RequestOTP(testPhoneNumber, models.PurposeLogin); err != nil {
+		t.Fatalf("Failed to request OTP: %v", err)
+	}
+	_, err = service.StepUp(stale, "000000")
+	assertErrorCode(t, err, "INVALID_OTP")
+
+	if _, err := service.RequestOTP(testPhoneNumber, models.PurposeLogin); err != nil {
+		t.Fatalf("Failed to request OTP: %v", err)
+	}
+	stepped, err := service.StepUp(stale, service.lastOTP(t))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if stepped.RefreshToken != "" {
+		t.Error("Expected the session to keep its refresh token")
+	}
+	fresh, err := service.ValidateToken(stepped.Token)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if fresh.SessionID != stale.SessionID || !fresh.AuthenticatedWithin(time.Minute) {
+		t.Errorf("Expected fresh token of the same session, got %+v", fresh)
+	}
+
+	// Later refreshes carry the new auth_time
+	refreshed, err = service.RefreshToken(refreshed.RefreshToken, models.ClientInfo{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if claims, err := service.ValidateToken(refreshed.Token); err != nil || !claims.AuthenticatedWithin(time.Minute) {
+		t.Errorf("Expected refreshed token to keep the step-up, got %v (%v)", claims, err)
+	}
+}
+
+// failingSender fails like a gateway that echoes its request URL
+type failingSender struct{}
+
+func (failingSender) Send(*delivery.Message) error {
+	return fmt.Errorf("POST https://sms.example.com/send?api_key=s3cret: 502 Bad Gateway")
+}
+
+func TestAuthService_DeliveryFailure(t *testing.T) {
+	service := newTestAuthService(t, newTestAuthConfig(t))
+	service.sender = failingSender{}
+
+	_, err := service.RequestOTP(testPhoneNumber, models.PurposeLogin)
+	assertErrorCode(t, err, "OTP_DELIVERY_FAILED")
+	if details := errors.GetDomainError(err).Details; details != "" {
+		t.Errorf("Expected no details for callers, got %q", details)
+	}
+}
@@ -0,0 +1,125 @@
+package services
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"time"
+
+	"otp-auth-service/internal/errors"
+	"otp-auth-service/internal/models"
+	"otp-auth-service/internal/signing"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// maxAssertionLifetime bounds how far ahead a client assertion may expire,
+// and so how long its jti has to be remembered
+const maxAssertionLifetime = 5 * time.Minute
+
+// clientCredentials issues an access token to a machine client acting on
+// its own behalf (RFC 6749 section 4.4). Requests without a scope get all
+// scopes of the client.
+func (s *OAuthService) clientCredentials(client *models.OAuthClient, scope string) (*models.OAuthTokenResponse, error) {
+	if client.IsPublic() {
+		return nil, errors.ErrOAuthUnauthorizedClient.WithDescription("public clients cannot use client_credentials")
+	}
+
+	scopes := models.ParseScope(scope)
+	if len(scopes) == 0 {
+		scopes = client.Scopes
+	}
+	if !client.AllowsScopes(scopes) {
+		return nil, errors.ErrOAuthInvalidScope.WithDescription("scope is not allowed for this client")
+	}
+
+	tokens, err := s.authService.issueServiceToken(client, scopes)
+	if err != nil {
+		return nil, err
+	}
+
+	return oauthTokenResponse(tokens), nil
+}
+
+// authenticateAssertion authenticates a client by a JWT signed with its
+// private key (private_key_jwt, RFC 7523 section 2.2). The assertion names
+// the client as issuer and subject, and each assertion can be used once.
+func (s *OAuthService) authenticateAssertion(credentials models.ClientCredentials) (*models.OAuthClient, error) {
+	if credentials.ClientAssertionType != models.ClientAssertionTypeJWTBearer {
+		return nil, errors.ErrOAuthInvalidClient.WithDescription("unsupported client_assertion_type")
+	}
+
+	// The key to verify with depends on the client the assertion names
+	unverified := &jwt.RegisteredClaims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(credentials.ClientAssertion, unverified); err != nil {
+		return nil, errors.ErrOAuthInvalidClient.WithDescription("malformed client_assertion")
+	}
+	if credentials.ClientID != "" && credentials.ClientID != unverified.Subject {
+		return nil, errors.ErrOAuthInvalidClient.WithDescription("client_id does not match client_assertion")
+	}
+
+	client, err := s.clientRepo.GetByID(unverified.Subject)
+	if err != nil {
+		return nil, errors.ErrOAuthInvalidClient.WithDescription("unknown client")
+	}
+	if client.PublicKey == nil {
+		return nil, errors.ErrOAuthInvalidClient.WithDescription("client has no public key")
+	}
+
+	claims := &jwt.RegisteredClaims{}
+	_, err = jwt.ParseWithClaims(credentials.ClientAssertion, claims,
+		func(*jwt.Token) (interface{}, error) { return client.PublicKey, nil },
+		jwt.WithValidMethods(assertionMethods(client.PublicKey)),
+		jwt.WithIssuer(client.ID),
+		jwt.WithSubject(client.ID),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return nil, errors.ErrOAuthInvalidClient.WithDescription("invalid client_assertion: " + err.Error())
+	}
+
+	if !s.acceptsAssertionAudience(claims.Audience) {
+		return nil, errors.ErrOAuthInvalidClient.WithDescription("client_assertion is not intended for this server")
+	}
+	if claims.ID == "" {
+		return nil, errors.ErrOAuthInvalidClient.WithDescription("client_assertion has no jti")
+	}
+	if time.Until(claims.ExpiresAt.Time) > maxAssertionLifetime {
+		return nil, errors.ErrOAuthInvalidClient.WithDescription("client_assertion expires too far in the future")
+	}
+
+	fresh, err := s.assertionRepo.MarkUsed(client.ID, claims.ID, claims.ExpiresAt.Time)
+	if err != nil {
+		return nil, err
+	}
+	if !fresh {
+		return nil, errors.ErrOAuthInvalidClient.WithDescription("client_assertion was already used")
+	}
+
+	return client, nil
+}
+
+// acceptsAssertionAudience reports whether an assertion is addressed to
+// this server: its issuer or its token endpoint
+func (s *OAuthService) acceptsAssertionAudience(audience jwt.ClaimStrings) bool {
+	for _, aud := range audience {
+		if aud == s.config.Issuer || aud == s.endpoint("/oauth/token") {
+			return true
+		}
+	}
+	return false
+}
+
+// assertionMethods returns the signing algorithm matching a client's key
+func assertionMethods(key crypto.PublicKey) []string {
+	switch key.(type) {
+	case *rsa.PublicKey:
+		return []string{signing.AlgorithmRS256}
+	case *ecdsa.PublicKey:
+		return []string{signing.AlgorithmES256}
+	case ed25519.PublicKey:
+		return []string{signing.AlgorithmEdDSA}
+	}
+	return nil
+}
@@ -0,0 +1,191 @@
+package services
+
+import (
+	"crypto/ed25519"
+	"testing"
+	"time"
+
+	"otp-auth-service/internal/models"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// clientAssertion signs a private_key_jwt assertion for the signer client
+func clientAssertion(t *testing.T, key ed25519.PrivateKey, modify func(claims *jwt.RegisteredClaims)) string {
+	t.Helper()
+
+	claims := &jwt.RegisteredClaims{
+		ID:        uuid.New().String(),
+		Issuer:    "signer",
+		Subject:   "signer",
+		Audience:  jwt.ClaimStrings{"https://auth.example.com/api/v1/oauth/token"},
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+	}
+	if modify != nil {
+		modify(claims)
+	}
+
+	assertion, err := jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims).SignedString(key)
+	if err != nil {
+		t.Fatalf("Failed to sign assertion: %v", err)
+	}
+	return assertion
+}
+
+func TestOAuthService_ClientCredentials(t *testing.T) {
+	service := newTestOAuthService(t)
+
+	tokens, err := service.Token(&models.TokenRequest{
+		GrantType:    models.GrantTypeClientCredentials,
+		ClientID:     "batch",
+		ClientSecret: testClientSecret,
+	}, models.ClientInfo{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if tokens.RefreshToken != "" || tokens.IDToken != "" {
+		t.Errorf("Expected an access token only, got %+v", tokens)
+	}
+	if tokens.Scope != "users:read reports" {
+		t.Errorf("Expected all scopes of the client, got %q", tokens.Scope)
+	}
+
+	claims, err := service.auth.ValidateToken(tokens.AccessToken)
+	if err != nil {
+		t.Fatalf("Expected valid token, got %v", err)
+	}
+	if !claims.IsService() || claims.Subject != "batch" || claims.ClientID != "batch" {
+		t.Errorf("Expected service principal claims, got %+v", claims)
+	}
+	if claims.PhoneNumber != "" || claims.SessionID != "" {
+		t.Errorf("Expected no user claims, got %+v", claims)
+	}
+
+	narrowed, err := service.Token(&models.TokenRequest{
+		GrantType:    models.GrantTypeClientCredentials,
+		Scope:        "users:read",
+		ClientID:     "batch",
+		ClientSecret: testClientSecret,
+	}, models.ClientInfo{})
+	if err != nil || narrowed.Scope != "users:read" {
+		t.Errorf("Expected narrowed scope, got %+v (%v)", narrowed, err)
+	}
+}
+
+func TestOAuthService_ClientCredentialsRejected(t *testing.T) {
+	service := newTestOAuthService(t)
+
+	tests := []struct {
+		name     string
+		req      *models.TokenRequest
+		wantCode string
+	}{
+		{
+			name:     "wrong secret",
+			req:      &models.TokenRequest{GrantType: models.GrantTypeClientCredentials, ClientID: "batch", ClientSecret: "wrong"},
+			wantCode: "invalid_client",
+		},
+		{
+			name:     "scope not allowed",
+			req:      &models.TokenRequest{GrantType: models.GrantTypeClientCredentials, Scope: "admin", ClientID: "batch", ClientSecret: testClientSecret},
+			wantCode: "invalid_scope",
+		},
+		{
+			name:     "public client",
+			req:      &models.TokenRequest{GrantType: models.GrantTypeClientCredentials, ClientID: "spa"},
+			wantCode: "unauthorized_client",
+		},
+		{
+			name:     "grant not registered",
+			req:      &models.TokenRequest{GrantType: models.GrantTypeClientCredentials, ClientID: "backend", ClientSecret: testClientSecret},
+			wantCode: "unauthorized_client",
+		},
+		{
+			name:     "machine client refreshing",
+			req:      &models.TokenRequest{GrantType: models.GrantTypeRefreshToken, RefreshToken: "token", ClientID: "batch", ClientSecret: testClientSecret},
+			wantCode: "unauthorized_client",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := service.Token(tt.req, models.ClientInfo{})
+			assertOAuthError(t, err, tt.wantCode)
+		})
+	}
+
+	// Machine clients cannot send users through the authorization flow
+	_, err := service.ValidateClient("batch", testRedirectURI)
+	assertOAuthError(t, err, "unauthorized_client")
+}
+
+func TestOAuthService_PrivateKeyJWT(t *testing.T) {
+	service := newTestOAuthService(t)
+
+	token := func(clientID, assertion string) error {
+		_, err := service.Token(&models.TokenRequest{
+			GrantType:           models.GrantTypeClientCredentials,
+			ClientID:            clientID,
+			ClientAssertionType: models.ClientAssertionTypeJWTBearer,
+			ClientAssertion:     assertion,
+		}, models.ClientInfo{})
+		return err
+	}
+
+	assertion := clientAssertion(t, service.signerKey, nil)
+	if err := token("", assertion); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// Assertions can be used once
+	assertOAuthError(t, token("", assertion), "invalid_client")
+
+	// The issuer is accepted as audience too
+	if err := token("signer", clientAssertion(t, service.signerKey, func(c *jwt.RegisteredClaims) {
+		c.Audience = jwt.ClaimStrings{"https://auth.example.com"}
+	})); err != nil {
+		t.Errorf("Expected issuer audience to be accepted, got %v", err)
+	}
+
+	_, otherKey, _ := ed25519.GenerateKey(nil)
+	tests := []struct {
+		name      string
+		clientID  string
+		assertion string
+	}{
+		{name: "signed with another key", assertion: clientAssertion(t, otherKey, nil)},
+		{name: "client_id mismatch", clientID: "batch", assertion: clientAssertion(t, service.signerKey, nil)},
+		{name: "client without key", assertion: clientAssertion(t, service.signerKey, func(c *jwt.RegisteredClaims) {
+			c.Issuer, c.Subject = "batch", "batch"
+		})},
+		{name: "issuer is not the client", assertion: clientAssertion(t, service.signerKey, func(c *jwt.RegisteredClaims) { c.Issuer = "someone" })},
+		{name: "wrong audience", assertion: clientAssertion(t, service.signerKey, func(c *jwt.RegisteredClaims) {
+			c.Audience = jwt.ClaimStrings{"https://other.example.com"}
+		})},
+		{name: "expired", assertion: clientAssertion(t, service.signerKey, func(c *jwt.RegisteredClaims) {
+			c.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+		})},
+		{name: "no expiry", assertion: clientAssertion(t, service.signerKey, func(c *jwt.RegisteredClaims) { c.ExpiresAt = nil })},
+		{name: "too long lived", assertion: clientAssertion(t, service.signerKey, func(c *jwt.RegisteredClaims) {
+			c.ExpiresAt = jwt.NewNumericDate(time.Now().Add(time.Hour))
+		})},
+		{name: "no jti", assertion: clientAssertion(t, service.signerKey, func(c *jwt.RegisteredClaims) { c.ID = "" })},
+		{name: "malformed", assertion: "not-a-jwt"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertOAuthError(t, token(tt.clientID, tt.assertion), "invalid_client")
+		})
+	}
+
+	// A secret and an assertion together are ambiguous
+	_, err := service.Token(&models.TokenRequest{
+		GrantType:           models.GrantTypeClientCredentials,
+		ClientSecret:        testClientSecret,
+		ClientAssertionType: models.ClientAssertionTypeJWTBearer,
+		ClientAssertion:     clientAssertion(t, service.signerKey, nil),
+	}, models.ClientInfo{})
+	assertOAuthError(t, err, "invalid_request")
+}
@@ -0,0 +1,201 @@
+package services
+
+import (
+	"net/http"
+	"time"
+
+	"otp-auth-service/internal/errors"
+	"otp-auth-service/internal/models"
+)
+
+// Introspect reports whether a token is active and what it grants (RFC
+// 7662), for services that cannot verify tokens themselves. Only
+// confidential clients may introspect. Access tokens of any client can be
+// inspected, refresh tokens only by the client they were issued to.
+func (s *OAuthService) Introspect(req *models.IntrospectionRequest) (*models.IntrospectionResponse, error) {
+	client, err := s.authenticateClient(models.ClientCredentials{
+		ClientID:            req.ClientID,
+		ClientSecret:        req.ClientSecret,
+		ClientAssertionType: req.ClientAssertionType,
+		ClientAssertion:     req.ClientAssertion,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if client.IsPublic() {
+		return nil, errors.ErrOAuthUnauthorizedClient.WithDescription("public clients cannot introspect tokens")
+	}
+	if req.Token == "" {
+		return nil, errors.ErrOAuthInvalidRequest.WithDescription("token is required")
+	}
+
+	for _, tokenType := range tokenTypes(req.TokenTypeHint) {
+		var response *models.IntrospectionResponse
+		if tokenType == models.TokenTypeHintAccessToken {
+			response, err = s.introspectAccessToken(req.Token)
+		} else {
+			response, err = s.introspectRefreshToken(client, req.Token)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if response != nil {
+			return response, nil
+		}
+	}
+
+	return &models.IntrospectionResponse{Active: false}, nil
+}
+
+// introspectAccessToken describes an active access token, or returns nil
+// if token is not one
+func (s *OAuthService) introspectAccessToken(token string) (*models.IntrospectionResponse, error) {
+	claims, err := s.authService.ValidateToken(token)
+	if err != nil {
+		if isServerError(err) {
+			return nil, err
+		}
+		return nil, nil
+	}
+
+	response := &models.IntrospectionResponse{
+		Active:        true,
+		Scope:         claims.Scope,
+		ClientID:      claims.ClientID,
+		PrincipalType: claims.Principal(),
+		TokenType:     "Bearer",
+		ExpiresAt:     claims.ExpiresAt.Unix(),
+		IssuedAt:      claims.IssuedAt.Unix(),
+		Subject:       claims.Subject,
+		Audience:      claims.Audience,
+		Issuer:        claims.Issuer,
+		TokenID:       claims.ID,
+		SessionID:     claims.SessionID,
+		PhoneNumber:   claims.PhoneNumber,
+	}
+	if claims.NotBefore != nil {
+		response.NotBefore = claims.NotBefore.Unix()
+	}
+	if claims.AuthTime != nil {
+		response.AuthTime = claims.AuthTime.Unix()
+		response.ACR = claims.ACR
+	}
+
+	return response, nil
+}
+
+// introspectRefreshToken describes an active refresh token of client, or
+// returns nil if token is not one
+func (s *OAuthService) introspectRefreshToken(client *models.OAuthClient, token string) (*models.IntrospectionResponse, error) {
+	grant, err := s.findRefreshToken(client, token)
+	if err != nil || grant == nil {
+		return nil, err
+	}
+	if grant.Rotated || time.Now().After(grant.ExpiresAt) {
+		return nil, nil
+	}
+
+	return &models.IntrospectionResponse{
+		Active:        true,
+		Scope:         models.FormatScope(grant.Scopes),
+		ClientID:      grant.ClientID,
+		PrincipalType: models.PrincipalUser,
+		ExpiresAt:     grant.ExpiresAt.Unix(),
+		IssuedAt:      grant.IssuedAt.Unix(),
+		Subject:       grant.UserID,
+		Issuer:        s.config.Issuer,
+		SessionID:     grant.FamilyID,
+		PhoneNumber:   grant.PhoneNumber,
+	}, nil
+}
+
+// Revoke invalidates a token issued to the calling client (RFC 7009).
+// Revoking a refresh token ends its session, which also rejects the
+// session's access tokens. Unknown tokens and tokens of other clients are
+// ignored, so the response does not reveal whether a token exists.
+func (s *OAuthService) Revoke(req *models.RevocationRequest) error {
+	client, err := s.authenticateClient(models.ClientCredentials{
+		ClientID:            req.ClientID,
+		ClientSecret:        req.ClientSecret,
+		ClientAssertionType: req.ClientAssertionType,
+		ClientAssertion:     req.ClientAssertion,
+	})
+	if err != nil {
+		return err
+	}
+	if req.Token == "" {
+		return errors.ErrOAuthInvalidRequest.WithDescription("token is required")
+	}
+
+	for _, tokenType := range tokenTypes(req.TokenTypeHint) {
+		var revoked bool
+		if tokenType == models.TokenTypeHintAccessToken {
+			revoked, err = s.revokeAccessToken(client, req.Token)
+		} else {
+			revoked, err = s.revokeRefreshToken(client, req.Token)
+		}
+		if err != nil || revoked {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// revokeAccessToken adds an access token of client to the revocation list
+func (s *OAuthService) revokeAccessToken(client *models.OAuthClient, token string) (bool, error) {
+	claims, err := s.authService.ValidateToken(token)
+	if err != nil {
+		if isServerError(err) {
+			return false, err
+		}
+		return false, nil
+	}
+	if claims.ClientID != client.ID {
+		return false, nil
+	}
+
+	return true, s.authService.revocationRepo.RevokeToken(claims.ID, claims.ExpiresAt.Time)
+}
+
+// revokeRefreshToken ends the session of a refresh token of client
+func (s *OAuthService) revokeRefreshToken(client *models.OAuthClient, token string) (bool, error) {
+	grant, err := s.findRefreshToken(client, token)
+	if err != nil || grant == nil {
+		return false, err
+	}
+
+	return true, s.authService.endSession(grant.UserID, grant.FamilyID)
+}
+
+// findRefreshToken looks up a refresh token issued to client. It returns
+// nil if the token is unknown or belongs to someone else.
+func (s *OAuthService) findRefreshToken(client *models.OAuthClient, token string) (*models.RefreshToken, error) {
+	grant, err := s.authService.tokenRepo.GetRefreshToken(token)
+	if err != nil {
+		if isServerError(err) {
+			return nil, err
+		}
+		return nil, nil
+	}
+	if grant.ClientID != client.ID {
+		return nil, nil
+	}
+
+	return grant, nil
+}
+
+// tokenTypes lists the token types to look a token up as. The hint only
+// decides the order: a token is found even if the hint is wrong.
+func tokenTypes(hint string) []string {
+	if hint == models.TokenTypeHintRefreshToken {
+		return []string{models.TokenTypeHintRefreshToken, models.TokenTypeHintAccessToken}
+	}
+	return []string{models.TokenTypeHintAccessToken, models.TokenTypeHintRefreshToken}
+}
+
+// isServerError reports whether err is a failure of the service rather
+// than a problem with the token
+func isServerError(err error) bool {
+	return errors.GetDomainError(err).HTTPStatus >= http.StatusInternalServerError
+}
@@ -0,0 +1,162 @@
+package services
+
+import (
+	"testing"
+
+	"otp-auth-service/internal/models"
+)
+
+// confidentialTokens signs the test user in to the backend client
+func (s *testOAuthService) confidentialTokens(t *testing.T) *models.OAuthTokenResponse {
+	t.Helper()
+
+	req := newSPARequest()
+	req.ClientID, req.CodeChallenge, req.CodeChallengeMethod = "backend", "", ""
+	response := s.authorize(t, req)
+	response, err := s.Consent(response.RequestID, true, testBrowser)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	tokens, err := s.Token(&models.TokenRequest{
+		GrantType:    models.GrantTypeAuthorizationCode,
+		Code:         codeFrom(t, response.RedirectTo),
+		RedirectURI:  testRedirectURI,
+		ClientID:     "backend",
+		ClientSecret: testClientSecret,
+	}, models.ClientInfo{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	return tokens
+}
+
+func TestOAuthService_Introspect(t *testing.T) {
+	service := newTestOAuthService(t)
+	tokens := service.confidentialTokens(t)
+	own := service.auth.login(t)
+
+	introspect := func(token, hint string) *models.IntrospectionResponse {
+		t.Helper()
+		response, err := service.Introspect(&models.IntrospectionRequest{
+			Token:         token,
+			TokenTypeHint: hint,
+			ClientID:      "backend",
+			ClientSecret:  testClientSecret,
+		})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		return response
+	}
+
+	access := introspect(tokens.AccessToken, "")
+	if !access.Active || access.ClientID != "backend" || access.Scope != "profile" || access.TokenType != "Bearer" {
+		t.Errorf("Unexpected access token introspection: %+v", access)
+	}
+	if access.Subject != own.User.ID || access.PhoneNumber != testPhoneNumber || access.SessionID == "" {
+		t.Errorf("Expected user claims, got %+v", access)
+	}
+
+	// Access tokens of other clients and first-party apps can be inspected
+	if first := introspect(own.Token, models.TokenTypeHintAccessToken); !first.Active || first.ClientID != "" {
+		t.Errorf("Expected active first-party token, got %+v", first)
+	}
+
+	// The hint only orders the lookup
+	for _, hint := range []string{models.TokenTypeHintRefreshToken, models.TokenTypeHintAccessToken} {
+		refresh := introspect(tokens.RefreshToken, hint)
+		if !refresh.Active || refresh.SessionID != access.SessionID || refresh.TokenType != "" {
+			t.Errorf("Unexpected refresh token introspection with hint %s: %+v", hint, refresh)
+		}
+	}
+
+	// Refresh tokens of other clients are not revealed
+	if refresh := introspect(own.RefreshToken, ""); refresh.Active {
+		t.Errorf("Expected first-party refresh token to be inactive, got %+v", refresh)
+	}
+	if garbage := introspect("not-a-token", ""); garbage.Active || garbage.Subject != "" {
+		t.Errorf("Expected inactive token, got %+v", garbage)
+	}
+
+	// Rotated refresh tokens are inactive
+	if _, err := service.Token(&models.TokenRequest{
+		GrantType:    models.GrantTypeRefreshToken,
+		RefreshToken: tokens.RefreshToken,
+		ClientID:     "backend",
+		ClientSecret: testClientSecret,
+	}, models.ClientInfo{}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if refresh := introspect(tokens.RefreshToken, models.TokenTypeHintRefreshToken); refresh.Active {
+		t.Errorf("Expected rotated refresh token to be inactive, got %+v", refresh)
+	}
+}
+
+func TestOAuthService_IntrospectRequiresConfidentialClient(t *testing.T) {
+	service := newTestOAuthService(t)
+
+	_, err := service.Introspect(&models.IntrospectionRequest{Token: "token", ClientID: "spa"})
+	assertOAuthError(t, err, "unauthorized_client")
+
+	_, err = service.Introspect(&models.IntrospectionRequest{Token: "token", ClientID: "backend", ClientSecret: "wrong"})
+	assertOAuthError(t, err, "invalid_client")
+
+	_, err = service.Introspect(&models.IntrospectionRequest{ClientID: "backend", ClientSecret: testClientSecret})
+	assertOAuthError(t, err, "invalid_request")
+}
+
+func TestOAuthService_Revoke(t *testing.T) {
+	service := newTestOAuthService(t)
+	tokens := service.confidentialTokens(t)
+
+	revoke := func(clientID, clientSecret, token, hint string) {
+		t.Helper()
+		err := service.Revoke(&models.RevocationRequest{
+			Token:         token,
+			TokenTypeHint: hint,
+			ClientID:      clientID,
+			ClientSecret:  clientSecret,
+		})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+
+	// Tokens of other clients and unknown tokens are silently ignored
+	revoke("spa", "", tokens.AccessToken, "")
+	revoke("spa", "", tokens.RefreshToken, models.TokenTypeHintRefreshToken)
+	revoke("backend", testClientSecret, "not-a-token", "")
+	if _, err := service.auth.ValidateToken(tokens.AccessToken); err != nil {
+		t.Fatalf("Expected token to stay valid, got %v", err)
+	}
+
+	// Revoking the access token leaves the session alone
+	revoke("backend", testClientSecret, tokens.AccessToken, models.TokenTypeHintAccessToken)
+	_, err := service.auth.ValidateToken(tokens.AccessToken)
+	assertErrorCode(t, err, "TOKEN_REVOKED")
+
+	refreshed, err := service.Token(&models.TokenRequest{
+		GrantType:    models.GrantTypeRefreshToken,
+		RefreshToken: tokens.RefreshToken,
+		ClientID:     "backend",
+		ClientSecret: testClientSecret,
+	}, models.ClientInfo{})
+	if err != nil {
+		t.Fatalf("Expected refresh to work, got %v", err)
+	}
+
+	// Revoking the refresh token ends the session and its access tokens
+	revoke("backend", testClientSecret, refreshed.RefreshToken, "")
+	_, err = service.auth.ValidateToken(refreshed.AccessToken)
+	assertErrorCode(t, err, "TOKEN_REVOKED")
+
+	_, err = service.Token(&models.TokenRequest{
+		GrantType:    models.GrantTypeRefreshToken,
+		RefreshToken: refreshed.RefreshToken,
+		ClientID:     "backend",
+		ClientSecret: testClientSecret,
+	}, models.ClientInfo{})
+	assertOAuthError(t, err, "invalid_grant")
+}
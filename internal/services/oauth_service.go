@@ -0,0 +1,587 @@
+package services
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"net/url"
+	"regexp"
+	"time"
+
+	"otp-auth-service/internal/errors"
+	"otp-auth-service/internal/models"
+	"otp-auth-service/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// OAuthConfig holds the settings of OAuthService
+type OAuthConfig struct {
+	// Issuer is sent as the iss parameter of authorization responses
+	// (RFC 9207), so clients can tell which server answered
+	Issuer string
+	// LoginURL is the login UI that /oauth/authorize sends the browser to,
+	// with the request_id to drive the OTP and consent steps
+	LoginURL string
+	// APIURL is the public address of the API base path, used for the
+	// endpoints of the OpenID Connect discovery document
+	APIURL           string
+	AuthorizationTTL time.Duration
+	CodeTTL          time.Duration
+}
+
+// OAuthService is an OAuth 2.0 authorization server for the authorization
+// code flow, and an OpenID Connect provider on top of it. Users sign in with
+// a login OTP; the tokens are those issued by AuthService, tied to a session
+// like any other sign-in.
+type OAuthService struct {
+	authService       *AuthService
+	clientRepo        repository.ClientRepository
+	authorizationRepo repository.AuthorizationRepository
+	consentRepo       repository.ConsentRepository
+	assertionRepo     repository.AssertionRepository
+	config            OAuthConfig
+}
+
+func NewOAuthService(authService *AuthService, clientRepo repository.ClientRepository, authorizationRepo repository.AuthorizationRepository, consentRepo repository.ConsentRepository, assertionRepo repository.AssertionRepository, config OAuthConfig) *OAuthService {
+	return &OAuthService{
+		authService:       authService,
+		clientRepo:        clientRepo,
+		authorizationRepo: authorizationRepo,
+		consentRepo:       consentRepo,
+		assertionRepo:     assertionRepo,
+		config:            config,
+	}
+}
+
+// pkceValueRegex matches PKCE code verifiers (RFC 7636 section 4.1)
+var pkceValueRegex = regexp.MustCompile(`^[A-Za-z0-9\-._~]{43,128}$`)
+
+// ValidateClient checks the client and redirect URI of an authorization
+// request. Until both are known to be valid, errors must be shown to the
+// user instead of being redirected to the client.
+func (s *OAuthService) ValidateClient(clientID, redirectURI string) (*models.OAuthClient, error) {
+	if clientID == "" {
+		return nil, errors.ErrOAuthInvalidRequest.WithDescription("client_id is required")
+	}
+
+	client, err := s.clientRepo.GetByID(clientID)
+	if err != nil {
+		return nil, errors.ErrOAuthInvalidRequest.WithDescription("unknown client_id")
+	}
+
+	if !client.AllowsGrantType(models.GrantTypeAuthorizationCode) {
+		return nil, errors.ErrOAuthUnauthorizedClient.WithDescription("client may not use the authorization code flow")
+	}
+
+	if !client.HasRedirectURI(redirectURI) {
+		return nil, errors.ErrOAuthInvalidRequest.WithDescription("redirect_uri is not registered for this client")
+	}
+
+	return client, nil
+}
+
+// Authorize starts an authorization request for a validated client. Errors
+// are OAuth errors meant for the client's redirect URI.
+func (s *OAuthService) Authorize(client *models.OAuthClient, req *models.AuthorizeRequest) (*models.AuthorizationResponse, error) {
+	if req.ResponseType != "code" {
+		return nil, errors.ErrOAuthUnsupportedResponseType.WithDescription("only response_type=code is supported")
+	}
+
+	scopes := models.ParseScope(req.Scope)
+	if len(scopes) == 0 {
+		scopes = client.Scopes
+	}
+	if !client.AllowsScopes(scopes) {
+		return nil, errors.ErrOAuthInvalidScope.WithDescription("scope is not allowed for this client")
+	}
+	if models.ContainsScopes(scopes, []string{models.ScopeOpenID}) && !s.IssuesIDTokens() {
+		return nil, errors.ErrOAuthInvalidScope.WithDescription("openid requires an asymmetric JWT_ALGORITHM; HS256 ID tokens cannot be verified by clients")
+	}
+
+	// Public clients cannot authenticate at the token endpoint, so PKCE is
+	// what stops a stolen code from being redeemed
+	if req.CodeChallenge == "" {
+		if client.IsPublic() {
+			return nil, errors.ErrOAuthInvalidRequest.WithDescription("code_challenge is required for public clients")
+		}
+	} else {
+		if req.CodeChallengeMethod != models.CodeChallengeMethodS256 {
+			return nil, errors.ErrOAuthInvalidRequest.WithDescription("code_challenge_method must be S256")
+		}
+		if !pkceValueRegex.MatchString(req.CodeChallenge) {
+			return nil, errors.ErrOAuthInvalidRequest.WithDescription("invalid code_challenge")
+		}
+	}
+
+	request := &models.AuthorizationRequest{
+		ID:                  uuid.New().String(),
+		ClientID:            client.ID,
+		RedirectURI:         req.RedirectURI,
+		Scopes:              scopes,
+		State:               req.State,
+		CodeChallenge:       req.CodeChallenge,
+		CodeChallengeMethod: req.CodeChallengeMethod,
+		Nonce:               req.Nonce,
+		ExpiresAt:           time.Now().Add(s.config.AuthorizationTTL),
+	}
+	if err := s.authorizationRepo.CreateRequest(request); err != nil {
+		return nil, errors.ErrOAuthServerError
+	}
+
+	return authorizationResponse(request, client, models.AuthorizationLoginRequired, ""), nil
+}
+
+// LoginURL returns the login UI address for an authorization request, or
+// an empty string if no login UI is configured
+func (s *OAuthService) LoginURL(requestID string) string {
+	if s.config.LoginURL == "" {
+		return ""
+	}
+
+	return addQuery(s.config.LoginURL, url.Values{"request_id": {requestID}})
+}
+
+// ErrorRedirectURL returns the redirect URI of a validated client carrying
+// an authorization error
+func (s *OAuthService) ErrorRedirectURL(redirectURI, state string, err error) string {
+	oauthErr := errors.GetOAuthError(err)
+
+	params := url.Values{"error": {oauthErr.Code}}
+	if oauthErr.Description != "" {
+		params.Set("error_description", oauthErr.Description)
+	}
+
+	return s.redirectURL(redirectURI, state, params)
+}
+
+// GetAuthorization returns the state of a pending authorization request
+func (s *OAuthService) GetAuthorization(requestID string) (*models.AuthorizationResponse, error) {
+	request, client, err := s.getRequest(requestID)
+	if err != nil {
+		return nil, err
+	}
+
+	status := models.AuthorizationLoginRequired
+	if request.UserID != "" {
+		status = models.AuthorizationConsentRequired
+	}
+
+	return authorizationResponse(request, client, status, ""), nil
+}
+
+// RequestOTP sends a login OTP to the phone number signing in for a pending
+// authorization request
+func (s *OAuthService) RequestOTP(requestID, phoneNumber string) (*models.RequestOTPResponse, error) {
+	request, _, err := s.getRequest(requestID)
+	if err != nil {
+		return nil, err
+	}
+	if request.UserID != "" {
+		return nil, errors.ErrAuthorizationOutOfOrder.WithDetails("user has already signed in")
+	}
+
+	response, err := s.authService.RequestOTP(phoneNumber, models.PurposeLogin)
+	if err != nil {
+		return nil, err
+	}
+
+	request.PhoneNumber = phoneNumber
+	if err := s.authorizationRepo.UpdateRequest(request); err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// VerifyOTP signs the user in for a pending authorization request. The
+// request completes right away unless the user still has to consent.
+func (s *OAuthService) VerifyOTP(requestID, otp string, browser models.ClientInfo) (*models.AuthorizationResponse, error) {
+	request, client, err := s.getRequest(requestID)
+	if err != nil {
+		return nil, err
+	}
+	if request.PhoneNumber == "" || request.UserID != "" {
+		return nil, errors.ErrAuthorizationOutOfOrder.WithDetails("request an OTP first")
+	}
+
+	user, _, err := s.authService.authenticate(request.PhoneNumber, otp)
+	if err != nil {
+		return nil, err
+	}
+	request.UserID = user.ID
+	request.AuthTime = time.Now()
+
+	needsConsent, err := s.needsConsent(client, user.ID, request.Scopes)
+	if err != nil {
+		return nil, err
+	}
+	if needsConsent {
+		if err := s.authorizationRepo.UpdateRequest(request); err != nil {
+			return nil, err
+		}
+		return authorizationResponse(request, client, models.AuthorizationConsentRequired, ""), nil
+	}
+
+	return s.complete(request, client, browser)
+}
+
+// Consent records the user's decision on a pending authorization request.
+// Denying it sends the browser back to the client with access_denied.
+func (s *OAuthService) Consent(requestID string, approve bool, browser models.ClientInfo) (*models.AuthorizationResponse, error) {
+	request, client, err := s.getRequest(requestID)
+	if err != nil {
+		return nil, err
+	}
+	if request.UserID == "" {
+		return nil, errors.ErrAuthorizationOutOfOrder.WithDetails("verify the OTP first")
+	}
+
+	if !approve {
+		if err := s.authorizationRepo.DeleteRequest(request.ID); err != nil {
+			return nil, err
+		}
+		redirectTo := s.ErrorRedirectURL(request.RedirectURI, request.State,
+			errors.ErrOAuthAccessDenied.WithDescription("the user denied the request"))
+		return authorizationResponse(request, client, models.AuthorizationComplete, redirectTo), nil
+	}
+
+	if err := s.grantConsent(request.UserID, client.ID, request.Scopes); err != nil {
+		return nil, err
+	}
+
+	return s.complete(request, client, browser)
+}
+
+func (s *OAuthService) getRequest(requestID string) (*models.AuthorizationRequest, *models.OAuthClient, error) {
+	request, err := s.authorizationRepo.GetRequest(requestID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	client, err := s.clientRepo.GetByID(request.ClientID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return request, client, nil
+}
+
+// needsConsent reports whether the user has yet to grant the scopes to the client
+func (s *OAuthService) needsConsent(client *models.OAuthClient, userID string, scopes []string) (bool, error) {
+	if client.Trusted {
+		return false, nil
+	}
+
+	consent, err := s.consentRepo.Get(userID, client.ID)
+	if err != nil {
+		if errors.GetDomainError(err).Code == errors.ErrConsentNotFound.Code {
+			return true, nil
+		}
+		return false, err
+	}
+
+	return !models.ContainsScopes(consent.Scopes, scopes), nil
+}
+
+// grantConsent adds scopes to the user's consent for the client
+func (s *OAuthService) grantConsent(userID, clientID string, scopes []string) error {
+	consent, err := s.consentRepo.Get(userID, clientID)
+	if err != nil {
+		if errors.GetDomainError(err).Code != errors.ErrConsentNotFound.Code {
+			return err
+		}
+		consent = &models.Consent{UserID: userID, ClientID: clientID}
+	}
+
+	for _, scope := range scopes {
+		if !models.ContainsScopes(consent.Scopes, []string{scope}) {
+			consent.Scopes = append(consent.Scopes, scope)
+		}
+	}
+	consent.GrantedAt = time.Now()
+
+	return s.consentRepo.Save(consent)
+}
+
+// complete issues the authorization code and ends the request
+func (s *OAuthService) complete(request *models.AuthorizationRequest, client *models.OAuthClient, browser models.ClientInfo) (*models.AuthorizationResponse, error) {
+	code, err := generateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.authorizationRepo.CreateCode(code, &models.AuthorizationCode{
+		ClientID:            client.ID,
+		RedirectURI:         request.RedirectURI,
+		UserID:              request.UserID,
+		Scopes:              request.Scopes,
+		CodeChallenge:       request.CodeChallenge,
+		CodeChallengeMethod: request.CodeChallengeMethod,
+		Nonce:               request.Nonce,
+		AuthTime:            request.AuthTime,
+		IPAddress:           browser.IPAddress,
+		UserAgent:           browser.UserAgent,
+		ExpiresAt:           time.Now().Add(s.config.CodeTTL),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.authorizationRepo.DeleteRequest(request.ID); err != nil {
+		return nil, err
+	}
+
+	redirectTo := s.redirectURL(request.RedirectURI, request.State, url.Values{"code": {code}})
+	return authorizationResponse(request, client, models.AuthorizationComplete, redirectTo), nil
+}
+
+// redirectURL adds response parameters, the state and the issuer to a
+// client's redirect URI
+func (s *OAuthService) redirectURL(redirectURI, state string, params url.Values) string {
+	if state != "" {
+		params.Set("state", state)
+	}
+	params.Set("iss", s.config.Issuer)
+
+	return addQuery(redirectURI, params)
+}
+
+// addQuery merges params into the query of rawURL, which must be valid
+func addQuery(rawURL string, params url.Values) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+
+	query := u.Query()
+	for key, values := range params {
+		query[key] = values
+	}
+	u.RawQuery = query.Encode()
+
+	return u.String()
+}
+
+func authorizationResponse(request *models.AuthorizationRequest, client *models.OAuthClient, status, redirectTo string) *models.AuthorizationResponse {
+	return &models.AuthorizationResponse{
+		RequestID:  request.ID,
+		Status:     status,
+		ClientID:   client.ID,
+		ClientName: client.Name,
+		Scopes:     request.Scopes,
+		RedirectTo: redirectTo,
+	}
+}
+
+// Token implements the token endpoint for the authorization_code,
+// refresh_token and client_credentials grants. caller describes the client
+// of the HTTP request.
+func (s *OAuthService) Token(req *models.TokenRequest, caller models.ClientInfo) (*models.OAuthTokenResponse, error) {
+	client, err := s.authenticateClient(models.ClientCredentials{
+		ClientID:            req.ClientID,
+		ClientSecret:        req.ClientSecret,
+		ClientAssertionType: req.ClientAssertionType,
+		ClientAssertion:     req.ClientAssertion,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	switch req.GrantType {
+	case models.GrantTypeAuthorizationCode, models.GrantTypeRefreshToken, models.GrantTypeClientCredentials:
+		if !client.AllowsGrantType(req.GrantType) {
+			return nil, errors.ErrOAuthUnauthorizedClient.WithDescription("grant type is not allowed for this client")
+		}
+	case "":
+		return nil, errors.ErrOAuthInvalidRequest.WithDescription("grant_type is required")
+	default:
+		return nil, errors.ErrOAuthUnsupportedGrantType
+	}
+
+	switch req.GrantType {
+	case models.GrantTypeAuthorizationCode:
+		return s.exchangeCode(client, req)
+	case models.GrantTypeRefreshToken:
+		if req.RefreshToken == "" {
+			return nil, errors.ErrOAuthInvalidRequest.WithDescription("refresh_token is required")
+		}
+		tokens, err := s.authService.refresh(req.RefreshToken, client.ID, caller)
+		if err != nil {
+			return nil, err
+		}
+		return oauthTokenResponse(tokens), nil
+	default:
+		return s.clientCredentials(client, req.Scope)
+	}
+}
+
+func oauthTokenResponse(tokens *models.TokenResponse) *models.OAuthTokenResponse {
+	return &models.OAuthTokenResponse{
+		AccessToken:  tokens.Token,
+		TokenType:    tokens.TokenType,
+		ExpiresIn:    tokens.ExpiresIn,
+		RefreshToken: tokens.RefreshToken,
+		Scope:        tokens.Scope,
+	}
+}
+
+// authenticateClient identifies the client of a token request. Public
+// clients only name themselves; confidential clients prove their secret or
+// sign a private_key_jwt assertion.
+func (s *OAuthService) authenticateClient(credentials models.ClientCredentials) (*models.OAuthClient, error) {
+	if credentials.ClientAssertionType != "" || credentials.ClientAssertion != "" {
+		if credentials.ClientSecret != "" {
+			return nil, errors.ErrOAuthInvalidRequest.WithDescription("use only one client authentication method")
+		}
+		return s.authenticateAssertion(credentials)
+	}
+
+	if credentials.ClientID == "" {
+		return nil, errors.ErrOAuthInvalidClient.WithDescription("client authentication is required")
+	}
+
+	client, err := s.clientRepo.GetByID(credentials.ClientID)
+	if err != nil {
+		return nil, errors.ErrOAuthInvalidClient.WithDescription("unknown client")
+	}
+
+	if client.IsPublic() {
+		if credentials.ClientSecret != "" {
+			return nil, errors.ErrOAuthInvalidClient.WithDescription("public clients have no secret")
+		}
+		return client, nil
+	}
+
+	if !checkClientSecret(client, credentials.ClientSecret) {
+		return nil, errors.ErrOAuthInvalidClient.WithDescription("invalid client credentials")
+	}
+
+	return client, nil
+}
+
+func checkClientSecret(client *models.OAuthClient, secret string) bool {
+	if secret == "" {
+		return false
+	}
+
+	want, err := hex.DecodeString(client.SecretHash)
+	if err != nil {
+		return false
+	}
+	got := sha256.Sum256([]byte(secret))
+
+	return subtle.ConstantTimeCompare(got[:], want) == 1
+}
+
+// exchangeCode redeems an authorization code and starts the session of the
+// sign-in. The session records the browser the user signed in from. Codes
+// granting the openid scope also get an ID token.
+func (s *OAuthService) exchangeCode(client *models.OAuthClient, req *models.TokenRequest) (*models.OAuthTokenResponse, error) {
+	if req.Code == "" {
+		return nil, errors.ErrOAuthInvalidRequest.WithDescription("code is required")
+	}
+
+	code, err := s.authorizationRepo.ConsumeCode(req.Code)
+	if err != nil {
+		return nil, err
+	}
+
+	if code.ClientID != client.ID {
+		return nil, errors.ErrOAuthInvalidGrant.WithDescription("code was issued to another client")
+	}
+	if code.RedirectURI != req.RedirectURI {
+		return nil, errors.ErrOAuthInvalidGrant.WithDescription("redirect_uri does not match the authorization request")
+	}
+
+	if code.CodeChallenge != "" {
+		if !verifyCodeChallenge(req.CodeVerifier, code.CodeChallenge) {
+			return nil, errors.ErrOAuthInvalidGrant.WithDescription("code_verifier does not match code_challenge")
+		}
+	} else if req.CodeVerifier != "" {
+		return nil, errors.ErrOAuthInvalidGrant.WithDescription("code was issued without code_challenge")
+	}
+
+	user, err := s.authService.userRepo.GetByID(code.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens, err := s.authService.startSession(user, client, code.Scopes, models.ClientInfo{
+		IPAddress: code.IPAddress,
+		UserAgent: code.UserAgent,
+	}, code.AuthTime)
+	if err != nil {
+		return nil, err
+	}
+
+	response := oauthTokenResponse(tokens)
+	if models.ContainsScopes(code.Scopes, []string{models.ScopeOpenID}) {
+		response.IDToken, err = s.generateIDToken(user, client.ID, code.Nonce, code.AuthTime)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return response, nil
+}
+
+// verifyCodeChallenge checks a PKCE verifier against its S256 challenge
+func verifyCodeChallenge(verifier, challenge string) bool {
+	if !pkceValueRegex.MatchString(verifier) {
+		return false
+	}
+
+	sum := sha256.Sum256([]byte(verifier))
+	expected := base64.RawURLEncoding.EncodeToString(sum[:])
+
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(challenge)) == 1
+}
+
+// ListConsents returns the clients the user has granted access to
+func (s *OAuthService) ListConsents(userID string) ([]*models.ConsentResponse, error) {
+	consents, err := s.consentRepo.GetByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]*models.ConsentResponse, len(consents))
+	for i, consent := range consents {
+		responses[i] = &models.ConsentResponse{
+			ClientID:  consent.ClientID,
+			Scopes:    consent.Scopes,
+			GrantedAt: consent.GrantedAt,
+		}
+		// Clients removed from the config keep their ID only
+		if client, err := s.clientRepo.GetByID(consent.ClientID); err == nil {
+			responses[i].ClientName = client.Name
+		}
+	}
+
+	return responses, nil
+}
+
+// RevokeConsent withdraws the user's consent for a client and ends the
+// sessions the client holds for the user
+func (s *OAuthService) RevokeConsent(userID, clientID string) error {
+	if err := s.consentRepo.Delete(userID, clientID); err != nil {
+		return err
+	}
+
+	sessions, err := s.authService.sessionRepo.GetByUserID(userID)
+	if err != nil {
+		return err
+	}
+
+	for _, session := range sessions {
+		if session.ClientID != clientID {
+			continue
+		}
+		if err := s.authService.endSession(userID, session.ID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
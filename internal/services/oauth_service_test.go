@@ -0,0 +1,410 @@
+package services
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"net/url"
+	"testing"
+	"time"
+
+	"otp-auth-service/internal/errors"
+	"otp-auth-service/internal/models"
+	"otp-auth-service/internal/repository"
+)
+
+const (
+	testRedirectURI  = "https://app.example.com/callback"
+	testCodeVerifier = "dBjftJeZ4CVP-mJ92K9lVkDLqE1zd7hQ4c3hY8dAqZ0x"
+	testClientSecret = "s3cret"
+)
+
+var testBrowser = models.ClientInfo{IPAddress: "203.0.113.7", UserAgent: "browser/1.0"}
+
+type testOAuthService struct {
+	*OAuthService
+	auth *testAuthService
+	// signerKey signs the client assertions of the "signer" client
+	signerKey ed25519.PrivateKey
+}
+
+func newTestOAuthService(t *testing.T) *testOAuthService {
+	t.Helper()
+
+	auth := newTestAuthService(t, newTestAuthConfig(t))
+	secretHash := sha256.Sum256([]byte(testClientSecret))
+	signerPublic, signerKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+
+	clients := repository.NewClientRepository([]*models.OAuthClient{
+		{ID: "spa", Name: "Web App", RedirectURIs: []string{testRedirectURI}, Scopes: []string{"profile", "orders"}},
+		{ID: "backend", Name: "Partner", SecretHash: hex.EncodeToString(secretHash[:]), RedirectURIs: []string{testRedirectURI}, Scopes: []string{"profile"}},
+		{ID: "first-party", Name: "Our App", RedirectURIs: []string{testRedirectURI}, Scopes: []string{"profile"}, Trusted: true},
+		{ID: "batch", Name: "Batch Jobs", SecretHash: hex.EncodeToString(secretHash[:]), GrantTypes: []string{models.GrantTypeClientCredentials}, Scopes: []string{models.ScopeUsersRead, "reports"}},
+		{ID: "signer", Name: "Signing Job", PublicKey: signerPublic, GrantTypes: []string{models.GrantTypeClientCredentials}, Scopes: []string{models.ScopeUsersRead}},
+	})
+
+	service := NewOAuthService(auth.AuthService, clients,
+		repository.NewAuthorizationRepository(auth.redis),
+		repository.NewConsentRepository(auth.redis),
+		repository.NewAssertionRepository(auth.redis),
+		OAuthConfig{
+			Issuer:           "https://auth.example.com",
+			APIURL:           "https://auth.example.com/api/v1",
+			AuthorizationTTL: 10 * time.Minute,
+			CodeTTL:          time.Minute,
+		},
+	)
+
+	return &testOAuthService{OAuthService: service, auth: auth, signerKey: signerKey}
+}
+
+func testCodeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// authorize starts an authorization request and signs the test user in
+func (s *testOAuthService) authorize(t *testing.T, req *models.AuthorizeRequest) *models.AuthorizationResponse {
+	t.Helper()
+
+	client, err := s.ValidateClient(req.ClientID, req.RedirectURI)
+	if err != nil {
+		t.Fatalf("Expected valid client, got %v", err)
+	}
+
+	pending, err := s.Authorize(client, req)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if pending.Status != models.AuthorizationLoginRequired {
+		t.Fatalf("Expected %s, got %s", models.AuthorizationLoginRequired, pending.Status)
+	}
+
+	if _, err := s.RequestOTP(pending.RequestID, testPhoneNumber); err != nil {
+		t.Fatalf("Failed to request OTP: %v", err)
+	}
+
+	response, err := s.VerifyOTP(pending.RequestID, s.auth.lastOTP(t), testBrowser)
+	if err != nil {
+		t.Fatalf("Failed to verify OTP: %v", err)
+	}
+
+	return response
+}
+
+func newSPARequest() *models.AuthorizeRequest {
+	return &models.AuthorizeRequest{
+		ResponseType:        "code",
+		ClientID:            "spa",
+		RedirectURI:         testRedirectURI,
+		Scope:               "profile",
+		State:               "xyz",
+		CodeChallenge:       testCodeChallenge(testCodeVerifier),
+		CodeChallengeMethod: models.CodeChallengeMethodS256,
+	}
+}
+
+// codeFrom extracts the authorization code from a redirect
+func codeFrom(t *testing.T, redirectTo string) string {
+	t.Helper()
+
+	u, err := url.Parse(redirectTo)
+	if err != nil {
+		t.Fatalf("Invalid redirect %q: %v", redirectTo, err)
+	}
+	query := u.Query()
+	if query.Get("state") != "xyz" || query.Get("iss") != "https://auth.example.com" {
+		t.Errorf("Expected state and iss in redirect, got %s", redirectTo)
+	}
+	if query.Get("code") == "" {
+		t.Fatalf("Expected code in redirect, got %s", redirectTo)
+	}
+
+	return query.Get("code")
+}
+
+func TestOAuthService_AuthorizationCodeFlow(t *testing.T) {
+	service := newTestOAuthService(t)
+
+	response := service.authorize(t, newSPARequest())
+	if response.Status != models.AuthorizationConsentRequired {
+		t.Fatalf("Expected consent to be required, got %s", response.Status)
+	}
+
+	response, err := service.Consent(response.RequestID, true, testBrowser)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	code := codeFrom(t, response.RedirectTo)
+
+	tokens, err := service.Token(&models.TokenRequest{
+		GrantType:    models.GrantTypeAuthorizationCode,
+		Code:         code,
+		RedirectURI:  testRedirectURI,
+		CodeVerifier: testCodeVerifier,
+		ClientID:     "spa",
+	}, models.ClientInfo{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if tokens.Scope != "profile" || tokens.RefreshToken == "" {
+		t.Errorf("Unexpected token response: %+v", tokens)
+	}
+
+	claims, err := service.auth.ValidateToken(tokens.AccessToken)
+	if err != nil {
+		t.Fatalf("Expected valid access token, got %v", err)
+	}
+	if claims.ClientID != "spa" || claims.Scope != "profile" {
+		t.Errorf("Expected client_id and scope claims, got %+v", claims)
+	}
+
+	// The sign-in is a session of the client, from the user's browser
+	sessions, _ := service.auth.ListSessions(claims.UserID(), "")
+	if len(sessions) != 1 || sessions[0].ClientID != "spa" || sessions[0].DeviceName != "Web App" || sessions[0].IPAddress != "203.0.113.7" {
+		t.Errorf("Unexpected sessions: %+v", sessions)
+	}
+
+	// Codes can be redeemed once
+	_, err = service.Token(&models.TokenRequest{
+		GrantType:    models.GrantTypeAuthorizationCode,
+		Code:         code,
+		RedirectURI:  testRedirectURI,
+		CodeVerifier: testCodeVerifier,
+		ClientID:     "spa",
+	}, models.ClientInfo{})
+	assertOAuthError(t, err, "invalid_grant")
+
+	// Refresh tokens of the client are refreshed at the token endpoint only
+	_, err = service.auth.RefreshToken(tokens.RefreshToken, models.ClientInfo{})
+	assertErrorCode(t, err, "INVALID_REFRESH_TOKEN")
+
+	refreshed, err := service.Token(&models.TokenRequest{
+		GrantType:    models.GrantTypeRefreshToken,
+		RefreshToken: tokens.RefreshToken,
+		ClientID:     "spa",
+	}, models.ClientInfo{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if refreshed.Scope != "profile" {
+		t.Errorf("Expected scope to be kept on refresh, got %q", refreshed.Scope)
+	}
+
+	// Consent is remembered
+	response = service.authorize(t, newSPARequest())
+	if response.Status != models.AuthorizationComplete {
+		t.Errorf("Expected remembered consent, got %s", response.Status)
+	}
+}
+
+func TestOAuthService_TokenRejectsMismatches(t *testing.T) {
+	tests := []struct {
+		name     string
+		modify   func(req *models.TokenRequest)
+		wantCode string
+	}{
+		{name: "wrong verifier", modify: func(req *models.TokenRequest) { req.CodeVerifier = testCodeVerifier + "x" }, wantCode: "invalid_grant"},
+		{name: "missing verifier", modify: func(req *models.TokenRequest) { req.CodeVerifier = "" }, wantCode: "invalid_grant"},
+		{name: "other redirect URI", modify: func(req *models.TokenRequest) { req.RedirectURI = "https://evil.example.com/" }, wantCode: "invalid_grant"},
+		{name: "other client", modify: func(req *models.TokenRequest) { req.ClientID = "first-party" }, wantCode: "invalid_grant"},
+		{name: "unknown client", modify: func(req *models.TokenRequest) { req.ClientID = "nobody" }, wantCode: "invalid_client"},
+		{name: "unsupported grant", modify: func(req *models.TokenRequest) { req.GrantType = "password" }, wantCode: "unsupported_grant_type"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := newTestOAuthService(t)
+
+			response := service.authorize(t, newSPARequest())
+			response, err := service.Consent(response.RequestID, true, testBrowser)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+
+			req := &models.TokenRequest{
+				GrantType:    models.GrantTypeAuthorizationCode,
+				Code:         codeFrom(t, response.RedirectTo),
+				RedirectURI:  testRedirectURI,
+				CodeVerifier: testCodeVerifier,
+				ClientID:     "spa",
+			}
+			tt.modify(req)
+
+			_, err = service.Token(req, models.ClientInfo{})
+			assertOAuthError(t, err, tt.wantCode)
+		})
+	}
+}
+
+func TestOAuthService_AuthorizeValidation(t *testing.T) {
+	service := newTestOAuthService(t)
+
+	if _, err := service.ValidateClient("nobody", testRedirectURI); err == nil {
+		t.Error("Expected unknown client to be rejected")
+	}
+	if _, err := service.ValidateClient("spa", testRedirectURI+"/other"); err == nil {
+		t.Error("Expected unregistered redirect URI to be rejected")
+	}
+
+	tests := []struct {
+		name     string
+		clientID string
+		modify   func(req *models.AuthorizeRequest)
+		wantCode string
+	}{
+		{name: "implicit flow", clientID: "spa", modify: func(req *models.AuthorizeRequest) { req.ResponseType = "token" }, wantCode: "unsupported_response_type"},
+		{name: "scope not allowed", clientID: "spa", modify: func(req *models.AuthorizeRequest) { req.Scope = "profile admin" }, wantCode: "invalid_scope"},
+		{name: "public client without PKCE", clientID: "spa", modify: func(req *models.AuthorizeRequest) { req.CodeChallenge = "" }, wantCode: "invalid_request"},
+		{name: "plain PKCE", clientID: "spa", modify: func(req *models.AuthorizeRequest) { req.CodeChallengeMethod = "plain" }, wantCode: "invalid_request"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, _ := service.ValidateClient(tt.clientID, testRedirectURI)
+			req := newSPARequest()
+			tt.modify(req)
+
+			_, err := service.Authorize(client, req)
+			assertOAuthError(t, err, tt.wantCode)
+		})
+	}
+
+	// Confidential clients may skip PKCE
+	client, _ := service.ValidateClient("backend", testRedirectURI)
+	req := newSPARequest()
+	req.ClientID, req.CodeChallenge, req.CodeChallengeMethod = "backend", "", ""
+	if _, err := service.Authorize(client, req); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+}
+
+func TestOAuthService_ConfidentialClient(t *testing.T) {
+	service := newTestOAuthService(t)
+
+	req := newSPARequest()
+	req.ClientID, req.CodeChallenge, req.CodeChallengeMethod = "backend", "", ""
+	response := service.authorize(t, req)
+	response, err := service.Consent(response.RequestID, true, testBrowser)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	code := codeFrom(t, response.RedirectTo)
+
+	tokenRequest := &models.TokenRequest{
+		GrantType:    models.GrantTypeAuthorizationCode,
+		Code:         code,
+		RedirectURI:  testRedirectURI,
+		ClientID:     "backend",
+		ClientSecret: "wrong",
+	}
+	_, err = service.Token(tokenRequest, models.ClientInfo{})
+	assertOAuthError(t, err, "invalid_client")
+
+	tokenRequest.ClientSecret = testClientSecret
+	if _, err := service.Token(tokenRequest, models.ClientInfo{}); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+}
+
+func TestOAuthService_TrustedClientSkipsConsent(t *testing.T) {
+	service := newTestOAuthService(t)
+
+	req := newSPARequest()
+	req.ClientID = "first-party"
+	response := service.authorize(t, req)
+
+	if response.Status != models.AuthorizationComplete {
+		t.Fatalf("Expected no consent step, got %s", response.Status)
+	}
+	codeFrom(t, response.RedirectTo)
+}
+
+func TestOAuthService_ConsentDenied(t *testing.T) {
+	service := newTestOAuthService(t)
+
+	response := service.authorize(t, newSPARequest())
+	response, err := service.Consent(response.RequestID, false, testBrowser)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	u, _ := url.Parse(response.RedirectTo)
+	if u.Query().Get("error") != "access_denied" || u.Query().Get("code") != "" {
+		t.Errorf("Expected access_denied redirect, got %s", response.RedirectTo)
+	}
+
+	// The request is gone
+	_, err = service.GetAuthorization(response.RequestID)
+	assertErrorCode(t, err, "AUTHORIZATION_REQUEST_NOT_FOUND")
+}
+
+func TestOAuthService_StepsOutOfOrder(t *testing.T) {
+	service := newTestOAuthService(t)
+
+	client, _ := service.ValidateClient("spa", testRedirectURI)
+	pending, err := service.Authorize(client, newSPARequest())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	_, err = service.VerifyOTP(pending.RequestID, "123456", models.ClientInfo{})
+	assertErrorCode(t, err, "AUTHORIZATION_OUT_OF_ORDER")
+
+	_, err = service.Consent(pending.RequestID, true, models.ClientInfo{})
+	assertErrorCode(t, err, "AUTHORIZATION_OUT_OF_ORDER")
+}
+
+func TestOAuthService_RevokeConsent(t *testing.T) {
+	service := newTestOAuthService(t)
+
+	response := service.authorize(t, newSPARequest())
+	response, _ = service.Consent(response.RequestID, true, testBrowser)
+	tokens, err := service.Token(&models.TokenRequest{
+		GrantType:    models.GrantTypeAuthorizationCode,
+		Code:         codeFrom(t, response.RedirectTo),
+		RedirectURI:  testRedirectURI,
+		CodeVerifier: testCodeVerifier,
+		ClientID:     "spa",
+	}, models.ClientInfo{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// A first-party sign-in of the same user is not affected
+	own := service.auth.login(t)
+	claims, _ := service.auth.ValidateToken(own.Token)
+
+	consents, err := service.ListConsents(claims.UserID())
+	if err != nil || len(consents) != 1 || consents[0].ClientName != "Web App" {
+		t.Fatalf("Unexpected consents %+v (%v)", consents, err)
+	}
+
+	if err := service.RevokeConsent(claims.UserID(), "spa"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	_, err = service.auth.ValidateToken(tokens.AccessToken)
+	assertErrorCode(t, err, "TOKEN_REVOKED")
+	if _, err := service.auth.ValidateToken(own.Token); err != nil {
+		t.Errorf("Expected first-party session to stay valid, got %v", err)
+	}
+
+	err = service.RevokeConsent(claims.UserID(), "spa")
+	assertErrorCode(t, err, "CONSENT_NOT_FOUND")
+}
+
+func assertOAuthError(t *testing.T, err error, code string) {
+	t.Helper()
+
+	if err == nil {
+		t.Fatalf("Expected %s, got no error", code)
+	}
+	if got := errors.GetOAuthError(err).Code; got != code {
+		t.Errorf("Expected OAuth error %s, got %s (%v)", code, got, err)
+	}
+}
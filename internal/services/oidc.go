@@ -0,0 +1,90 @@
+package services
+
+import (
+	"strings"
+	"time"
+
+	"otp-auth-service/internal/models"
+	"otp-auth-service/internal/signing"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// generateIDToken signs an OpenID Connect ID token for a user signed in
+// through client. Users sign in with their phone number, so it is always
+// included and always verified.
+func (s *OAuthService) generateIDToken(user *models.User, clientID, nonce string, authTime time.Time) (string, error) {
+	now := time.Now()
+	claims := &models.IDTokenClaims{
+		PhoneNumber:         user.PhoneNumber,
+		PhoneNumberVerified: true,
+		Nonce:               nonce,
+		AuthTime:            jwt.NewNumericDate(authTime),
+		ACR:                 models.ACRPhoneOTP,
+		AuthorizedParty:     clientID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.New().String(),
+			Issuer:    s.config.Issuer,
+			Subject:   user.ID,
+			Audience:  jwt.ClaimStrings{clientID},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(s.authService.config.AccessTokenTTL)),
+		},
+	}
+
+	return s.authService.config.Keyring.Sign(claims)
+}
+
+// IssuesIDTokens reports whether the service acts as an OpenID Provider.
+// ID tokens are signed with the keys of access tokens, and relying parties
+// cannot verify tokens signed with the server's HS256 secret, so the
+// openid scope is refused unless the active key is asymmetric.
+func (s *OAuthService) IssuesIDTokens() bool {
+	return !s.authService.config.Keyring.Active().IsSymmetric()
+}
+
+// Discovery returns the OpenID Provider metadata. Endpoint URLs are built
+// from the issuer and APIURL, so the issuer must be the service's public URL.
+// Without an asymmetric key no scope or ID token algorithm is advertised.
+func (s *OAuthService) Discovery() *models.DiscoveryDocument {
+	scopes := []string{models.ScopeOpenID}
+	idTokenAlgorithms := []string{s.authService.config.Keyring.Active().Algorithm}
+	if !s.IssuesIDTokens() {
+		scopes, idTokenAlgorithms = []string{}, []string{}
+	}
+
+	return &models.DiscoveryDocument{
+		Issuer:                 s.config.Issuer,
+		AuthorizationEndpoint:  s.endpoint("/oauth/authorize"),
+		TokenEndpoint:          s.endpoint("/oauth/token"),
+		UserInfoEndpoint:       s.endpoint("/oauth/userinfo"),
+		IntrospectionEndpoint:  s.endpoint("/oauth/introspect"),
+		RevocationEndpoint:     s.endpoint("/oauth/revoke"),
+		JWKSURI:                strings.TrimSuffix(s.config.Issuer, "/") + "/.well-known/jwks.json",
+		ScopesSupported:        scopes,
+		ResponseTypesSupported: []string{"code"},
+		ResponseModesSupported: []string{"query"},
+		GrantTypesSupported: []string{
+			models.GrantTypeAuthorizationCode, models.GrantTypeRefreshToken, models.GrantTypeClientCredentials,
+		},
+		SubjectTypesSupported:                      []string{"public"},
+		IDTokenSigningAlgValuesSupported:           idTokenAlgorithms,
+		TokenEndpointAuthMethodsSupported:          []string{"client_secret_basic", "client_secret_post", "private_key_jwt", "none"},
+		TokenEndpointAuthSigningAlgValuesSupported: []string{signing.AlgorithmRS256, signing.AlgorithmES256, signing.AlgorithmEdDSA},
+		IntrospectionEndpointAuthMethodsSupported:  []string{"client_secret_basic", "client_secret_post", "private_key_jwt"},
+		RevocationEndpointAuthMethodsSupported:     []string{"client_secret_basic", "client_secret_post", "private_key_jwt", "none"},
+		CodeChallengeMethodsSupported:              []string{models.CodeChallengeMethodS256},
+		ClaimsSupported: []string{
+			"iss", "sub", "aud", "exp", "iat", "auth_time", "acr", "nonce", "azp",
+			"phone_number", "phone_number_verified",
+		},
+		ACRValuesSupported:                         []string{models.ACRPhoneOTP},
+		AuthorizationResponseIssParameterSupported: true,
+	}
+}
+
+// endpoint returns the public URL of an API path
+func (s *OAuthService) endpoint(path string) string {
+	return strings.TrimSuffix(s.config.APIURL, "/") + path
+}
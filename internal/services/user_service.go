@@ -2,7 +2,9 @@ package services
 
 import (
 	"strconv"
+	"strings"
 
+	"otp-auth-service/internal/errors"
 	"otp-auth-service/internal/models"
 	"otp-auth-service/internal/repository"
 )
@@ -26,31 +28,99 @@ func (s *UserService) GetUser(id string) (*models.UserResponse, error) {
 	return user.ToResponse(), nil
 }
 
-func (s *UserService) GetUsers(pageStr, limitStr, search string) ([]*models.UserResponse, int, error) {
-	page := 1
-	limit := 10
+// UpdateProfile changes the profile fields given in req and returns the
+// updated user
+func (s *UserService) UpdateProfile(id string, req *models.UpdateProfileRequest) (*models.UserResponse, error) {
+	user, err := s.userRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		user.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.Locale != nil {
+		user.Locale = *req.Locale
+	}
+	if err := s.userRepo.Update(user); err != nil {
+		return nil, err
+	}
+
+	return user.ToResponse(), nil
+}
+
+// GetUsers returns a page of users and the number of users matching the
+// request. The cursor of the next page is empty on the last page.
+func (s *UserService) GetUsers(req *models.GetUsersRequest) ([]*models.UserResponse, int, string, error) {
+	query := models.UserQuery{
+		Page:   1,
+		Limit:  10,
+		Search: req.Search,
+		Sort:   models.UserSortRegisteredAt,
+		Desc:   req.Order == models.SortOrderDesc,
+	}
+
+	if req.Page != "" {
+		if p, err := strconv.Atoi(req.Page); err == nil && p > 0 {
+			query.Page = p
+		}
+	}
 
-	if pageStr != "" {
-		if p, err := strconv.Atoi(pageStr); err == nil && p > 0 {
-			page = p
+	if req.Limit != "" {
+		if l, err := strconv.Atoi(req.Limit); err == nil && l > 0 && l <= 100 {
+			query.Limit = l
 		}
 	}
 
-	if limitStr != "" {
-		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 100 {
-			limit = l
+	if req.Sort != "" {
+		query.Sort = req.Sort
+	}
+
+	filter, err := req.Filter()
+	if err != nil {
+		return nil, 0, "", errors.ErrInvalidFilter.WithDetails(err.Error())
+	}
+	query.Filter = filter
+
+	// A cursor carries the sort order it was issued for
+	if req.Cursor != "" {
+		cursor, err := models.ParseUserCursor(req.Cursor)
+		if err != nil {
+			return nil, 0, "", errors.ErrInvalidCursor.WithDetails(err.Error())
 		}
+		query.Cursor = cursor
+		query.Sort, query.Desc = cursor.Sort, cursor.Desc
 	}
 
-	users, total, err := s.userRepo.GetAll(page, limit, search)
+	page, err := s.userRepo.GetAll(query)
 	if err != nil {
-		return nil, 0, err
+		return nil, 0, "", err
 	}
 
 	var userResponses []*models.UserResponse
-	for _, user := range users {
+	for _, user := range page.Users {
 		userResponses = append(userResponses, user.ToResponse())
 	}
 
-	return userResponses, total, nil
+	var nextCursor string
+	if page.HasMore && len(page.Users) > 0 {
+		nextCursor = models.NewUserCursor(page.Users[len(page.Users)-1], query.Sort, query.Desc).Encode()
+	}
+
+	return userResponses, page.Total, nextCursor, nil
+}
+
+// GetUserInfo returns the OpenID Connect claims of a user. Phone numbers
+// are verified by the OTP the user signed in with.
+func (s *UserService) GetUserInfo(id string) (*models.UserInfoResponse, error) {
+	user, err := s.GetUser(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.UserInfoResponse{
+		Subject:             user.ID,
+		PhoneNumber:         user.PhoneNumber,
+		PhoneNumberVerified: true,
+	}, nil
 }
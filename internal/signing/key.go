@@ -0,0 +1,204 @@
+// Package signing holds the keys access tokens are signed with and
+// publishes their public halves as a JSON Web Key Set.
+package signing
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Supported signing algorithms
+const (
+	AlgorithmHS256 = "HS256"
+	AlgorithmRS256 = "RS256"
+	AlgorithmES256 = "ES256"
+	AlgorithmEdDSA = "EdDSA"
+)
+
+// minRSABits is the smallest RSA modulus accepted for signing
+const minRSABits = 2048
+
+// Key is a single token signing key
+type Key struct {
+	// ID is sent as the kid header. It is the RFC 7638 thumbprint of the
+	// key, so the same key always gets the same ID.
+	ID        string
+	Algorithm string
+	// ActivatesAt is when the key may start signing. Until then it is only
+	// published, so verifiers can fetch it before the first token shows up.
+	ActivatesAt time.Time
+
+	method    jwt.SigningMethod
+	signKey   interface{}
+	verifyKey interface{}
+}
+
+// NewHMACKey returns an HS256 key for a shared secret
+func NewHMACKey(secret string) (*Key, error) {
+	if secret == "" {
+		return nil, fmt.Errorf("HMAC secret must not be empty")
+	}
+
+	return &Key{
+		ID:        hmacKeyID(secret),
+		Algorithm: AlgorithmHS256,
+		method:    jwt.SigningMethodHS256,
+		signKey:   []byte(secret),
+		verifyKey: []byte(secret),
+	}, nil
+}
+
+// hmacKeyID is the RFC 7638 thumbprint of the secret as an oct JWK. It
+// reveals no more about the secret than the token signatures already do.
+func hmacKeyID(secret string) string {
+	data, _ := json.Marshal(struct {
+		K   string `json:"k"`
+		Kty string `json:"kty"`
+	}{encode([]byte(secret)), "oct"})
+	sum := sha256.Sum256(data)
+	return encode(sum[:])
+}
+
+// LoadKey reads a PEM encoded private key for an asymmetric algorithm
+func LoadKey(algorithm, path string) (*Key, error) {
+	pemData, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read signing key: %w", err)
+	}
+
+	key, err := ParseKey(algorithm, pemData)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return key, nil
+}
+
+// ParseKey parses a PEM encoded private key (PKCS#1, SEC 1 or PKCS#8)
+func ParseKey(algorithm string, pemData []byte) (*Key, error) {
+	var (
+		method     jwt.SigningMethod
+		privateKey crypto.Signer
+	)
+
+	switch algorithm {
+	case AlgorithmRS256:
+		rsaKey, err := jwt.ParseRSAPrivateKeyFromPEM(pemData)
+		if err != nil {
+			return nil, fmt.Errorf("invalid RSA private key: %w", err)
+		}
+		if rsaKey.N.BitLen() < minRSABits {
+			return nil, fmt.Errorf("RSA key must be at least %d bits, got %d", minRSABits, rsaKey.N.BitLen())
+		}
+		method, privateKey = jwt.SigningMethodRS256, rsaKey
+	case AlgorithmES256:
+		ecKey, err := jwt.ParseECPrivateKeyFromPEM(pemData)
+		if err != nil {
+			return nil, fmt.Errorf("invalid EC private key: %w", err)
+		}
+		if ecKey.Curve != elliptic.P256() {
+			return nil, fmt.Errorf("ES256 requires a P-256 key, got %s", ecKey.Curve.Params().Name)
+		}
+		method, privateKey = jwt.SigningMethodES256, ecKey
+	case AlgorithmEdDSA:
+		edKey, err := jwt.ParseEdPrivateKeyFromPEM(pemData)
+		if err != nil {
+			return nil, fmt.Errorf("invalid Ed25519 private key: %w", err)
+		}
+		method, privateKey = jwt.SigningMethodEdDSA, edKey.(ed25519.PrivateKey)
+	case AlgorithmHS256:
+		return nil, fmt.Errorf("HS256 uses a shared secret, not a key file")
+	default:
+		return nil, fmt.Errorf("unsupported signing algorithm %q", algorithm)
+	}
+
+	key := &Key{
+		Algorithm: algorithm,
+		method:    method,
+		signKey:   privateKey,
+		verifyKey: privateKey.Public(),
+	}
+
+	jwk, _ := key.JWK()
+	key.ID = jwk.Thumbprint()
+
+	return key, nil
+}
+
+// IsSymmetric reports whether the key can only be shared, not published
+func (k *Key) IsSymmetric() bool {
+	return k.Algorithm == AlgorithmHS256
+}
+
+// Sign signs the claims and sets the kid header
+func (k *Key) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(k.method, claims)
+	if k.ID != "" {
+		token.Header["kid"] = k.ID
+	}
+
+	return token.SignedString(k.signKey)
+}
+
+// VerificationKey returns the key jwt.Parse needs to check signatures,
+// after making sure the token uses this key's algorithm
+func (k *Key) VerificationKey(token *jwt.Token) (interface{}, error) {
+	if token.Method.Alg() != k.method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+
+	return k.verifyKey, nil
+}
+
+// PublicKey returns the public key of an asymmetric key, nil for HMAC keys
+func (k *Key) PublicKey() crypto.PublicKey {
+	switch pub := k.verifyKey.(type) {
+	case *rsa.PublicKey, *ecdsa.PublicKey, ed25519.PublicKey:
+		return pub
+	default:
+		return nil
+	}
+}
+
+// GenerateKey creates a new private key for an asymmetric algorithm and
+// returns it PKCS#8 PEM encoded
+func GenerateKey(algorithm string) ([]byte, error) {
+	var (
+		privateKey interface{}
+		err        error
+	)
+
+	switch algorithm {
+	case AlgorithmRS256:
+		privateKey, err = rsa.GenerateKey(rand.Reader, minRSABits)
+	case AlgorithmES256:
+		privateKey, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case AlgorithmEdDSA:
+		_, privateKey, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return nil, fmt.Errorf("cannot generate keys for %q", algorithm)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+}
@@ -0,0 +1,215 @@
+package signing
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func writePEM(t *testing.T, blockType string, der []byte) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "key.pem")
+	data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatalf("Failed to write key: %v", err)
+	}
+	return path
+}
+
+func writePKCS8(t *testing.T, key interface{}) string {
+	t.Helper()
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("Failed to marshal key: %v", err)
+	}
+	return writePEM(t, "PRIVATE KEY", der)
+}
+
+func TestLoadKey_SignAndVerify(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	ecDER, _ := x509.MarshalECPrivateKey(ecKey)
+
+	tests := []struct {
+		name      string
+		algorithm string
+		path      string
+		keyType   string
+	}{
+		{name: "RSA PKCS#1", algorithm: AlgorithmRS256, path: writePEM(t, "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey)), keyType: "RSA"},
+		{name: "RSA PKCS#8", algorithm: AlgorithmRS256, path: writePKCS8(t, rsaKey), keyType: "RSA"},
+		{name: "EC SEC 1", algorithm: AlgorithmES256, path: writePEM(t, "EC PRIVATE KEY", ecDER), keyType: "EC"},
+		{name: "EC PKCS#8", algorithm: AlgorithmES256, path: writePKCS8(t, ecKey), keyType: "EC"},
+		{name: "Ed25519", algorithm: AlgorithmEdDSA, path: writePKCS8(t, edKey), keyType: "OKP"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := LoadKey(tt.algorithm, tt.path)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if key.ID == "" {
+				t.Error("Expected key ID to be derived from the public key")
+			}
+			if key.IsSymmetric() {
+				t.Error("Expected asymmetric key")
+			}
+
+			tokenString, err := key.Sign(jwt.MapClaims{"sub": "user-1", "exp": time.Now().Add(time.Minute).Unix()})
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+
+			token, err := jwt.Parse(tokenString, key.VerificationKey)
+			if err != nil || !token.Valid {
+				t.Fatalf("Expected valid token, got %v", err)
+			}
+			if token.Header["kid"] != key.ID {
+				t.Errorf("Expected kid %s, got %v", key.ID, token.Header["kid"])
+			}
+
+			jwk, ok := key.JWK()
+			if !ok {
+				t.Fatal("Expected JWK for asymmetric key")
+			}
+			if jwk.KeyType != tt.keyType || jwk.Algorithm != tt.algorithm || jwk.KeyID != key.ID {
+				t.Errorf("Unexpected JWK: %+v", jwk)
+			}
+		})
+	}
+}
+
+func TestLoadKey_Invalid(t *testing.T) {
+	smallRSA, _ := rsa.GenerateKey(rand.Reader, 1024)
+	p384, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	tests := []struct {
+		name      string
+		algorithm string
+		path      string
+	}{
+		{name: "missing file", algorithm: AlgorithmRS256, path: "/does/not/exist.pem"},
+		{name: "RSA key too small", algorithm: AlgorithmRS256, path: writePKCS8(t, smallRSA)},
+		{name: "wrong curve", algorithm: AlgorithmES256, path: writePKCS8(t, p384)},
+		{name: "algorithm mismatch", algorithm: AlgorithmRS256, path: writePKCS8(t, ecKey)},
+		{name: "HMAC from file", algorithm: AlgorithmHS256, path: writePKCS8(t, ecKey)},
+		{name: "unknown algorithm", algorithm: "PS256", path: writePKCS8(t, ecKey)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := LoadKey(tt.algorithm, tt.path); err == nil {
+				t.Error("Expected error")
+			}
+		})
+	}
+}
+
+func TestHMACKey(t *testing.T) {
+	key, err := NewHMACKey("secret")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !key.IsSymmetric() {
+		t.Error("Expected symmetric key")
+	}
+	if _, ok := key.JWK(); ok {
+		t.Error("Expected HMAC key not to be published")
+	}
+
+	tokenString, err := key.Sign(jwt.MapClaims{"sub": "user-1"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, err := jwt.Parse(tokenString, key.VerificationKey); err != nil {
+		t.Errorf("Expected valid token, got %v", err)
+	}
+
+	if _, err := NewHMACKey(""); err == nil {
+		t.Error("Expected error for empty secret")
+	}
+}
+
+func TestVerificationKey_RejectsOtherAlgorithms(t *testing.T) {
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	key, err := LoadKey(AlgorithmES256, writePKCS8(t, ecKey))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// A token signed with HS256 must not be verified against the public key
+	hmacKey, _ := NewHMACKey("secret")
+	tokenString, _ := hmacKey.Sign(jwt.MapClaims{"sub": "user-1"})
+	if _, err := jwt.Parse(tokenString, key.VerificationKey); err == nil {
+		t.Error("Expected token with another algorithm to be rejected")
+	}
+}
+
+func TestThumbprint(t *testing.T) {
+	// RFC 7638 section 3.1 example
+	jwk := JWK{
+		KeyType: "RSA",
+		N:       "0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECPebWKRXjBZCiFV4n3oknjhMstn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n91CbOpbISD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0fM4lFd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw",
+		E:       "AQAB",
+	}
+
+	if got, want := jwk.Thumbprint(), "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs"; got != want {
+		t.Errorf("Expected thumbprint %s, got %s", want, got)
+	}
+}
+
+func TestJWK_PublicKey(t *testing.T) {
+	for _, algorithm := range []string{AlgorithmRS256, AlgorithmES256, AlgorithmEdDSA} {
+		t.Run(algorithm, func(t *testing.T) {
+			pemData, err := GenerateKey(algorithm)
+			if err != nil {
+				t.Fatalf("Failed to generate key: %v", err)
+			}
+			key, err := ParseKey(algorithm, pemData)
+			if err != nil {
+				t.Fatalf("Failed to parse key: %v", err)
+			}
+			jwk, ok := key.JWK()
+			if !ok {
+				t.Fatal("Expected a JWK")
+			}
+
+			public, err := jwk.PublicKey()
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if !key.PublicKey().(interface{ Equal(crypto.PublicKey) bool }).Equal(public) {
+				t.Errorf("Expected the decoded key to match")
+			}
+		})
+	}
+
+	invalid := []JWK{
+		{KeyType: "oct"},
+		{KeyType: "RSA", N: "AQAB", E: "AQAB"},
+		{KeyType: "EC", Curve: "P-384", X: "AA", Y: "AA"},
+		{KeyType: "EC", Curve: "P-256", X: "AQ", Y: "AQ"},
+		{KeyType: "OKP", Curve: "Ed25519", X: "AQAB"},
+	}
+	for _, jwk := range invalid {
+		if _, err := jwk.PublicKey(); err == nil {
+			t.Errorf("Expected error for %+v", jwk)
+		}
+	}
+}
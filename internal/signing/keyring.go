@@ -0,0 +1,270 @@
+package signing
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"otp-auth-service/internal/config"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// KeyFileTimeFormat names key files in a keys directory after the time
+// they activate, e.g. 20240101T120000Z.pem
+const KeyFileTimeFormat = "20060102T150405Z"
+
+// Keyring holds every key tokens may be signed with. One key signs new
+// tokens; the others remain valid for verification so that rotating keys
+// does not invalidate tokens already issued.
+type Keyring struct {
+	mu     sync.RWMutex
+	keys   []*Key // ordered by activation, oldest first
+	byID   map[string]*Key
+	load   func() ([]*Key, error)
+	source string
+	now    func() time.Time
+}
+
+// NewKeyring loads the keys selected by the configuration:
+//   - HS256: JWT_SECRET signs, JWT_PREVIOUS_SECRETS are accepted for verification
+//   - asymmetric algorithms: every key in JWT_KEYS_DIR, or the single key in
+//     JWT_PRIVATE_KEY_FILE
+func NewKeyring(cfg *config.Config) (*Keyring, error) {
+	var (
+		load   func() ([]*Key, error)
+		source string
+	)
+
+	switch cfg.JWTAlgorithm {
+	case "", AlgorithmHS256:
+		load = func() ([]*Key, error) { return hmacKeys(cfg.JWTSecret, cfg.JWTPreviousSecrets) }
+		source = "JWT_SECRET"
+	default:
+		switch {
+		case cfg.JWTKeysDir != "" && cfg.JWTPrivateKeyFile != "":
+			return nil, fmt.Errorf("set either JWT_KEYS_DIR or JWT_PRIVATE_KEY_FILE, not both")
+		case cfg.JWTKeysDir != "":
+			load = func() ([]*Key, error) { return LoadKeysDir(cfg.JWTAlgorithm, cfg.JWTKeysDir) }
+			source = cfg.JWTKeysDir
+		case cfg.JWTPrivateKeyFile != "":
+			load = func() ([]*Key, error) {
+				key, err := LoadKey(cfg.JWTAlgorithm, cfg.JWTPrivateKeyFile)
+				if err != nil {
+					return nil, err
+				}
+				return []*Key{key}, nil
+			}
+			source = cfg.JWTPrivateKeyFile
+		default:
+			return nil, fmt.Errorf("JWT_KEYS_DIR or JWT_PRIVATE_KEY_FILE is required for %s", cfg.JWTAlgorithm)
+		}
+	}
+
+	keyring := &Keyring{load: load, source: source, now: time.Now}
+	if err := keyring.Reload(); err != nil {
+		return nil, err
+	}
+
+	return keyring, nil
+}
+
+// NewStaticKeyring returns a keyring of fixed keys, oldest first
+func NewStaticKeyring(keys ...*Key) (*Keyring, error) {
+	keyring := &Keyring{
+		load:   func() ([]*Key, error) { return keys, nil },
+		source: "static keys",
+		now:    time.Now,
+	}
+	if err := keyring.Reload(); err != nil {
+		return nil, err
+	}
+
+	return keyring, nil
+}
+
+// hmacKeys returns the previous secrets followed by the current one; with
+// equal activation times the last key is the active one
+func hmacKeys(secret string, previousSecrets []string) ([]*Key, error) {
+	var keys []*Key
+	for _, previous := range previousSecrets {
+		key, err := NewHMACKey(previous)
+		if err != nil {
+			return nil, fmt.Errorf("JWT_PREVIOUS_SECRETS: %w", err)
+		}
+		keys = append(keys, key)
+	}
+
+	current, err := NewHMACKey(secret)
+	if err != nil {
+		return nil, fmt.Errorf("JWT_SECRET: %w", err)
+	}
+
+	return append(keys, current), nil
+}
+
+// keyFile is a private key file in a keys directory
+type keyFile struct {
+	path        string
+	activatesAt time.Time
+}
+
+// listKeyFiles returns the *.pem files of dir, oldest activation first. A
+// file named after KeyFileTimeFormat activates at that time, others when
+// they were last modified.
+func listKeyFiles(dir string) ([]keyFile, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]keyFile, 0, len(paths))
+	for _, path := range paths {
+		name := strings.TrimSuffix(filepath.Base(path), ".pem")
+		activatesAt, err := time.Parse(KeyFileTimeFormat, name)
+		if err != nil {
+			info, err := os.Stat(path)
+			if err != nil {
+				return nil, err
+			}
+			activatesAt = info.ModTime()
+		}
+		files = append(files, keyFile{path: path, activatesAt: activatesAt})
+	}
+
+	sort.SliceStable(files, func(i, j int) bool {
+		return files[i].activatesAt.Before(files[j].activatesAt)
+	})
+
+	return files, nil
+}
+
+// LoadKeysDir loads every key file of dir
+func LoadKeysDir(algorithm, dir string) ([]*Key, error) {
+	files, err := listKeyFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]*Key, 0, len(files))
+	for _, file := range files {
+		key, err := LoadKey(algorithm, file.path)
+		if err != nil {
+			return nil, err
+		}
+		key.ActivatesAt = file.activatesAt
+		keys = append(keys, key)
+	}
+
+	return keys, nil
+}
+
+// Reload reads the keys again from their source. The keyring is left
+// untouched if loading fails.
+func (r *Keyring) Reload() error {
+	keys, err := r.load()
+	if err != nil {
+		return err
+	}
+	if len(keys) == 0 {
+		return fmt.Errorf("no signing keys found in %s", r.source)
+	}
+
+	sorted := make([]*Key, len(keys))
+	copy(sorted, keys)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].ActivatesAt.Before(sorted[j].ActivatesAt)
+	})
+
+	byID := make(map[string]*Key, len(sorted))
+	for _, key := range sorted {
+		byID[key.ID] = key
+	}
+
+	r.mu.Lock()
+	r.keys = sorted
+	r.byID = byID
+	r.mu.Unlock()
+
+	return nil
+}
+
+// Watch reloads the keyring every interval until stop is closed, so keys
+// added by a rotation on another host are picked up
+func (r *Keyring) Watch(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := r.Reload(); err != nil {
+				log.Printf("Failed to reload signing keys: %v", err)
+			}
+		case <-stop:
+			return
+		}
+	}
+}
+
+// Active returns the key new tokens are signed with: the most recently
+// activated key, or the oldest key if none has activated yet
+func (r *Keyring) Active() *Key {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	now := r.now()
+	active := r.keys[0]
+	for _, key := range r.keys {
+		if key.ActivatesAt.After(now) {
+			break
+		}
+		active = key
+	}
+
+	return active
+}
+
+// Sign signs the claims with the active key
+func (r *Keyring) Sign(claims jwt.Claims) (string, error) {
+	return r.Active().Sign(claims)
+}
+
+// Keyfunc selects the verification key by the token's kid header. Tokens
+// without a kid or with an unknown one are rejected.
+func (r *Keyring) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		return nil, fmt.Errorf("token has no key ID")
+	}
+
+	r.mu.RLock()
+	key, ok := r.byID[kid]
+	r.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+
+	return key.VerificationKey(token)
+}
+
+// JWKS returns the public keys of the keyring, including keys that are
+// not active yet. It is empty for HMAC keys.
+func (r *Keyring) JWKS() *JWKS {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	jwks := &JWKS{Keys: []JWK{}}
+	for i := len(r.keys) - 1; i >= 0; i-- {
+		if jwk, ok := r.keys[i].JWK(); ok {
+			jwks.Keys = append(jwks.Keys, jwk)
+		}
+	}
+
+	return jwks
+}
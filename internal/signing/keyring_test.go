@@ -0,0 +1,196 @@
+package signing
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"otp-auth-service/internal/config"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func parseWith(keyring *Keyring, tokenString string) error {
+	_, err := jwt.Parse(tokenString, keyring.Keyfunc)
+	return err
+}
+
+func TestKeyring_HMACPreviousSecrets(t *testing.T) {
+	oldKeyring, err := NewKeyring(&config.Config{JWTAlgorithm: AlgorithmHS256, JWTSecret: "old-secret"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	oldToken, _ := oldKeyring.Sign(jwt.MapClaims{"sub": "user-1"})
+
+	keyring, err := NewKeyring(&config.Config{
+		JWTAlgorithm:       AlgorithmHS256,
+		JWTSecret:          "new-secret",
+		JWTPreviousSecrets: []string{"old-secret"},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	newToken, _ := keyring.Sign(jwt.MapClaims{"sub": "user-1"})
+	if err := parseWith(keyring, newToken); err != nil {
+		t.Errorf("Expected token signed with the new secret to be valid, got %v", err)
+	}
+	if err := parseWith(oldKeyring, newToken); err == nil {
+		t.Error("Expected the new secret to be used for signing")
+	}
+	if err := parseWith(keyring, oldToken); err != nil {
+		t.Errorf("Expected token signed with a previous secret to be valid, got %v", err)
+	}
+	if len(keyring.JWKS().Keys) != 0 {
+		t.Error("Expected HMAC secrets not to be published")
+	}
+
+	// Once the old secret is dropped its tokens are rejected
+	dropped, _ := NewKeyring(&config.Config{JWTAlgorithm: AlgorithmHS256, JWTSecret: "new-secret"})
+	if err := parseWith(dropped, oldToken); err == nil {
+		t.Error("Expected token of an unknown key to be rejected")
+	}
+}
+
+func TestKeyring_RejectsMissingKeyID(t *testing.T) {
+	keyring, _ := NewKeyring(&config.Config{JWTAlgorithm: AlgorithmHS256, JWTSecret: "secret"})
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"sub": "user-1"})
+	tokenString, _ := token.SignedString([]byte("secret"))
+
+	if err := parseWith(keyring, tokenString); err == nil {
+		t.Error("Expected token without kid to be rejected")
+	}
+}
+
+func TestKeyring_Dir(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+
+	writeKey := func(activatesAt time.Time) {
+		pemData, err := GenerateKey(AlgorithmES256)
+		if err != nil {
+			t.Fatalf("Failed to generate key: %v", err)
+		}
+		path := filepath.Join(dir, activatesAt.UTC().Format(KeyFileTimeFormat)+".pem")
+		if err := os.WriteFile(path, pemData, 0600); err != nil {
+			t.Fatalf("Failed to write key: %v", err)
+		}
+	}
+
+	writeKey(now.Add(-time.Hour))
+	keyring, err := NewKeyring(&config.Config{JWTAlgorithm: AlgorithmES256, JWTKeysDir: dir})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	first := keyring.Active()
+	oldToken, _ := keyring.Sign(jwt.MapClaims{"sub": "user-1"})
+
+	// A key that activates later is published but does not sign yet
+	writeKey(now.Add(time.Hour))
+	if err := keyring.Reload(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if keyring.Active() != keyring.byID[first.ID] {
+		t.Error("Expected the pending key not to sign yet")
+	}
+	jwks := keyring.JWKS()
+	if len(jwks.Keys) != 2 {
+		t.Fatalf("Expected 2 published keys, got %d", len(jwks.Keys))
+	}
+
+	// Once it activates it signs, and old tokens remain valid
+	keyring.now = func() time.Time { return now.Add(2 * time.Hour) }
+	second := keyring.Active()
+	if second.ID == first.ID {
+		t.Fatal("Expected the new key to be active")
+	}
+	newToken, _ := keyring.Sign(jwt.MapClaims{"sub": "user-1"})
+	token, err := jwt.Parse(newToken, keyring.Keyfunc)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if token.Header["kid"] != second.ID {
+		t.Errorf("Expected kid %s, got %v", second.ID, token.Header["kid"])
+	}
+	if err := parseWith(keyring, oldToken); err != nil {
+		t.Errorf("Expected token of the previous key to stay valid, got %v", err)
+	}
+}
+
+func TestKeyring_ReloadKeepsKeysOnError(t *testing.T) {
+	dir := t.TempDir()
+	pemData, _ := GenerateKey(AlgorithmEdDSA)
+	os.WriteFile(filepath.Join(dir, "key.pem"), pemData, 0600)
+
+	keyring, err := NewKeyring(&config.Config{JWTAlgorithm: AlgorithmEdDSA, JWTKeysDir: dir})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	os.WriteFile(filepath.Join(dir, "broken.pem"), []byte("not a key"), 0600)
+	if err := keyring.Reload(); err == nil {
+		t.Error("Expected reload to fail")
+	}
+	if _, err := keyring.Sign(jwt.MapClaims{"sub": "user-1"}); err != nil {
+		t.Errorf("Expected keyring to keep working, got %v", err)
+	}
+}
+
+func TestNewKeyring_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  *config.Config
+	}{
+		{name: "empty secret", cfg: &config.Config{JWTAlgorithm: AlgorithmHS256}},
+		{name: "no key source", cfg: &config.Config{JWTAlgorithm: AlgorithmRS256}},
+		{name: "both key sources", cfg: &config.Config{JWTAlgorithm: AlgorithmRS256, JWTKeysDir: "keys", JWTPrivateKeyFile: "key.pem"}},
+		{name: "empty keys dir", cfg: &config.Config{JWTAlgorithm: AlgorithmRS256, JWTKeysDir: t.TempDir()}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewKeyring(tt.cfg); err == nil {
+				t.Error("Expected error")
+			}
+		})
+	}
+}
+
+func TestRotateKeys(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+
+	// A key replaced two hours ago, and the key that replaced it
+	retired := filepath.Join(dir, now.Add(-3*time.Hour).UTC().Format(KeyFileTimeFormat)+".pem")
+	current := filepath.Join(dir, now.Add(-2*time.Hour).UTC().Format(KeyFileTimeFormat)+".pem")
+	for _, path := range []string{retired, current} {
+		pemData, _ := GenerateKey(AlgorithmEdDSA)
+		os.WriteFile(path, pemData, 0600)
+	}
+
+	path, removed, err := RotateKeys(dir, AlgorithmEdDSA, now.Add(5*time.Minute), time.Hour)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(removed) != 1 || removed[0] != retired {
+		t.Errorf("Expected only %s to be removed, got %v", retired, removed)
+	}
+
+	keys, err := LoadKeysDir(AlgorithmEdDSA, dir)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(keys) != 2 {
+		t.Fatalf("Expected 2 keys, got %d", len(keys))
+	}
+	if !keys[1].ActivatesAt.After(now) {
+		t.Errorf("Expected new key %s to activate in the future", path)
+	}
+
+	// The key being replaced now must stay until its tokens expire
+	if _, removed, _ := RotateKeys(dir, AlgorithmEdDSA, now.Add(10*time.Minute), time.Hour); len(removed) != 0 {
+		t.Errorf("Expected no key to be removed, got %v", removed)
+	}
+}
@@ -0,0 +1,66 @@
+package signing
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// RotateKeys adds a new key to dir that starts signing at activateAt, and
+// removes keys whose tokens have all expired: those replaced by a newer
+// key more than retireAfter ago. retireAfter must be at least the access
+// token lifetime. It returns the new key file and the removed ones.
+func RotateKeys(dir, algorithm string, activateAt time.Time, retireAfter time.Duration) (string, []string, error) {
+	pemData, err := GenerateKey(algorithm)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", nil, err
+	}
+
+	path := filepath.Join(dir, activateAt.UTC().Format(KeyFileTimeFormat)+".pem")
+	// O_EXCL so two rotations in the same second cannot overwrite a key
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create key file: %w", err)
+	}
+	if _, err := file.Write(pemData); err != nil {
+		file.Close()
+		return "", nil, err
+	}
+	if err := file.Close(); err != nil {
+		return "", nil, err
+	}
+
+	removed, err := pruneKeys(dir, time.Now().Add(-retireAfter))
+	if err != nil {
+		return path, nil, err
+	}
+
+	return path, removed, nil
+}
+
+// pruneKeys removes key files that were replaced by a newer, active key
+// before the cutoff
+func pruneKeys(dir string, cutoff time.Time) ([]string, error) {
+	files, err := listKeyFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var removed []string
+	for i := 0; i < len(files)-1; i++ {
+		if files[i+1].activatesAt.After(cutoff) {
+			break
+		}
+		if err := os.Remove(files[i].path); err != nil {
+			return removed, err
+		}
+		removed = append(removed, files[i].path)
+	}
+
+	return removed, nil
+}
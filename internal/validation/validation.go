@@ -4,16 +4,23 @@ import (
 	"fmt"
 	"regexp"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 
+	"otp-auth-service/internal/delivery"
 	"otp-auth-service/internal/errors"
+	"otp-auth-service/internal/models"
 )
 
 // PhoneNumberRegex is a regex pattern for international phone numbers
 var PhoneNumberRegex = regexp.MustCompile(`^\+[1-9]\d{1,14}$`)
 
-// OTPRegex is a regex pattern for 6-digit OTP codes
+// OTPRegex is a regex pattern for OTP codes in the configured format (6 digits by default)
 var OTPRegex = regexp.MustCompile(`^\d{6}$`)
 
+// otpFormatDescription describes the configured OTP format in validation errors
+var otpFormatDescription = "6 digits"
+
 // UUIDRegex is a regex pattern for UUID validation
 var UUIDRegex = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
 
@@ -51,13 +58,24 @@ func ValidateOTP(otp string) error {
 	
 	if !OTPRegex.MatchString(otp) {
 		return errors.ErrInvalidOTPFormat.WithDetails(
-			fmt.Sprintf("OTP must be exactly 6 digits, got '%s'", otp),
+			fmt.Sprintf("OTP must be exactly %s, got '%s'", otpFormatDescription, otp),
 		)
 	}
 
 	return nil
 }
 
+// SetOTPFormat configures the OTP length and characters accepted by ValidateOTP
+func SetOTPFormat(length int, charset string) {
+	OTPRegex = regexp.MustCompile(fmt.Sprintf(`^[%s]{%d}$`, regexp.QuoteMeta(charset), length))
+
+	if strings.Trim(charset, "0123456789") == "" {
+		otpFormatDescription = fmt.Sprintf("%d digits", length)
+	} else {
+		otpFormatDescription = fmt.Sprintf("%d characters from '%s'", length, charset)
+	}
+}
+
 // ValidateUUID validates UUID format
 func ValidateUUID(uuid string) error {
 	if uuid == "" {
@@ -133,8 +151,178 @@ func ValidateVerifyOTP(phoneNumber, otp string) error {
 	return nil
 }
 
+// ValidateChannel validates an optional delivery channel
+func ValidateChannel(channel string) error {
+	switch delivery.Channel(channel) {
+	case "", delivery.ChannelSMS, delivery.ChannelVoice, delivery.ChannelWhatsApp:
+		return nil
+	default:
+		return errors.ErrInvalidChannel.WithDetails(
+			fmt.Sprintf("channel '%s' must be one of sms, voice, whatsapp", channel),
+		)
+	}
+}
+
+// ValidateOTPPurpose validates an optional OTP purpose
+func ValidateOTPPurpose(purpose models.OTPPurpose) error {
+	if purpose == "" || purpose.IsValid() {
+		return nil
+	}
+
+	return errors.ErrInvalidOTPPurpose.WithDetails(
+		fmt.Sprintf("purpose '%s' must be one of login, phone_change, account_deletion, transaction_confirm", purpose),
+	)
+}
+
+// ValidateLoginOTPPurpose validates the purpose of OTPs requested without
+// signing in. Those may go to numbers nobody has proven to own, so only
+// login OTPs are sent.
+func ValidateLoginOTPPurpose(purpose models.OTPPurpose) error {
+	if err := ValidateOTPPurpose(purpose); err != nil {
+		return err
+	}
+
+	if purpose != "" && purpose != models.PurposeLogin {
+		return errors.ErrInvalidOTPPurpose.WithDetails(
+			fmt.Sprintf("%s OTPs are requested by signed in users at /users/me/otp", purpose),
+		)
+	}
+
+	return nil
+}
+
+// ValidateUserOTPPurpose validates the purpose of OTPs requested by signed
+// in users, which confirm flows other than login
+func ValidateUserOTPPurpose(purpose models.OTPPurpose) error {
+	if !purpose.IsValid() || purpose == models.PurposeLogin {
+		return errors.ErrInvalidOTPPurpose.WithDetails(
+			fmt.Sprintf("purpose '%s' must be one of phone_change, account_deletion, transaction_confirm", purpose),
+		)
+	}
+
+	return nil
+}
+
+// ValidateResendOTP validates ResendOTP request
+func ValidateResendOTP(phoneNumber, channel string) error {
+	if err := ValidatePhoneNumber(phoneNumber); err != nil {
+		return err
+	}
+
+	return ValidateChannel(channel)
+}
+
+// MaxDeviceNameLength is the longest device name a session can carry
+const MaxDeviceNameLength = 64
+
+// ValidateDeviceName validates the optional device name of a session
+func ValidateDeviceName(deviceName string) error {
+	if len(deviceName) > MaxDeviceNameLength {
+		return errors.ErrInvalidRequest.WithDetails(
+			fmt.Sprintf("device name must be at most %d characters", MaxDeviceNameLength),
+		)
+	}
+
+	for _, r := range deviceName {
+		if !unicode.IsPrint(r) {
+			return errors.ErrInvalidRequest.WithDetails("device name contains invalid characters")
+		}
+	}
+
+	return nil
+}
+
+// MaxNameLength is the maximum length of a user's name in characters
+const MaxNameLength = 100
+
+// LocaleRegex is a regex pattern for BCP 47 language tags such as en or de-DE
+var LocaleRegex = regexp.MustCompile(`^[a-zA-Z]{2,3}(-[a-zA-Z0-9]{2,8})*$`)
+
+// ValidateUpdateProfile validates UpdateProfile request
+func ValidateUpdateProfile(req *models.UpdateProfileRequest) error {
+	if req.Name == nil && req.Locale == nil {
+		return errors.ErrInvalidProfile.WithDetails("name or locale is required")
+	}
+
+	if req.Name != nil {
+		if utf8.RuneCountInString(*req.Name) > MaxNameLength {
+			return errors.ErrInvalidProfile.WithDetails(
+				fmt.Sprintf("name must be at most %d characters", MaxNameLength),
+			)
+		}
+		for _, r := range *req.Name {
+			if !unicode.IsPrint(r) {
+				return errors.ErrInvalidProfile.WithDetails("name contains invalid characters")
+			}
+		}
+	}
+
+	if req.Locale != nil && *req.Locale != "" && !LocaleRegex.MatchString(*req.Locale) {
+		return errors.ErrInvalidProfile.WithDetails("locale must be a language tag such as en or de-DE")
+	}
+
+	return nil
+}
+
+// ValidateRevokeSession validates RevokeSession request parameters
+func ValidateRevokeSession(sessionID string) error {
+	if sessionID == "" {
+		return errors.ErrMissingRequiredField.WithDetails("session ID is required")
+	}
+
+	return ValidateUUID(sessionID)
+}
+
+// ValidateAuthorizationRequestID validates the ID of a pending OAuth
+// authorization request
+func ValidateAuthorizationRequestID(requestID string) error {
+	if requestID == "" {
+		return errors.ErrMissingRequiredField.WithDetails("authorization request ID is required")
+	}
+
+	return ValidateUUID(requestID)
+}
+
+// ValidateUserSort validates the sort field and order of the users list
+func ValidateUserSort(sort, order string) error {
+	if sort != "" {
+		valid := false
+		for _, field := range models.UserSortFields {
+			if sort == field {
+				valid = true
+			}
+		}
+		if !valid {
+			return errors.ErrInvalidSort.WithDetails("sort must be one of " + strings.Join(models.UserSortFields, ", "))
+		}
+	}
+
+	if order != "" && order != models.SortOrderAsc && order != models.SortOrderDesc {
+		return errors.ErrInvalidSort.WithDetails("order must be asc or desc")
+	}
+
+	return nil
+}
+
+// ValidateUserCursor validates a users list cursor. Sort and order may be
+// omitted with a cursor; if given, they must be the ones it was issued for.
+func ValidateUserCursor(cursor, sort, order string) error {
+	parsed, err := models.ParseUserCursor(cursor)
+	if err != nil {
+		return errors.ErrInvalidCursor.WithDetails(err.Error())
+	}
+
+	if (sort != "" && sort != parsed.Sort) || (order != "" && (order == models.SortOrderDesc) != parsed.Desc) {
+		return errors.ErrInvalidCursor.WithDetails("cursor was issued for another sort order")
+	}
+
+	return nil
+}
+
 // ValidateGetUsers validates GetUsers request parameters
-func ValidateGetUsers(pageStr, limitStr, search string) error {
+func ValidateGetUsers(req *models.GetUsersRequest) error {
+	pageStr, limitStr, search := req.Page, req.Limit, req.Search
+
 	// Parse and validate page
 	page := 1
 	if pageStr != "" {
@@ -165,6 +353,26 @@ func ValidateGetUsers(pageStr, limitStr, search string) error {
 		return errors.ErrInvalidSearchQuery.WithDetails(err.Error())
 	}
 
+	// Validate filters
+	if _, err := req.Filter(); err != nil {
+		return errors.ErrInvalidFilter.WithDetails(err.Error())
+	}
+
+	// Validate sort order
+	if err := ValidateUserSort(req.Sort, req.Order); err != nil {
+		return err
+	}
+
+	// Validate cursor
+	if req.Cursor != "" {
+		if pageStr != "" {
+			return errors.ErrInvalidPagination.WithDetails("page cannot be combined with cursor")
+		}
+		if err := ValidateUserCursor(req.Cursor, req.Sort, req.Order); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
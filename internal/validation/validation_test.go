@@ -1,9 +1,11 @@
 package validation
 
 import (
+	"strings"
 	"testing"
 
 	"otp-auth-service/internal/errors"
+	"otp-auth-service/internal/models"
 )
 
 func TestValidatePhoneNumber(t *testing.T) {
@@ -417,53 +419,104 @@ func TestValidateVerifyOTP(t *testing.T) {
 }
 
 func TestValidateGetUsers(t *testing.T) {
+	cursor := models.NewUserCursor(models.NewUser("+1234567890"), models.UserSortPhoneNumber, true).Encode()
+
 	tests := []struct {
-		name     string
-		pageStr  string
-		limitStr string
-		search   string
-		wantErr  bool
+		name    string
+		req     models.GetUsersRequest
+		wantErr bool
 	}{
 		{
-			name:     "valid parameters",
-			pageStr:  "1",
-			limitStr: "10",
-			search:   "john",
-			wantErr:  false,
+			name:    "valid parameters",
+			req:     models.GetUsersRequest{Page: "1", Limit: "10", Search: "john"},
+			wantErr: false,
+		},
+		{
+			name:    "empty parameters",
+			req:     models.GetUsersRequest{},
+			wantErr: false,
+		},
+		{
+			name:    "invalid page",
+			req:     models.GetUsersRequest{Page: "0", Limit: "10"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid limit",
+			req:     models.GetUsersRequest{Page: "1", Limit: "101"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid search query",
+			req:     models.GetUsersRequest{Page: "1", Limit: "10", Search: "ab"},
+			wantErr: true,
+		},
+		{
+			name:    "valid sort",
+			req:     models.GetUsersRequest{Sort: "last_login_at", Order: "desc"},
+			wantErr: false,
+		},
+		{
+			name:    "invalid sort field",
+			req:     models.GetUsersRequest{Sort: "id"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid order",
+			req:     models.GetUsersRequest{Sort: "phone_number", Order: "up"},
+			wantErr: true,
 		},
 		{
-			name:     "empty parameters",
-			pageStr:  "",
-			limitStr: "",
-			search:   "",
-			wantErr:  false,
+			name: "valid filters",
+			req: models.GetUsersRequest{Search: "+4915", IsActive: "false", RegisteredAfter: "2024-01-01",
+				RegisteredBefore: "2024-02-01T12:00:00Z", LastLoginAfter: "2024-03-01T00:00:00+02:00"},
+			wantErr: false,
+		},
+		{
+			name:    "invalid status filter",
+			req:     models.GetUsersRequest{IsActive: "maybe"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid date filter",
+			req:     models.GetUsersRequest{LastLoginBefore: "01/02/2024"},
+			wantErr: true,
 		},
 		{
-			name:     "invalid page",
-			pageStr:  "0",
-			limitStr: "10",
-			search:   "",
-			wantErr:  true,
+			name:    "empty date range",
+			req:     models.GetUsersRequest{RegisteredAfter: "2024-02-01", RegisteredBefore: "2024-01-01"},
+			wantErr: true,
+		},
+		{
+			name:    "cursor",
+			req:     models.GetUsersRequest{Limit: "20", Cursor: cursor},
+			wantErr: false,
+		},
+		{
+			name:    "cursor with its sort order",
+			req:     models.GetUsersRequest{Sort: "phone_number", Order: "desc", Cursor: cursor},
+			wantErr: false,
+		},
+		{
+			name:    "cursor with another sort order",
+			req:     models.GetUsersRequest{Sort: "phone_number", Order: "asc", Cursor: cursor},
+			wantErr: true,
 		},
 		{
-			name:     "invalid limit",
-			pageStr:  "1",
-			limitStr: "101",
-			search:   "",
-			wantErr:  true,
+			name:    "cursor with page",
+			req:     models.GetUsersRequest{Page: "2", Cursor: cursor},
+			wantErr: true,
 		},
 		{
-			name:     "invalid search query",
-			pageStr:  "1",
-			limitStr: "10",
-			search:   "ab",
-			wantErr:  true,
+			name:    "malformed cursor",
+			req:     models.GetUsersRequest{Cursor: "not-a-cursor"},
+			wantErr: true,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			err := ValidateGetUsers(tt.pageStr, tt.limitStr, tt.search)
+			err := ValidateGetUsers(&tt.req)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("ValidateGetUsers() error = %v, wantErr %v", err, tt.wantErr)
 			}
@@ -551,3 +604,230 @@ func TestParsePositiveInt(t *testing.T) {
 		})
 	}
 }
+
+func TestSetOTPFormat(t *testing.T) {
+	defer SetOTPFormat(6, "0123456789")
+
+	SetOTPFormat(8, "23456789ABCDEFGHJKMNPQRSTUVWXYZ")
+
+	tests := []struct {
+		name    string
+		otp     string
+		wantErr bool
+	}{
+		{name: "valid alphanumeric OTP", otp: "AB23CD45", wantErr: false},
+		{name: "valid digits only", otp: "23456789", wantErr: false},
+		{name: "too short", otp: "AB23CD4", wantErr: true},
+		{name: "ambiguous character", otp: "AB23CD40", wantErr: true},
+		{name: "lowercase", otp: "ab23cd45", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateOTP(tt.otp)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateOTP() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	SetOTPFormat(4, "0123456789")
+	err := ValidateOTP("12345")
+	if err == nil {
+		t.Fatal("Expected error for 5-digit OTP with 4-digit format")
+	}
+	if domainErr, ok := err.(*errors.DomainError); !ok || !strings.Contains(domainErr.Details, "4 digits") {
+		t.Errorf("Expected details to describe 4-digit format, got %v", err)
+	}
+}
+
+func TestValidateResendOTP(t *testing.T) {
+	tests := []struct {
+		name        string
+		phoneNumber string
+		channel     string
+		wantErr     bool
+		errCode     string
+	}{
+		{name: "default channel", phoneNumber: "+1234567890", channel: "", wantErr: false},
+		{name: "sms", phoneNumber: "+1234567890", channel: "sms", wantErr: false},
+		{name: "voice", phoneNumber: "+1234567890", channel: "voice", wantErr: false},
+		{name: "whatsapp", phoneNumber: "+1234567890", channel: "whatsapp", wantErr: false},
+		{name: "unknown channel", phoneNumber: "+1234567890", channel: "fax", wantErr: true, errCode: "INVALID_CHANNEL"},
+		{name: "invalid phone number", phoneNumber: "123", channel: "sms", wantErr: true, errCode: "INVALID_PHONE_NUMBER"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateResendOTP(tt.phoneNumber, tt.channel)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateResendOTP() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				if domainErr, ok := err.(*errors.DomainError); !ok || domainErr.Code != tt.errCode {
+					t.Errorf("Expected error code %s, got %v", tt.errCode, err)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateOTPPurpose(t *testing.T) {
+	tests := []struct {
+		name    string
+		purpose models.OTPPurpose
+		wantErr bool
+	}{
+		{name: "default", purpose: "", wantErr: false},
+		{name: "login", purpose: models.PurposeLogin, wantErr: false},
+		{name: "phone change", purpose: models.PurposePhoneChange, wantErr: false},
+		{name: "account deletion", purpose: models.PurposeAccountDeletion, wantErr: false},
+		{name: "transaction confirm", purpose: models.PurposeTransactionConfirm, wantErr: false},
+		{name: "unknown", purpose: "password_reset", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateOTPPurpose(tt.purpose)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateOTPPurpose() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if domainErr, ok := err.(*errors.DomainError); !ok || domainErr.Code != "INVALID_OTP_PURPOSE" {
+					t.Errorf("Expected INVALID_OTP_PURPOSE, got %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateLoginAndUserOTPPurpose(t *testing.T) {
+	tests := []struct {
+		purpose  models.OTPPurpose
+		loginErr bool
+		userErr  bool
+	}{
+		{purpose: "", loginErr: false, userErr: true},
+		{purpose: models.PurposeLogin, loginErr: false, userErr: true},
+		{purpose: models.PurposePhoneChange, loginErr: true, userErr: false},
+		{purpose: models.PurposeAccountDeletion, loginErr: true, userErr: false},
+		{purpose: models.PurposeTransactionConfirm, loginErr: true, userErr: false},
+		{purpose: "password_reset", loginErr: true, userErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.purpose), func(t *testing.T) {
+			if err := ValidateLoginOTPPurpose(tt.purpose); (err != nil) != tt.loginErr {
+				t.Errorf("ValidateLoginOTPPurpose() error = %v, wantErr %v", err, tt.loginErr)
+			}
+			if err := ValidateUserOTPPurpose(tt.purpose); (err != nil) != tt.userErr {
+				t.Errorf("ValidateUserOTPPurpose() error = %v, wantErr %v", err, tt.userErr)
+			}
+		})
+	}
+}
+
+func TestValidateDeviceName(t *testing.T) {
+	tests := []struct {
+		name       string
+		deviceName string
+		wantErr    bool
+	}{
+		{name: "empty", deviceName: "", wantErr: false},
+		{name: "plain", deviceName: "Pixel 8", wantErr: false},
+		{name: "unicode", deviceName: "iPhone von Jürgen", wantErr: false},
+		{name: "too long", deviceName: strings.Repeat("a", MaxDeviceNameLength+1), wantErr: true},
+		{name: "control characters", deviceName: "Pixel\n8", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateDeviceName(tt.deviceName)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateDeviceName() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateUpdateProfile(t *testing.T) {
+	str := func(s string) *string { return &s }
+
+	tests := []struct {
+		name    string
+		req     models.UpdateProfileRequest
+		wantErr bool
+	}{
+		{name: "name and locale", req: models.UpdateProfileRequest{Name: str("Jürgen Müller"), Locale: str("de-DE")}, wantErr: false},
+		{name: "clear fields", req: models.UpdateProfileRequest{Name: str(""), Locale: str("")}, wantErr: false},
+		{name: "language only", req: models.UpdateProfileRequest{Locale: str("en")}, wantErr: false},
+		{name: "no fields", req: models.UpdateProfileRequest{}, wantErr: true},
+		{name: "name too long", req: models.UpdateProfileRequest{Name: str(strings.Repeat("ä", MaxNameLength+1))}, wantErr: true},
+		{name: "control characters", req: models.UpdateProfileRequest{Name: str("Jane\x00Doe")}, wantErr: true},
+		{name: "invalid locale", req: models.UpdateProfileRequest{Locale: str("German")}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateUpdateProfile(&tt.req)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateUpdateProfile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateRevokeSession(t *testing.T) {
+	tests := []struct {
+		name      string
+		sessionID string
+		wantCode  string
+	}{
+		{name: "valid", sessionID: "550e8400-e29b-41d4-a716-446655440000"},
+		{name: "empty", sessionID: "", wantCode: "MISSING_REQUIRED_FIELD"},
+		{name: "invalid", sessionID: "not-a-session", wantCode: "INVALID_UUID"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateRevokeSession(tt.sessionID)
+			if tt.wantCode == "" {
+				if err != nil {
+					t.Errorf("Expected no error, got %v", err)
+				}
+				return
+			}
+			if domainErr, ok := err.(*errors.DomainError); !ok || domainErr.Code != tt.wantCode {
+				t.Errorf("Expected %s, got %v", tt.wantCode, err)
+			}
+		})
+	}
+}
+
+func TestValidateAuthorizationRequestID(t *testing.T) {
+	tests := []struct {
+		name      string
+		requestID string
+		wantCode  string
+	}{
+		{name: "valid", requestID: "550e8400-e29b-41d4-a716-446655440000"},
+		{name: "empty", requestID: "", wantCode: "MISSING_REQUIRED_FIELD"},
+		{name: "invalid", requestID: "../consent", wantCode: "INVALID_UUID"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateAuthorizationRequestID(tt.requestID)
+			if tt.wantCode == "" {
+				if err != nil {
+					t.Errorf("Expected no error, got %v", err)
+				}
+				return
+			}
+			if domainErr, ok := err.(*errors.DomainError); !ok || domainErr.Code != tt.wantCode {
+				t.Errorf("Expected %s, got %v", tt.wantCode, err)
+			}
+		})
+	}
+}
@@ -3,13 +3,19 @@ package main
 import (
 	"log"
 	"os"
+	"strings"
 
 	"otp-auth-service/docs"
 	"otp-auth-service/internal/config"
+	"otp-auth-service/internal/delivery"
 	"otp-auth-service/internal/handlers"
 	"otp-auth-service/internal/middleware"
+	"otp-auth-service/internal/models"
+	"otp-auth-service/internal/otp"
 	"otp-auth-service/internal/repository"
 	"otp-auth-service/internal/services"
+	"otp-auth-service/internal/signing"
+	"otp-auth-service/internal/validation"
 
 	"github.com/gin-gonic/gin"
 	"github.com/redis/go-redis/v9"
@@ -24,7 +30,21 @@ import (
 // @BasePath /api/v1
 func main() {
 	// Load configuration
-	cfg := config.Load()
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatal("Failed to load configuration:", err)
+	}
+
+	// Subcommands
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "rotate-keys":
+			rotateKeys(cfg, os.Args[2:])
+			return
+		default:
+			log.Fatalf("Unknown command %q", os.Args[1])
+		}
+	}
 
 	// Initialize Redis client
 	redisClient := redis.NewClient(&redis.Options{
@@ -33,17 +53,76 @@ func main() {
 		DB:       cfg.RedisDB,
 	})
 
+	// Initialize OTP generation
+	otpGenerator, err := otp.NewGenerator(cfg.OTPLength, cfg.OTPAlphabet)
+	if err != nil {
+		log.Fatal("Invalid OTP format:", err)
+	}
+	validation.SetOTPFormat(otpGenerator.Length(), otpGenerator.Charset())
+
+	otpSealer, err := otp.NewSealer(cfg.OTPHashSecret)
+	if err != nil {
+		log.Fatal("Failed to initialize OTP sealer:", err)
+	}
+
 	// Initialize repositories
-	userRepo := repository.NewUserRepository()
-	otpRepo := repository.NewOTPRepository(redisClient)
+	userRepo, err := newUserRepository(cfg, redisClient)
+	if err != nil {
+		log.Fatal("Failed to open user store:", err)
+	}
+	otpRepo := repository.NewOTPRepository(redisClient, otpGenerator, otp.NewHasher(cfg.OTPHashSecret), otpSealer, cfg.OTPPurposePolicies)
+	tokenRepo := repository.NewTokenRepository(redisClient)
+	revocationRepo := repository.NewRevocationRepository(redisClient)
+	sessionRepo := repository.NewSessionRepository(redisClient)
+	clientRepo := repository.NewClientRepository(cfg.OAuthClients)
+	authorizationRepo := repository.NewAuthorizationRepository(redisClient)
+	consentRepo := repository.NewConsentRepository(redisClient)
+	assertionRepo := repository.NewAssertionRepository(redisClient)
+
+	// Initialize OTP delivery
+	sender, err := delivery.NewSender(cfg)
+	if err != nil {
+		log.Fatal("Failed to initialize OTP sender:", err)
+	}
+
+	keyring, err := signing.NewKeyring(cfg)
+	if err != nil {
+		log.Fatal("Failed to load JWT signing keys:", err)
+	}
+	// Pick up keys added or removed by rotate-keys
+	go keyring.Watch(cfg.JWTKeysReloadInterval, nil)
 
 	// Initialize services
-	authService := services.NewAuthService(userRepo, otpRepo, cfg.JWTSecret)
+	authService := services.NewAuthService(userRepo, otpRepo, tokenRepo, revocationRepo, sessionRepo, sender, services.AuthConfig{
+		Keyring:         keyring,
+		Issuer:          cfg.JWTIssuer,
+		Audiences:       cfg.JWTAudiences,
+		AccessTokenTTL:  cfg.AccessTokenTTL,
+		RefreshTokenTTL: cfg.RefreshTokenTTL,
+	})
+	oauthService := services.NewOAuthService(authService, clientRepo, authorizationRepo, consentRepo, assertionRepo, services.OAuthConfig{
+		Issuer:           cfg.JWTIssuer,
+		LoginURL:         cfg.OAuthLoginURL,
+		APIURL:           strings.TrimSuffix(cfg.JWTIssuer, "/") + handlers.APIBasePath,
+		AuthorizationTTL: cfg.OAuthAuthorizationTTL,
+		CodeTTL:          cfg.OAuthCodeTTL,
+	})
+	if !oauthService.IssuesIDTokens() {
+		for _, client := range cfg.OAuthClients {
+			if models.ContainsScopes(client.Scopes, []string{models.ScopeOpenID}) {
+				log.Printf("OAuth client %s lists the openid scope, which is refused with %s; set an asymmetric JWT_ALGORITHM for OpenID Connect", client.ID, signing.AlgorithmHS256)
+			}
+		}
+	}
 	userService := services.NewUserService(userRepo)
 
 	// Initialize handlers
 	authHandler := handlers.NewAuthHandler(authService)
+	oauthHandler := handlers.NewOAuthHandler(oauthService)
+	oidcHandler := handlers.NewOIDCHandler(oauthService, userService)
 	userHandler := handlers.NewUserHandler(userService)
+	authMiddleware := middleware.AuthMiddleware(authService)
+	verifyMiddleware := middleware.AuthMiddleware(authService, middleware.WithTokenCookie(cfg.AuthCookieName))
 
 	// Setup Gin router
 	router := gin.Default()
@@ -52,31 +131,15 @@ func main() {
 	router.Use(middleware.CORS())
 	router.Use(middleware.Logger())
 
-	// API routes
-	api := router.Group("/api/v1")
-	{
-		// Auth routes
-		auth := api.Group("/auth")
-		{
-			auth.POST("/request-otp", authHandler.RequestOTP)
-			auth.POST("/verify-otp", authHandler.VerifyOTP)
-		}
-
-		// User routes (protected)
-		users := api.Group("/users")
-		users.Use(middleware.AuthMiddleware(cfg.JWTSecret))
-		{
-			users.GET("/", userHandler.GetUsers)
-			users.GET("/:id", userHandler.GetUser)
-		}
-	}
+	// API and /.well-known routes
+	handlers.RegisterRoutes(router, authHandler, oauthHandler, oidcHandler, userHandler, authMiddleware, verifyMiddleware)
 
 	// Swagger documentation
 	docs.SwaggerInfo.Title = "OTP Authentication Service"
 	docs.SwaggerInfo.Description = "A backend service for OTP-based authentication and user management"
 	docs.SwaggerInfo.Version = "1.0"
 	docs.SwaggerInfo.Host = "localhost:8080"
-	docs.SwaggerInfo.BasePath = "/api/v1"
+	docs.SwaggerInfo.BasePath = handlers.APIBasePath
 	docs.SwaggerInfo.Schemes = []string{"http"}
 
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
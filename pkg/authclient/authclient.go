@@ -0,0 +1,144 @@
+// Package authclient verifies access tokens of the OTP auth service in
+// other Go services. A Verifier checks tokens either locally against the
+// service's published keys (JWKSVerifier) or by asking the service
+// (IntrospectionVerifier), and Middleware and GinMiddleware put the
+// authenticated Principal into the request context.
+package authclient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// Principal types, matching the principal_type claim
+const (
+	PrincipalUser    = "user"
+	PrincipalService = "service"
+)
+
+// Principal is the user or machine client a token was issued to
+type Principal struct {
+	// Subject is the user ID, or the client ID of a service principal
+	Subject string
+	// Type is PrincipalUser or PrincipalService
+	Type        string
+	PhoneNumber string
+	SessionID   string
+	// ClientID is the OAuth client the token was issued to. It is empty for
+	// tokens of the service's own apps, which signed in with /auth/verify-otp.
+	ClientID  string
+	Scopes    []string
+	TokenID   string
+	ExpiresAt time.Time
+	// AuthTime is when the user last verified an OTP and ACR how; both
+	// are empty for service principals
+	AuthTime time.Time
+	ACR      string
+}
+
+// IsService reports whether the token was issued to a machine client
+func (p *Principal) IsService() bool {
+	return p.Type == PrincipalService
+}
+
+// UserID returns the ID of the user, or "" for service principals
+func (p *Principal) UserID() string {
+	if p.IsService() {
+		return ""
+	}
+	return p.Subject
+}
+
+// AuthenticatedWithin reports whether the user verified an OTP no longer
+// than maxAge ago
+func (p *Principal) AuthenticatedWithin(maxAge time.Duration) bool {
+	return !p.AuthTime.IsZero() && time.Since(p.AuthTime) <= maxAge
+}
+
+// HasScope reports whether the token grants scope. Tokens of the service's
+// own apps are not limited by scopes, like on the auth service itself.
+func (p *Principal) HasScope(scope string) bool {
+	if p.ClientID == "" && !p.IsService() {
+		return true
+	}
+	for _, granted := range p.Scopes {
+		if granted == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Verifier checks an access token and returns its principal. Failures are
+// *Error values: ErrInvalidToken for tokens to reject, ErrUnavailable when
+// the token could not be checked.
+type Verifier interface {
+	Verify(ctx context.Context, token string) (*Principal, error)
+}
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying principal
+func NewContext(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, contextKey{}, principal)
+}
+
+// FromContext returns the principal the middleware authenticated
+func FromContext(ctx context.Context) (*Principal, bool) {
+	principal, ok := ctx.Value(contextKey{}).(*Principal)
+	return principal, ok && principal != nil
+}
+
+// Error is a verification failure. Codes and statuses match the errors the
+// auth service itself responds with.
+type Error struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Details string `json:"details,omitempty"`
+	Status  int    `json:"-"`
+}
+
+// Error implements the error interface
+func (e *Error) Error() string {
+	if e.Details != "" {
+		return e.Message + ": " + e.Details
+	}
+	return e.Message
+}
+
+// Is matches errors by code, so errors.Is(err, ErrInvalidToken) holds for
+// every invalid token whatever its details
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+func (e *Error) withDetails(details string) *Error {
+	return &Error{Code: e.Code, Message: e.Message, Details: details, Status: e.Status}
+}
+
+// Verification errors
+var (
+	ErrMissingToken      = &Error{Code: "MISSING_AUTH_HEADER", Message: "Authorization header is required", Status: http.StatusUnauthorized}
+	ErrInvalidAuthFormat = &Error{Code: "INVALID_AUTH_FORMAT", Message: "Invalid authorization header format", Status: http.StatusUnauthorized}
+	ErrInvalidToken      = &Error{Code: "INVALID_TOKEN", Message: "Invalid or expired token", Status: http.StatusUnauthorized}
+	ErrInsufficientScope = &Error{Code: "INSUFFICIENT_SCOPE", Message: "Token does not grant the required scope", Status: http.StatusForbidden}
+	ErrUserRequired      = &Error{Code: "USER_REQUIRED", Message: "This endpoint is only available to users", Status: http.StatusForbidden}
+	ErrStepUpRequired    = &Error{Code: "STEP_UP_REQUIRED", Message: "A recent OTP verification is required", Status: http.StatusUnauthorized}
+	ErrUnavailable       = &Error{Code: "AUTH_SERVICE_UNAVAILABLE", Message: "Token could not be verified", Status: http.StatusServiceUnavailable}
+)
+
+// toError converts a verifier failure to an *Error. Errors of custom
+// verifiers that are not *Error count as ErrUnavailable.
+func toError(err error) *Error {
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+	return ErrUnavailable.withDetails(err.Error())
+}
+
+// defaultHTTPClient is used by verifiers configured without a client
+var defaultHTTPClient = &http.Client{Timeout: 10 * time.Second}
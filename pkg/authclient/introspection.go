@@ -0,0 +1,115 @@
+package authclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"otp-auth-service/internal/models"
+)
+
+// IntrospectionConfig configures an IntrospectionVerifier
+type IntrospectionConfig struct {
+	// URL of the introspection endpoint, e.g.
+	// https://auth.example.com/api/v1/oauth/introspect
+	URL string
+	// ClientID and ClientSecret of a confidential client registered with
+	// the auth service
+	ClientID     string
+	ClientSecret string
+	// Audience, if set, must be one of the audiences of the token
+	Audience   string
+	HTTPClient *http.Client
+}
+
+// IntrospectionVerifier asks the auth service about every token (RFC
+// 7662). Unlike JWKSVerifier it sees logouts and revoked sessions at once,
+// at the cost of a request per verification.
+type IntrospectionVerifier struct {
+	config IntrospectionConfig
+}
+
+// NewIntrospectionVerifier creates a verifier
+func NewIntrospectionVerifier(config IntrospectionConfig) (*IntrospectionVerifier, error) {
+	if config.URL == "" || config.ClientID == "" || config.ClientSecret == "" {
+		return nil, fmt.Errorf("URL, client ID and client secret are required")
+	}
+	if config.HTTPClient == nil {
+		config.HTTPClient = defaultHTTPClient
+	}
+
+	return &IntrospectionVerifier{config: config}, nil
+}
+
+// Verify implements Verifier
+func (v *IntrospectionVerifier) Verify(ctx context.Context, token string) (*Principal, error) {
+	form := url.Values{
+		"token":           {token},
+		"token_type_hint": {models.TokenTypeHintAccessToken},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, v.config.URL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, ErrUnavailable.withDetails(err.Error())
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(url.QueryEscape(v.config.ClientID), url.QueryEscape(v.config.ClientSecret))
+
+	resp, err := v.config.HTTPClient.Do(req)
+	if err != nil {
+		return nil, ErrUnavailable.withDetails(err.Error())
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, ErrUnavailable.withDetails(fmt.Sprintf("introspection returned status %d", resp.StatusCode))
+	}
+
+	var introspection models.IntrospectionResponse
+	if err := json.NewDecoder(resp.Body).Decode(&introspection); err != nil {
+		return nil, ErrUnavailable.withDetails("malformed introspection response")
+	}
+
+	if !introspection.Active {
+		return nil, ErrInvalidToken
+	}
+	// Refresh tokens of our own client are active too
+	if introspection.TokenType != "Bearer" {
+		return nil, ErrInvalidToken.withDetails("token is not an access token")
+	}
+	if v.config.Audience != "" && !containsString(introspection.Audience, v.config.Audience) {
+		return nil, ErrInvalidToken.withDetails("token is not intended for this service")
+	}
+
+	principal := &Principal{
+		Subject:     introspection.Subject,
+		Type:        introspection.PrincipalType,
+		PhoneNumber: introspection.PhoneNumber,
+		SessionID:   introspection.SessionID,
+		ClientID:    introspection.ClientID,
+		Scopes:      models.ParseScope(introspection.Scope),
+		TokenID:     introspection.TokenID,
+		ExpiresAt:   time.Unix(introspection.ExpiresAt, 0),
+		ACR:         introspection.ACR,
+	}
+	if principal.Type == "" {
+		principal.Type = PrincipalUser
+	}
+	if introspection.AuthTime != 0 {
+		principal.AuthTime = time.Unix(introspection.AuthTime, 0)
+	}
+
+	return principal, nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
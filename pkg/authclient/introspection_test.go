@@ -0,0 +1,109 @@
+package authclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"otp-auth-service/internal/models"
+)
+
+// newTestIntrospectionServer answers introspection requests of the
+// "orders" client from a fixed set of token descriptions
+func newTestIntrospectionServer(t *testing.T, tokens map[string]models.IntrospectionResponse) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Credentials are form encoded before Basic encoding (RFC 6749 section 2.3.1)
+		clientID, secret, ok := r.BasicAuth()
+		if !ok || clientID != "orders" || secret != "s3cret%3A%2B" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.PostFormValue("token_type_hint") != models.TokenTypeHintAccessToken {
+			t.Errorf("Expected access_token hint, got %q", r.PostFormValue("token_type_hint"))
+		}
+
+		json.NewEncoder(w).Encode(tokens[r.PostFormValue("token")])
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestIntrospectionVerifier_Verify(t *testing.T) {
+	expiresAt := time.Now().Add(time.Minute).Truncate(time.Second)
+	server := newTestIntrospectionServer(t, map[string]models.IntrospectionResponse{
+		"user": {
+			Active: true, TokenType: "Bearer", Subject: "user-1", PrincipalType: models.PrincipalUser,
+			PhoneNumber: "+1234567890", SessionID: "session-1", Scope: "orders",
+			ClientID: "web-app", Audience: []string{testAudience}, ExpiresAt: expiresAt.Unix(),
+		},
+		"service": {
+			Active: true, TokenType: "Bearer", Subject: "batch", PrincipalType: models.PrincipalService,
+			ClientID: "batch", Audience: []string{testAudience},
+		},
+		"refresh":       {Active: true, Subject: "user-1", PrincipalType: models.PrincipalUser},
+		"elsewhere":     {Active: true, TokenType: "Bearer", Subject: "user-1", Audience: []string{"billing-api"}},
+		"revoked-token": {Active: false},
+	})
+
+	verifier, err := NewIntrospectionVerifier(IntrospectionConfig{
+		URL:          server.URL,
+		ClientID:     "orders",
+		ClientSecret: "s3cret:+",
+		Audience:     testAudience,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create verifier: %v", err)
+	}
+
+	principal, err := verifier.Verify(context.Background(), "user")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if principal.UserID() != "user-1" || principal.PhoneNumber != "+1234567890" || principal.SessionID != "session-1" {
+		t.Errorf("Unexpected principal: %+v", principal)
+	}
+	if !principal.HasScope("orders") || !principal.ExpiresAt.Equal(expiresAt) {
+		t.Errorf("Unexpected principal: %+v", principal)
+	}
+
+	principal, err = verifier.Verify(context.Background(), "service")
+	if err != nil || !principal.IsService() {
+		t.Errorf("Expected service principal, got %+v (%v)", principal, err)
+	}
+
+	for _, token := range []string{"refresh", "elsewhere", "revoked-token", "unknown"} {
+		if _, err := verifier.Verify(context.Background(), token); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("%s: expected INVALID_TOKEN, got %v", token, err)
+		}
+	}
+}
+
+func TestIntrospectionVerifier_Unavailable(t *testing.T) {
+	server := newTestIntrospectionServer(t, nil)
+
+	// Rejected client credentials are a problem of this service, not of the token
+	verifier, err := NewIntrospectionVerifier(IntrospectionConfig{URL: server.URL, ClientID: "orders", ClientSecret: "wrong"})
+	if err != nil {
+		t.Fatalf("Failed to create verifier: %v", err)
+	}
+	if _, err := verifier.Verify(context.Background(), "user"); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("Expected AUTH_SERVICE_UNAVAILABLE, got %v", err)
+	}
+
+	server.Close()
+	verifier.config.ClientSecret = "s3cret:+"
+	if _, err := verifier.Verify(context.Background(), "user"); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("Expected AUTH_SERVICE_UNAVAILABLE, got %v", err)
+	}
+
+	if _, err := NewIntrospectionVerifier(IntrospectionConfig{URL: server.URL, ClientID: "orders"}); err == nil {
+		t.Error("Expected error without client secret")
+	}
+}
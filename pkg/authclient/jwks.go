@@ -0,0 +1,199 @@
+package authclient
+
+import (
+	"context"
+	"crypto"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"otp-auth-service/internal/models"
+	"otp-auth-service/internal/signing"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	defaultCacheTTL = 5 * time.Minute
+	// defaultRefreshInterval limits how often tokens with an unknown kid,
+	// or a failing auth service, make the verifier fetch the key set
+	defaultRefreshInterval = 30 * time.Second
+)
+
+// JWKSConfig configures a JWKSVerifier
+type JWKSConfig struct {
+	// Issuer is the JWT_ISSUER of the auth service
+	Issuer string
+	// Audience is the audience of this service. It must be one of the auth
+	// service's JWT_AUDIENCES; it also keeps ID tokens from being accepted.
+	Audience string
+	// JWKSURL defaults to Issuer + "/.well-known/jwks.json"
+	JWKSURL string
+	// CacheTTL is how long a fetched key set is used, 5 minutes by default
+	CacheTTL   time.Duration
+	HTTPClient *http.Client
+}
+
+// JWKSVerifier verifies tokens locally with the public keys the auth
+// service publishes. It needs no round trip per request, but cannot see
+// revocations: a logged out token stays valid here until it expires. Use
+// IntrospectionVerifier where that matters.
+type JWKSVerifier struct {
+	config          JWKSConfig
+	refreshInterval time.Duration
+
+	mu          sync.Mutex
+	keys        map[string]jwksKey
+	fetchedAt   time.Time
+	attemptedAt time.Time
+}
+
+type jwksKey struct {
+	algorithm string
+	key       crypto.PublicKey
+}
+
+// asymmetricMethods are the algorithms a published key can verify. HS256
+// is never accepted: its key is secret and cannot be published.
+var asymmetricMethods = []string{signing.AlgorithmRS256, signing.AlgorithmES256, signing.AlgorithmEdDSA}
+
+// NewJWKSVerifier creates a verifier. Keys are fetched on first use.
+func NewJWKSVerifier(config JWKSConfig) (*JWKSVerifier, error) {
+	if config.Issuer == "" || config.Audience == "" {
+		return nil, fmt.Errorf("issuer and audience are required")
+	}
+	if config.JWKSURL == "" {
+		config.JWKSURL = strings.TrimSuffix(config.Issuer, "/") + "/.well-known/jwks.json"
+	}
+	if config.CacheTTL <= 0 {
+		config.CacheTTL = defaultCacheTTL
+	}
+	if config.HTTPClient == nil {
+		config.HTTPClient = defaultHTTPClient
+	}
+
+	return &JWKSVerifier{config: config, refreshInterval: defaultRefreshInterval}, nil
+}
+
+// Verify implements Verifier
+func (v *JWKSVerifier) Verify(ctx context.Context, token string) (*Principal, error) {
+	var keyErr error
+	claims := &models.Claims{}
+	_, err := jwt.ParseWithClaims(token, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		key, err := v.publicKey(ctx, kid)
+		if err != nil {
+			keyErr = err
+			return nil, err
+		}
+		if key.algorithm != "" && key.algorithm != token.Method.Alg() {
+			return nil, fmt.Errorf("token algorithm does not match its key")
+		}
+		return key.key, nil
+	},
+		jwt.WithValidMethods(asymmetricMethods),
+		jwt.WithIssuer(v.config.Issuer),
+		jwt.WithAudience(v.config.Audience),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+	)
+	if err != nil {
+		if keyErr != nil {
+			return nil, keyErr
+		}
+		return nil, ErrInvalidToken.withDetails(err.Error())
+	}
+
+	principal := &Principal{
+		Subject:     claims.Subject,
+		Type:        claims.Principal(),
+		PhoneNumber: claims.PhoneNumber,
+		SessionID:   claims.SessionID,
+		ClientID:    claims.ClientID,
+		Scopes:      models.ParseScope(claims.Scope),
+		TokenID:     claims.ID,
+		ExpiresAt:   claims.ExpiresAt.Time,
+		ACR:         claims.ACR,
+	}
+	if claims.AuthTime != nil {
+		principal.AuthTime = claims.AuthTime.Time
+	}
+
+	return principal, nil
+}
+
+// publicKey returns the key with ID kid. The key set is fetched again once
+// the cache expires, or early when kid is unknown, since a new key may
+// have been rotated in. If fetching fails, cached keys keep being used.
+func (v *JWKSVerifier) publicKey(ctx context.Context, kid string) (jwksKey, error) {
+	if kid == "" {
+		return jwksKey{}, ErrInvalidToken.withDetails("token has no kid")
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	key, ok := v.keys[kid]
+	now := time.Now()
+	stale := now.Sub(v.fetchedAt) > v.config.CacheTTL
+	if (stale || !ok) && now.Sub(v.attemptedAt) >= v.refreshInterval {
+		v.attemptedAt = now
+		if err := v.refresh(ctx); err != nil && !ok {
+			return jwksKey{}, ErrUnavailable.withDetails(err.Error())
+		}
+		if fresh, found := v.keys[kid]; found {
+			key, ok = fresh, true
+		}
+	}
+
+	if !ok {
+		if v.fetchedAt.IsZero() {
+			return jwksKey{}, ErrUnavailable.withDetails("key set has not been fetched")
+		}
+		return jwksKey{}, ErrInvalidToken.withDetails("token is signed with an unknown key")
+	}
+
+	return key, nil
+}
+
+// refresh fetches the key set. Keys that cannot be decoded are skipped.
+func (v *JWKSVerifier) refresh(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.config.JWKSURL, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := v.config.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("fetch key set: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("fetch key set: status %d", resp.StatusCode)
+	}
+
+	var set signing.JWKS
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return fmt.Errorf("decode key set: %w", err)
+	}
+
+	keys := make(map[string]jwksKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.KeyID == "" || (jwk.Use != "" && jwk.Use != "sig") {
+			continue
+		}
+		key, err := jwk.PublicKey()
+		if err != nil {
+			continue
+		}
+		keys[jwk.KeyID] = jwksKey{algorithm: jwk.Algorithm, key: key}
+	}
+
+	v.keys = keys
+	v.fetchedAt = time.Now()
+
+	return nil
+}
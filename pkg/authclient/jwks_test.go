@@ -0,0 +1,268 @@
+package authclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"otp-auth-service/internal/models"
+	"otp-auth-service/internal/signing"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	testIssuer   = "https://auth.example.com"
+	testAudience = "orders-api"
+)
+
+// testKeyServer publishes the key set of a keyring and counts fetches
+type testKeyServer struct {
+	*httptest.Server
+
+	mu      sync.Mutex
+	keyring *signing.Keyring
+	fetches int
+	failing bool
+}
+
+func newTestKeyServer(t *testing.T) *testKeyServer {
+	t.Helper()
+
+	server := &testKeyServer{keyring: newTestKeyring(t, signing.AlgorithmEdDSA)}
+	server.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		server.mu.Lock()
+		defer server.mu.Unlock()
+
+		server.fetches++
+		if server.failing {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(server.keyring.JWKS())
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func (s *testKeyServer) setKeyring(keyring *signing.Keyring) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keyring = keyring
+}
+
+func (s *testKeyServer) setFailing(failing bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failing = failing
+}
+
+func (s *testKeyServer) fetchCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.fetches
+}
+
+func (s *testKeyServer) verifier(t *testing.T) *JWKSVerifier {
+	t.Helper()
+
+	verifier, err := NewJWKSVerifier(JWKSConfig{Issuer: testIssuer, Audience: testAudience, JWKSURL: s.URL})
+	if err != nil {
+		t.Fatalf("Failed to create verifier: %v", err)
+	}
+	return verifier
+}
+
+func newTestKeyring(t *testing.T, algorithm string) *signing.Keyring {
+	t.Helper()
+
+	pemData, err := signing.GenerateKey(algorithm)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	key, err := signing.ParseKey(algorithm, pemData)
+	if err != nil {
+		t.Fatalf("Failed to parse key: %v", err)
+	}
+	keyring, err := signing.NewStaticKeyring(key)
+	if err != nil {
+		t.Fatalf("Failed to create keyring: %v", err)
+	}
+	return keyring
+}
+
+// testClaims are the claims of a user token the auth service would issue
+func testClaims() *models.Claims {
+	now := time.Now()
+	return &models.Claims{
+		PhoneNumber: "+1234567890",
+		SessionID:   "session-1",
+		ClientID:    "web-app",
+		Scope:       "openid orders",
+		AuthTime:    jwt.NewNumericDate(time.Now().Add(-time.Minute)),
+		ACR:         models.ACRPhoneOTP,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        "token-1",
+			Issuer:    testIssuer,
+			Subject:   "user-1",
+			Audience:  jwt.ClaimStrings{"otp-auth-service", testAudience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(15 * time.Minute)),
+		},
+	}
+}
+
+func sign(t *testing.T, keyring *signing.Keyring, claims jwt.Claims) string {
+	t.Helper()
+
+	token, err := keyring.Sign(claims)
+	if err != nil {
+		t.Fatalf("Failed to sign token: %v", err)
+	}
+	return token
+}
+
+func TestJWKSVerifier_Verify(t *testing.T) {
+	server := newTestKeyServer(t)
+	verifier := server.verifier(t)
+
+	principal, err := verifier.Verify(context.Background(), sign(t, server.keyring, testClaims()))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if principal.UserID() != "user-1" || principal.Type != PrincipalUser || principal.PhoneNumber != "+1234567890" {
+		t.Errorf("Unexpected principal: %+v", principal)
+	}
+	if principal.SessionID != "session-1" || principal.ClientID != "web-app" || principal.TokenID != "token-1" {
+		t.Errorf("Unexpected principal: %+v", principal)
+	}
+	if !principal.AuthenticatedWithin(5*time.Minute) || principal.ACR != models.ACRPhoneOTP {
+		t.Errorf("Expected auth_time and acr, got %v %q", principal.AuthTime, principal.ACR)
+	}
+	if !principal.HasScope("orders") || principal.HasScope("admin") {
+		t.Errorf("Expected the scopes of the token, got %v", principal.Scopes)
+	}
+
+	service := testClaims()
+	service.PrincipalType, service.Subject, service.ClientID = models.PrincipalService, "batch", "batch"
+	service.PhoneNumber, service.SessionID = "", ""
+	principal, err = verifier.Verify(context.Background(), sign(t, server.keyring, service))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !principal.IsService() || principal.UserID() != "" || principal.Subject != "batch" {
+		t.Errorf("Expected service principal, got %+v", principal)
+	}
+
+	// The key set is cached
+	if got := server.fetchCount(); got != 1 {
+		t.Errorf("Expected 1 fetch, got %d", got)
+	}
+}
+
+func TestJWKSVerifier_Rejects(t *testing.T) {
+	server := newTestKeyServer(t)
+	verifier := server.verifier(t)
+
+	// An HS256 token naming the published key must not be verified with it
+	hmac := jwt.NewWithClaims(jwt.SigningMethodHS256, testClaims())
+	hmac.Header["kid"] = server.keyring.Active().ID
+	confused, err := hmac.SignedString([]byte("secret"))
+	if err != nil {
+		t.Fatalf("Failed to sign token: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		token  string
+		modify func(claims *models.Claims)
+	}{
+		{name: "wrong issuer", modify: func(c *models.Claims) { c.Issuer = "https://evil.example.com" }},
+		{name: "wrong audience", modify: func(c *models.Claims) { c.Audience = jwt.ClaimStrings{"otp-auth-service"} }},
+		{name: "expired", modify: func(c *models.Claims) { c.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute)) }},
+		{name: "no subject", modify: func(c *models.Claims) { c.Subject = "" }},
+		{name: "unknown key", token: sign(t, newTestKeyring(t, signing.AlgorithmES256), testClaims())},
+		{name: "HS256 with published kid", token: confused},
+		{name: "malformed", token: "not-a-token"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token := tt.token
+			if tt.modify != nil {
+				claims := testClaims()
+				tt.modify(claims)
+				token = sign(t, server.keyring, claims)
+			}
+
+			_, err := verifier.Verify(context.Background(), token)
+			if !errors.Is(err, ErrInvalidToken) {
+				t.Errorf("Expected INVALID_TOKEN, got %v", err)
+			}
+		})
+	}
+}
+
+func TestJWKSVerifier_KeyRotation(t *testing.T) {
+	server := newTestKeyServer(t)
+	verifier := server.verifier(t)
+	verifier.refreshInterval = 0
+
+	if _, err := verifier.Verify(context.Background(), sign(t, server.keyring, testClaims())); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// A token signed with a new key makes the verifier fetch the set again
+	rotated := newTestKeyring(t, signing.AlgorithmRS256)
+	server.setKeyring(rotated)
+	if _, err := verifier.Verify(context.Background(), sign(t, rotated, testClaims())); err != nil {
+		t.Fatalf("Expected no error after rotation, got %v", err)
+	}
+	if got := server.fetchCount(); got != 2 {
+		t.Errorf("Expected 2 fetches, got %d", got)
+	}
+}
+
+func TestJWKSVerifier_Unavailable(t *testing.T) {
+	server := newTestKeyServer(t)
+	verifier := server.verifier(t)
+	verifier.refreshInterval = 0
+	token := sign(t, server.keyring, testClaims())
+
+	server.setFailing(true)
+	if _, err := verifier.Verify(context.Background(), token); !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("Expected AUTH_SERVICE_UNAVAILABLE, got %v", err)
+	}
+
+	server.setFailing(false)
+	if _, err := verifier.Verify(context.Background(), token); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// Once fetched, keys keep working while the service is down
+	verifier.config.CacheTTL = 0
+	server.setFailing(true)
+	if _, err := verifier.Verify(context.Background(), token); err != nil {
+		t.Errorf("Expected cached key to be used, got %v", err)
+	}
+}
+
+func TestNewJWKSVerifier(t *testing.T) {
+	verifier, err := NewJWKSVerifier(JWKSConfig{Issuer: testIssuer + "/", Audience: testAudience})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if verifier.config.JWKSURL != testIssuer+"/.well-known/jwks.json" {
+		t.Errorf("Expected JWKS URL of the issuer, got %s", verifier.config.JWKSURL)
+	}
+
+	if _, err := NewJWKSVerifier(JWKSConfig{Issuer: testIssuer}); err == nil {
+		t.Error("Expected error without audience")
+	}
+}
@@ -0,0 +1,139 @@
+package authclient
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Option restricts which principals the middleware lets through
+type Option func(*options)
+
+type options struct {
+	scopes      []string
+	requireUser bool
+	maxAuthAge  time.Duration
+}
+
+// RequireScopes only lets tokens through that grant every one of scopes.
+// Tokens of the service's own apps are not limited by scopes.
+func RequireScopes(scopes ...string) Option {
+	return func(o *options) {
+		o.scopes = append(o.scopes, scopes...)
+	}
+}
+
+// RequireUser rejects service principals, for endpoints that act on the
+// signed in user. Tokens carry no roles; the principal type and scopes
+// are what requests are authorized by.
+func RequireUser() Option {
+	return func(o *options) {
+		o.requireUser = true
+	}
+}
+
+// RequireFreshAuth rejects users who verified their OTP more than maxAge
+// ago, for sensitive operations. They step up at the auth service's
+// /auth/step-up and retry with the new token. Service principals pass.
+func RequireFreshAuth(maxAge time.Duration) Option {
+	return func(o *options) {
+		o.maxAuthAge = maxAge
+	}
+}
+
+// Middleware authenticates requests to next with a bearer token. The
+// principal is available to next through FromContext; failed requests get
+// the same JSON errors the auth service responds with.
+func Middleware(verifier Verifier, opts ...Option) func(http.Handler) http.Handler {
+	o := newOptions(opts)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, err := authenticate(r, verifier, o)
+			if err != nil {
+				challenge(w.Header(), err, o)
+				w.Header().Set("Content-Type", "application/json; charset=utf-8")
+				w.WriteHeader(err.Status)
+				json.NewEncoder(w).Encode(map[string]*Error{"error": err})
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(NewContext(r.Context(), principal)))
+		})
+	}
+}
+
+// GinMiddleware is Middleware for gin. Handlers get the principal with
+// FromContext(c.Request.Context()).
+func GinMiddleware(verifier Verifier, opts ...Option) gin.HandlerFunc {
+	o := newOptions(opts)
+
+	return func(c *gin.Context) {
+		principal, err := authenticate(c.Request, verifier, o)
+		if err != nil {
+			challenge(c.Writer.Header(), err, o)
+			c.AbortWithStatusJSON(err.Status, gin.H{"error": err})
+			return
+		}
+
+		c.Request = c.Request.WithContext(NewContext(c.Request.Context(), principal))
+		c.Next()
+	}
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// authenticate verifies the bearer token of r and checks that its
+// principal may pass
+func authenticate(r *http.Request, verifier Verifier, o *options) (*Principal, *Error) {
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		return nil, ErrMissingToken
+	}
+	if !strings.HasPrefix(authHeader, "Bearer ") {
+		return nil, ErrInvalidAuthFormat
+	}
+
+	principal, err := verifier.Verify(r.Context(), strings.TrimPrefix(authHeader, "Bearer "))
+	if err != nil {
+		return nil, toError(err)
+	}
+
+	if o.requireUser && principal.IsService() {
+		return nil, ErrUserRequired
+	}
+	if o.maxAuthAge > 0 && !principal.IsService() && !principal.AuthenticatedWithin(o.maxAuthAge) {
+		return nil, ErrStepUpRequired
+	}
+	for _, scope := range o.scopes {
+		if !principal.HasScope(scope) {
+			return nil, ErrInsufficientScope.withDetails(scope + " scope is required")
+		}
+	}
+
+	return principal, nil
+}
+
+// challenge sets the RFC 6750 WWW-Authenticate header for err
+func challenge(header http.Header, err *Error, o *options) {
+	switch {
+	case err.Is(ErrMissingToken), err.Is(ErrInvalidAuthFormat):
+		header.Set("WWW-Authenticate", "Bearer")
+	case err.Is(ErrInvalidToken):
+		header.Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+	case err.Is(ErrInsufficientScope):
+		header.Set("WWW-Authenticate", fmt.Sprintf(`Bearer error="insufficient_scope", scope="%s"`, strings.Join(o.scopes, " ")))
+	case err.Is(ErrStepUpRequired):
+		header.Set("WWW-Authenticate", fmt.Sprintf(`Bearer error="insufficient_user_authentication", max_age=%d`, int(o.maxAuthAge.Seconds())))
+	}
+}
@@ -0,0 +1,117 @@
+package authclient
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// testVerifier accepts the tokens it maps to principals
+type testVerifier map[string]*Principal
+
+func (v testVerifier) Verify(ctx context.Context, token string) (*Principal, error) {
+	if token == "down" {
+		return nil, ErrUnavailable
+	}
+	principal, ok := v[token]
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+	return principal, nil
+}
+
+var middlewareTestVerifier = testVerifier{
+	"first-party": {Subject: "user-1", Type: PrincipalUser, AuthTime: time.Now()},
+	"stale":       {Subject: "user-1", Type: PrincipalUser, AuthTime: time.Now().Add(-time.Hour)},
+	"client":      {Subject: "user-1", Type: PrincipalUser, ClientID: "web-app", Scopes: []string{"profile"}},
+	"service":     {Subject: "batch", Type: PrincipalService, ClientID: "batch", Scopes: []string{"orders:read"}},
+}
+
+func TestMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name       string
+		header     string
+		opts       []Option
+		wantStatus int
+		wantCode   string
+		wantAuth   string
+	}{
+		{name: "first-party user", header: "Bearer first-party", opts: []Option{RequireScopes("orders:read"), RequireUser()}, wantStatus: http.StatusOK},
+		{name: "service with scope", header: "Bearer service", opts: []Option{RequireScopes("orders:read")}, wantStatus: http.StatusOK},
+		{name: "missing header", wantStatus: http.StatusUnauthorized, wantCode: "MISSING_AUTH_HEADER", wantAuth: "Bearer"},
+		{name: "wrong scheme", header: "Basic abc", wantStatus: http.StatusUnauthorized, wantCode: "INVALID_AUTH_FORMAT", wantAuth: "Bearer"},
+		{name: "invalid token", header: "Bearer nope", wantStatus: http.StatusUnauthorized, wantCode: "INVALID_TOKEN", wantAuth: `Bearer error="invalid_token"`},
+		{
+			name: "client without scope", header: "Bearer client", opts: []Option{RequireScopes("orders:read")},
+			wantStatus: http.StatusForbidden, wantCode: "INSUFFICIENT_SCOPE", wantAuth: `Bearer error="insufficient_scope", scope="orders:read"`,
+		},
+		{name: "service on user endpoint", header: "Bearer service", opts: []Option{RequireUser()}, wantStatus: http.StatusForbidden, wantCode: "USER_REQUIRED"},
+		{name: "recent OTP", header: "Bearer first-party", opts: []Option{RequireFreshAuth(5 * time.Minute)}, wantStatus: http.StatusOK},
+		{name: "service needs no OTP", header: "Bearer service", opts: []Option{RequireFreshAuth(5 * time.Minute)}, wantStatus: http.StatusOK},
+		{
+			name: "stale OTP", header: "Bearer stale", opts: []Option{RequireFreshAuth(5 * time.Minute)},
+			wantStatus: http.StatusUnauthorized, wantCode: "STEP_UP_REQUIRED", wantAuth: `Bearer error="insufficient_user_authentication", max_age=300`,
+		},
+		{name: "auth service down", header: "Bearer down", wantStatus: http.StatusServiceUnavailable, wantCode: "AUTH_SERVICE_UNAVAILABLE"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got *Principal
+			next := func(r *http.Request) {
+				got, _ = FromContext(r.Context())
+			}
+
+			router := gin.New()
+			router.GET("/", GinMiddleware(middlewareTestVerifier, tt.opts...), func(c *gin.Context) {
+				next(c.Request)
+			})
+			handlers := map[string]http.Handler{
+				"net/http": Middleware(middlewareTestVerifier, tt.opts...)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					next(r)
+				})),
+				"gin": router,
+			}
+
+			for kind, handler := range handlers {
+				got = nil
+				req := httptest.NewRequest(http.MethodGet, "/", nil)
+				if tt.header != "" {
+					req.Header.Set("Authorization", tt.header)
+				}
+				w := httptest.NewRecorder()
+				handler.ServeHTTP(w, req)
+
+				if w.Code != tt.wantStatus {
+					t.Fatalf("%s: expected status %d, got %d", kind, tt.wantStatus, w.Code)
+				}
+				if tt.wantStatus == http.StatusOK {
+					if got == nil {
+						t.Errorf("%s: expected principal in context", kind)
+					}
+					continue
+				}
+
+				var body struct {
+					Error Error `json:"error"`
+				}
+				if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+					t.Fatalf("%s: failed to decode response: %v", kind, err)
+				}
+				if body.Error.Code != tt.wantCode {
+					t.Errorf("%s: expected %s, got %s", kind, tt.wantCode, body.Error.Code)
+				}
+				if got := w.Header().Get("WWW-Authenticate"); got != tt.wantAuth {
+					t.Errorf("%s: expected WWW-Authenticate %q, got %q", kind, tt.wantAuth, got)
+				}
+			}
+		})
+	}
+}
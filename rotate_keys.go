@@ -0,0 +1,41 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"time"
+
+	"otp-auth-service/internal/config"
+	"otp-auth-service/internal/signing"
+)
+
+// rotateKeys adds a new signing key to JWT_KEYS_DIR and removes keys whose
+// tokens have expired. Running services pick the new key up on their next
+// reload.
+//
+//	go run . rotate-keys [-activate-in 10m]
+func rotateKeys(cfg *config.Config, args []string) {
+	flags := flag.NewFlagSet("rotate-keys", flag.ExitOnError)
+	// Verifiers caching the JWKS must see the key before tokens signed
+	// with it arrive, so by default it is published a while before use
+	activateIn := flags.Duration("activate-in", 2*cfg.JWTKeysReloadInterval+5*time.Minute,
+		"how long the new key is only published before it starts signing")
+	flags.Parse(args)
+
+	if cfg.JWTAlgorithm == signing.AlgorithmHS256 {
+		log.Fatal("HS256 secrets are rotated by moving JWT_SECRET to JWT_PREVIOUS_SECRETS and setting a new JWT_SECRET")
+	}
+	if cfg.JWTKeysDir == "" {
+		log.Fatal("rotate-keys needs JWT_KEYS_DIR")
+	}
+
+	path, removed, err := signing.RotateKeys(cfg.JWTKeysDir, cfg.JWTAlgorithm, time.Now().Add(*activateIn), cfg.AccessTokenTTL)
+	if err != nil {
+		log.Fatal("Failed to rotate signing keys:", err)
+	}
+
+	log.Printf("Created signing key %s, active in %s", path, *activateIn)
+	for _, file := range removed {
+		log.Printf("Removed retired signing key %s", file)
+	}
+}
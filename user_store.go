@@ -0,0 +1,47 @@
+package main
+
+import (
+	"database/sql"
+	"log"
+
+	"otp-auth-service/internal/config"
+	"otp-auth-service/internal/repository"
+
+	"github.com/redis/go-redis/v9"
+
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// newUserRepository opens the user store selected by USER_STORE and brings
+// its schema up to date
+func newUserRepository(cfg *config.Config, redisClient *redis.Client) (repository.UserRepository, error) {
+	var dialect string
+	switch cfg.UserStore {
+	case "redis":
+		return repository.NewRedisUserRepository(redisClient), nil
+	case "sqlite":
+		dialect = repository.DialectSQLite
+	case "postgres":
+		dialect = repository.DialectPostgres
+	default:
+		log.Println("Users are kept in memory and lost on restart; set USER_STORE to persist them")
+		return repository.NewUserRepository(), nil
+	}
+
+	db, err := sql.Open(dialect, cfg.DatabaseURL)
+	if err != nil {
+		return nil, err
+	}
+	if dialect == repository.DialectSQLite {
+		// SQLite allows one writer at a time; a single connection queues
+		// writes instead of failing them with "database is locked"
+		db.SetMaxOpenConns(1)
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return repository.NewSQLUserRepository(db, dialect)
+}